@@ -0,0 +1,71 @@
+package api
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/middlewares"
+	"Hexo-AutoCD/redact"
+	"Hexo-AutoCD/release"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Register 注册管理接口，api.token 为空时不注册
+func Register(r *gin.Engine) {
+	if config.Get().API.Token == "" {
+		return
+	}
+	sites := r.Group("/api/sites/:site", middlewares.APIAuth(), siteExists)
+	sites.GET("/releases", listReleases)
+	sites.POST("/rollback", rollback)
+}
+
+// siteExists 只接受配置中的站点
+func siteExists(c *gin.Context) {
+	if c.Param("site") != config.Get().Site.Name {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"错误": "站点不存在"})
+		return
+	}
+	if !config.Get().Releases.Enabled {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"错误": "未启用 releases"})
+		return
+	}
+	c.Next()
+}
+
+// listReleases 返回所有版本，最新的在前
+func listReleases(c *gin.Context) {
+	releases, err := release.New(config.Get().Releases).List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"错误": redact.String(err.Error())})
+		return
+	}
+	if releases == nil {
+		releases = []release.Release{}
+	}
+	c.JSON(http.StatusOK, redact.Value(releases))
+}
+
+// rollback 把 current 切换到请求中的版本，请求体为空时切换到上一个版本
+func rollback(c *gin.Context) {
+	var req struct {
+		Release string `json:"release"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"错误": "无法解析请求体"})
+			return
+		}
+	}
+
+	to, from, err := release.RollbackSite(req.Release, "api "+c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"错误": redact.String(err.Error()), "当前版本": from})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"消息":   "已回滚",
+		"当前版本": to,
+		"原版本":  from,
+	})
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/events"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/redact"
+	"Hexo-AutoCD/scripts"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// command 定义一个子命令
+type command struct {
+	Name  string                  // 子命令名称，多级子命令用空格分隔，如 "config check"
+	Short string                  // 一句话说明
+	Run   func(args []string) int // 执行子命令，返回进程退出码
+}
+
+// configPath 通过 --config 指定的配置文件路径
+var configPath string
+
+// commands 返回所有子命令
+func commands() []command {
+	return []command{
+		{Name: "serve", Short: "启动 Webhook 服务（默认子命令）", Run: runServe},
+		{Name: "config check", Short: "校验配置文件并试加载 SSL 证书", Run: runConfigCheck},
+		{Name: "run", Short: "在本地立即执行一次部署", Run: runRun},
+		{Name: "replay", Short: "重放一次 Webhook 事件", Run: runReplay},
+		{Name: "sign", Short: "从本地提交构造推送载荷并计算 X-Hub-Signature-256 签名", Run: runSign},
+		{Name: "send", Short: "从本地提交构造推送载荷，签名后发送给服务或输出 curl 命令", Run: runSend},
+		{Name: "posts preprocess", Short: "按 posts 配置为文章补全 front-matter", Run: runPostsPreprocess},
+		{Name: "posts lint", Short: "检查文章的 front-matter、图片与文章链接", Run: runPostsLint},
+		{Name: "rollback", Short: "把站点切换到之前发布的版本", Run: runRollback},
+		{Name: "purge", Short: "刷新 CDN 缓存", Run: runPurge},
+		{Name: "token", Short: "生成随机的 Webhook 密钥", Run: runToken},
+		{Name: "version", Short: "显示版本信息", Run: runVersion},
+	}
+}
+
+// Run 解析命令行参数并执行对应的子命令，返回进程退出码
+func Run(args []string) int {
+	// 执行器启动的辅助进程，不解析参数也不读取配置
+	if len(args) > 0 && args[0] == scripts.SandboxCommand {
+		return scripts.SandboxMain(args[1:])
+	}
+
+	// 解析出现在子命令之前的全局参数
+	global := flag.NewFlagSet("hexo-autocd", flag.ContinueOnError)
+	global.StringVar(&configPath, "config", "", "配置文件路径")
+	global.Usage = usage
+	if err := global.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	args = global.Args()
+
+	// 未指定子命令时启动服务，兼容旧的 systemd 配置
+	if len(args) == 0 {
+		return runServe(nil)
+	}
+
+	if args[0] == "help" {
+		usage()
+		return 0
+	}
+
+	for _, cmd := range commands() {
+		words := strings.Fields(cmd.Name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != cmd.Name {
+			continue
+		}
+		return cmd.Run(args[len(words):])
+	}
+
+	fmt.Fprintf(os.Stderr, "未知的子命令: %s\n\n", strings.Join(args, " "))
+	usage()
+	return 2
+}
+
+// usage 输出帮助信息
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: hexo-autocd [--config 配置文件] <子命令> [参数]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "子命令:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.Name, cmd.Short)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "未指定 --config 时依次在以下目录查找 config.yaml: %s\n", strings.Join(config.SearchPaths(), ", "))
+	fmt.Fprintf(os.Stderr, "任意配置项都可以用 %s_ 前缀的环境变量覆盖，如 %s_WEBHOOK_SECRET\n", config.EnvPrefix, config.EnvPrefix)
+	fmt.Fprintln(os.Stderr, "使用 \"hexo-autocd <子命令> -h\" 查看子命令的参数")
+}
+
+// newFlagSet 创建子命令的参数集，所有子命令都支持 --config
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "用法: hexo-autocd %s %s\n\n参数:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse 解析子命令参数，返回值为 false 时调用方应使用 code 作为退出码
+func parse(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0, false
+		}
+		return 2, false
+	}
+	return 0, true
+}
+
+// setup 加载配置并初始化日志与出站事件投递器
+// 供需要执行部署的子命令使用；keys 非空时只校验这些配置项，见 config.InitConfig
+func setup(keys ...string) {
+	config.InitConfig(configPath, keys...)
+	redact.Apply()
+
+	if err := logger.Init(); err != nil {
+		fmt.Printf("初始化日志系统失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := events.Init(); err != nil {
+		logger.Fatalf("初始化出站事件投递器失败: %v", err)
+	}
+}
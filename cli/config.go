@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"fmt"
+)
+
+// runConfigCheck 执行 `hexo-autocd config check [配置文件]`
+// 运行与启动时相同的校验，并试加载 SSL 证书，存在致命错误时返回非零退出码，
+// 便于在部署流程中重启服务前先校验配置
+func runConfigCheck(args []string) int {
+	fs := newFlagSet("config check", "[配置文件]")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	path := configPath
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	file, problems, err := config.Check(path)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("配置文件: %s\n", file)
+
+	fatal := problems.Fatal()
+	warnings := problems.Warnings()
+	for _, p := range fatal {
+		fmt.Printf("  ✗ [错误] %s\n", p)
+	}
+	for _, p := range warnings {
+		fmt.Printf("  ! [警告] %s\n", p)
+	}
+
+	if len(fatal) > 0 {
+		fmt.Printf("✗ 校验失败：%d 个错误，%d 个警告\n", len(fatal), len(warnings))
+		return 1
+	}
+	fmt.Printf("✓ 校验通过：%d 个警告\n", len(warnings))
+	return 0
+}
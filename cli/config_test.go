@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// capture 执行 run 并返回它写到标准输出的内容
+func capture(t *testing.T, run func() int) (int, string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	code := run()
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return code, string(out)
+}
+
+func TestConfigCheckExitCode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "deploy.sh"), []byte("#!/bin/bash\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := "logs:\n  path: " + dir + "/logs/webhooks.log\nscripts:\n  path: " + dir + "\n  push: deploy.sh\n"
+	tests := []struct {
+		name, content string
+		args          []string
+		code          int
+		output        string
+	}{
+		{name: "valid with warnings", content: base + "webhook:\n  secret: short\n", code: 0, output: "✓ 校验通过：1 个警告"},
+		{name: "fatal problems", content: base + "webhook:\n  secret: 0123456789abcdef0123\n  timout: 5s\n", code: 1, output: "webhook.timout: 未知的配置项"},
+		{name: "missing secret", content: base, code: 1, output: "webhook.secret"},
+		{name: "missing file", args: []string{filepath.Join(dir, "missing.yaml")}, code: 1, output: "读取配置文件失败"},
+		{name: "bad flag", args: []string{"--unknown"}, code: 2},
+	}
+	for i, tt := range tests {
+		args := tt.args
+		if tt.content != "" {
+			path := filepath.Join(dir, "config"+string(rune('a'+i))+".yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			args = []string{path}
+		}
+		code, out := capture(t, func() int { return runConfigCheck(args) })
+		if code != tt.code || !strings.Contains(out, tt.output) {
+			t.Errorf("%s: exit code %d, want %d; output:\n%s", tt.name, code, tt.code, out)
+		}
+	}
+}
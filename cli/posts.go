@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/posts"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runPostsPreprocess 执行 `hexo-autocd posts preprocess`
+// 按 posts 配置为文章补全 front-matter，与流水线中的 posts/preprocess 步骤行为一致
+func runPostsPreprocess(args []string) int {
+	fs := newFlagSet("posts preprocess", "[--all] [--dry-run] [文章...]")
+	all := fs.Bool("all", false, "处理文章仓库中的所有文章")
+	dryRun := fs.Bool("dry-run", false, "只输出处理结果，不写入文件")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	if cfg.Posts.SourceDir == "" {
+		fmt.Println("✗ 未设置 posts.source_dir 或 site.repo_dir")
+		return 1
+	}
+
+	p := posts.NewPreprocessor(cfg.Posts)
+	files, code := postFiles(p, fs.Args(), *all)
+	if code != 0 {
+		return code
+	}
+
+	if *dryRun {
+		for _, file := range files {
+			content, err := os.ReadFile(filepath.Join(p.SourceDir, file))
+			if err == nil {
+				content, err = p.Rules.Process(file, content, "", time.Now())
+			}
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", file, err)
+				code = 1
+				continue
+			}
+			fmt.Printf("==> %s\n%s\n", file, content)
+		}
+		return code
+	}
+
+	for _, result := range p.Apply(files) {
+		switch {
+		case result.Error != nil:
+			fmt.Printf("✗ %s: %v\n", result.Source, result.Error)
+			code = 1
+		case result.Dest != "":
+			fmt.Printf("✓ %s -> %s\n", result.Source, result.Dest)
+		default:
+			fmt.Printf("✓ %s\n", result.Source)
+		}
+	}
+	return code
+}
+
+// runPostsLint 执行 `hexo-autocd posts lint`
+// 检查文章的 front-matter 与正文中的本地引用，与流水线中的 posts/lint 步骤行为一致；
+// 设置了 posts.dest_dir 时检查其中预处理后的文章
+func runPostsLint(args []string) int {
+	fs := newFlagSet("posts lint", "[--all] [文章...]")
+	all := fs.Bool("all", false, "检查目录中的所有文章")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	if cfg.Posts.SourceDir == "" {
+		fmt.Println("✗ 未设置 posts.source_dir 或 site.repo_dir")
+		return 1
+	}
+
+	l := posts.NewLinter(cfg.Posts)
+	files, code := postFiles(&posts.Preprocessor{SourceDir: l.Dir}, fs.Args(), *all)
+	if code != 0 {
+		return code
+	}
+
+	findings, err := l.Lint(files)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	var errorCount int
+	for _, f := range findings {
+		if f.Severity == posts.SeverityError {
+			errorCount++
+		}
+		fmt.Println(f.String())
+	}
+	fmt.Printf("检查了 %d 篇文章，%d 个错误，%d 个警告\n", len(files), errorCount, len(findings)-errorCount)
+	if l.Failed(findings) {
+		return 1
+	}
+	return 0
+}
+
+// postFiles 返回要处理的文章，路径相对于文章仓库
+// 命令行中的路径可以是相对于当前目录的路径，也可以是相对于文章仓库的路径
+func postFiles(p *posts.Preprocessor, args []string, all bool) ([]string, int) {
+	if all {
+		files, err := p.All()
+		if err != nil {
+			fmt.Printf("✗ 读取文章目录失败: %v\n", err)
+			return nil, 1
+		}
+		return files, 0
+	}
+	if len(args) == 0 {
+		fmt.Println("✗ 请指定文章或使用 --all")
+		return nil, 2
+	}
+
+	source, _ := filepath.Abs(p.SourceDir)
+	var files []string
+	for _, arg := range args {
+		if abs, err := filepath.Abs(arg); err == nil {
+			if rel, err := filepath.Rel(source, abs); err == nil && filepath.IsLocal(rel) {
+				if _, err := os.Stat(abs); err == nil {
+					arg = rel
+				}
+			}
+		}
+		// 文章不存在时 Apply 会删除输出目录中的同名文章，命令行中不应因为路径写错而删除
+		if _, err := os.Stat(filepath.Join(p.SourceDir, arg)); err != nil {
+			fmt.Printf("✗ 文章不存在: %s\n", arg)
+			return nil, 1
+		}
+		if !posts.IsPost(arg) {
+			fmt.Printf("✗ 不是需要处理的文章: %s\n", arg)
+			return nil, 1
+		}
+		files = append(files, filepath.ToSlash(arg))
+	}
+	return files, 0
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/purge"
+	"fmt"
+	"strings"
+)
+
+// runPurge 执行 `hexo-autocd purge`
+// 刷新指定路径的 CDN 缓存，未指定时刷新 purge.paths 中的页面
+func runPurge(args []string) int {
+	fs := newFlagSet("purge", "[--dry-run] [路径...]")
+	dryRun := fs.Bool("dry-run", false, "只列出要刷新的 URL，不调用刷新接口")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	if !cfg.Purge.Enabled {
+		fmt.Println("✗ 未启用 purge")
+		return 1
+	}
+
+	paths := cfg.Purge.Paths
+	if fs.NArg() > 0 {
+		paths = nil
+		for _, p := range fs.Args() {
+			paths = append(paths, "/"+strings.TrimPrefix(p, "/"))
+		}
+	}
+
+	if *dryRun {
+		urls, err := purge.New(cfg.Purge).URLs(paths)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		for _, u := range urls {
+			fmt.Println(u)
+		}
+		return 0
+	}
+
+	setup()
+	results := purge.New(cfg.Purge).Purge(paths)
+	for _, r := range results {
+		fmt.Println(r.String())
+	}
+	if failed := purge.Failed(results); len(failed) > 0 {
+		fmt.Printf("✗ %d 个 URL 刷新失败\n", len(failed))
+		return 1
+	}
+	fmt.Printf("✓ 已刷新 %d 个 URL\n", len(results))
+	return 0
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/release"
+	"fmt"
+)
+
+// runRollback 执行 `hexo-autocd rollback`
+// 把 current 切换到指定版本，未指定时切换到当前版本之前的一个版本
+func runRollback(args []string) int {
+	fs := newFlagSet("rollback", "[--list] [版本]")
+	list := fs.Bool("list", false, "列出所有版本")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	if !cfg.Releases.Enabled {
+		fmt.Println("✗ 未启用 releases")
+		return 1
+	}
+
+	if *list {
+		releases, err := release.New(cfg.Releases).List()
+		if err != nil {
+			fmt.Printf("✗ 读取版本失败: %v\n", err)
+			return 1
+		}
+		if len(releases) == 0 {
+			fmt.Println("还没有发布过版本")
+			return 0
+		}
+		for _, r := range releases {
+			mark := " "
+			if r.Current {
+				mark = "*"
+			}
+			fmt.Printf("%s %-28s %s\n", mark, r.ID, r.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return 0
+	}
+
+	// 回滚只用到发布目录、日志、脱敏与出站事件，其余配置有问题（如部署脚本已被删除）时也应能回滚
+	setup("releases", "logs", "redact", "outbound")
+	to, from, err := release.RollbackSite(fs.Arg(0), "cli")
+	if err != nil {
+		fmt.Printf("✗ 回滚失败: %v\n", err)
+		return 1
+	}
+	if from == "" {
+		from = "无"
+	}
+	fmt.Printf("✓ current -> %s（原版本 %s）\n", to, from)
+	return 0
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/redact"
+	"Hexo-AutoCD/scripts"
+	"Hexo-AutoCD/simulator"
+	"Hexo-AutoCD/webhooks"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// runRun 执行 `hexo-autocd run`，不经过 Webhook 直接在本地执行一次部署
+func runRun(args []string) int {
+	fs := newFlagSet("run", "[--event push] [--payload 文件]")
+	event := fs.String("event", "push", "事件类型")
+	payloadFile := fs.String("payload", "", "事件载荷 JSON 文件，\"-\" 表示标准输入；为空时使用空载荷")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	payload := []byte("{}")
+	if *payloadFile != "" {
+		data, err := readPayload(*payloadFile)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		payload = data
+	}
+
+	setup()
+	return runLocal(*event, payload)
+}
+
+// runReplay 执行 `hexo-autocd replay`
+// 指定 --url 时签名后发送给正在运行的服务，否则在本地直接处理
+func runReplay(args []string) int {
+	fs := newFlagSet("replay", "[--event push] [--url 地址] <载荷文件|->")
+	event := fs.String("event", "push", "事件类型，对应 X-GitHub-Event 头")
+	url := fs.String("url", "", "正在运行的服务的 Webhook 地址，如 https://example.com:8080/webhook")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	payload, err := readPayload(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+
+	if *url == "" {
+		setup()
+		return runLocal(*event, payload)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	headers, err := simulator.Headers(simulator.FormatGitHub, *event, payload, cfg.Webhook.Secret)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	return post(*url, payload, headers)
+}
+
+// runLocal 在本地处理事件并输出结果
+func runLocal(event string, payload []byte) int {
+	result, err := webhooks.Run(event, payload)
+	if err != nil {
+		fmt.Printf("✗ 部署失败: %s\n", redact.String(err.Error()))
+		return 1
+	}
+	if result == nil {
+		// 删除预览等没有执行步骤的操作
+		fmt.Println("✓ 完成")
+		return 0
+	}
+	printSteps(result.Steps)
+	printHosts(result.Hosts, "  ")
+	printAttempts(result.History, "  ")
+	if result.ExitCode != 0 {
+		fmt.Printf("✗ 部署失败，退出码 %d: %s\n", result.ExitCode, redact.String(result.Error))
+		return 1
+	}
+	fmt.Println("✓ 部署成功")
+	return 0
+}
+
+// printSteps 输出流水线各步骤的执行情况
+func printSteps(steps []scripts.StepResult) {
+	for _, step := range steps {
+		switch step.Status {
+		case scripts.StepSuccess:
+			if step.Attempts > 1 {
+				fmt.Printf("  ✓ %-16s %dms，执行 %d 次\n", step.Name, step.DurationMs, step.Attempts)
+				break
+			}
+			fmt.Printf("  ✓ %-16s %dms\n", step.Name, step.DurationMs)
+		case scripts.StepFailed:
+			fmt.Printf("  ✗ %-16s %dms，执行 %d 次，退出码 %d\n", step.Name, step.DurationMs, step.Attempts, step.ExitCode)
+		default:
+			fmt.Printf("  - %-16s 跳过: %s\n", step.Name, step.Reason)
+		}
+		printHosts(step.Hosts, "      ")
+		printAttempts(step.History, "      ")
+		for _, sync := range step.Sync {
+			if sync.Error != "" {
+				fmt.Printf("      ✗ %-16s %s\n", sync.Target, sync.Error)
+				continue
+			}
+			fmt.Printf("      ✓ %-16s 上传 %d，删除 %d，未变 %d\n", sync.Target, sync.Uploaded, sync.Deleted, sync.Unchanged)
+		}
+	}
+}
+
+// printHosts 输出在各远程主机上的执行情况
+func printHosts(hosts []scripts.HostResult, indent string) {
+	for _, host := range hosts {
+		switch host.Status {
+		case scripts.StepSuccess:
+			fmt.Printf("%s✓ %-16s %dms\n", indent, host.Host, host.DurationMs)
+		case scripts.StepFailed:
+			fmt.Printf("%s✗ %-16s %dms，退出码 %d: %s\n", indent, host.Host, host.DurationMs, host.ExitCode, host.Error)
+		default:
+			fmt.Printf("%s- %-16s 跳过: %s\n", indent, host.Host, host.Error)
+		}
+	}
+}
+
+// printAttempts 输出重试时每次执行的情况
+func printAttempts(attempts []scripts.Attempt, indent string) {
+	for _, attempt := range attempts {
+		if attempt.ExitCode == 0 && attempt.Error == "" {
+			fmt.Printf("%s✓ 第 %d 次 %dms\n", indent, attempt.Attempt, attempt.DurationMs)
+			continue
+		}
+		// 只输出错误信息的第一行，完整内容见日志
+		reason, _, _ := strings.Cut(strings.TrimSpace(attempt.Error), "\n")
+		fmt.Printf("%s✗ 第 %d 次 %dms，退出码 %d: %s\n", indent, attempt.Attempt, attempt.DurationMs, attempt.ExitCode, redact.String(reason))
+	}
+}
+
+// post 签名并发送事件，与 GitHub 发送 Webhook 的方式相同
+func post(url string, payload []byte, headers http.Header) int {
+	status, body, err := simulator.Send(url, payload, headers)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	fmt.Printf("%d %s\n%s\n", status, http.StatusText(status), body)
+	if status < 200 || status >= 300 {
+		return 1
+	}
+	return 0
+}
+
+// readPayload 读取载荷文件，"-" 表示标准输入
+func readPayload(path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("读取标准输入失败: %v", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取载荷文件失败: %v", err)
+	}
+	return data, nil
+}
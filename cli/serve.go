@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/redact"
+	"Hexo-AutoCD/router"
+	"Hexo-AutoCD/static"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// runServe 执行 `hexo-autocd serve`，启动 Webhook 服务
+func runServe(args []string) int {
+	fs := newFlagSet("serve", "")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	setup()
+
+	// 初始化路由
+	router.InitRouter()
+
+	// 监听配置文件变化与 SIGHUP 信号，热加载配置
+	config.Watch(applyConfig)
+
+	cfg := config.Get()
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Webhook.Port),
+		Handler: router.Handler(),
+	}
+
+	// 日志记录启动信息
+	logger.Info("服务器开始启动")
+
+	// 启用内置静态文件服务时在单独的端口上提供生成的站点，替代 hexo serve
+	if cfg.Static.Enabled {
+		go func() {
+			logger.Infof("静态文件服务启动于 %s，站点目录 %s", cfg.Static.Listen, cfg.Static.Root)
+			// 端口被占用等错误不影响 Webhook 服务
+			if err := http.ListenAndServe(cfg.Static.Listen, static.Handler()); err != nil {
+				logger.Errorf("启动静态文件服务失败: %v", err)
+			}
+		}()
+	}
+
+	// 根据配置决定使用 HTTP 还是 HTTPS
+	if cfg.SSL.Enabled {
+		// 使用 HTTPS
+		logger.Infof("HTTPS 服务器启动于 %s", server.Addr)
+		err := server.ListenAndServeTLS(cfg.SSL.CertFile, cfg.SSL.KeyFile)
+		if err != nil {
+			logger.Fatalf("启动 HTTPS 服务器失败: %v", err)
+		}
+	} else {
+		// 使用 HTTP
+		logger.Infof("HTTP 服务器启动于 %s", server.Addr)
+		err := server.ListenAndServe()
+		if err != nil {
+			logger.Fatalf("启动 HTTP 服务器失败: %v", err)
+		}
+	}
+	return 0
+}
+
+// applyConfig 在配置热加载后应用新配置
+// 日志级别/格式与路由立即生效，密钥与脚本等配置在下一次部署时生效
+func applyConfig(result config.ReloadResult) {
+	if result.Err != nil {
+		logger.WithField("触发来源", result.Trigger).WithError(result.Err).Error("配置热加载失败，继续使用旧配置")
+		return
+	}
+	if !result.Changed {
+		logger.WithField("触发来源", result.Trigger).Debug("配置文件内容未变化")
+		return
+	}
+
+	logger.Apply()
+	redact.Apply()
+	router.Reload()
+
+	logger.WithField("触发来源", result.Trigger).Info("配置热加载成功")
+	for _, p := range result.Warnings {
+		logger.WithField("配置项", p.Key).Warn(p.Message)
+	}
+	if len(result.Restart) > 0 {
+		logger.WithField("配置项", strings.Join(result.Restart, ", ")).Warn("以下配置项已修改，需要重启服务才能生效")
+	}
+}
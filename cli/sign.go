@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"Hexo-AutoCD/config"
+	sign "Hexo-AutoCD/signature"
+	"Hexo-AutoCD/simulator"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// pushFlags 从本地仓库构造推送载荷所需的参数，sign 与 send 共用
+type pushFlags struct {
+	repo   *string
+	rng    *string
+	branch *string
+	format *string
+	secret *string
+}
+
+func addPushFlags(fs *flag.FlagSet) *pushFlags {
+	return &pushFlags{
+		repo:   fs.String("repo", "", "从该 Git 仓库的提交构造推送载荷"),
+		rng:    fs.String("range", "HEAD", "提交范围，如 HEAD~3..HEAD；只写一个提交时等价于 <提交>~1..<提交>"),
+		branch: fs.String("branch", "", "推送的分支，默认为仓库当前分支"),
+		format: fs.String("format", simulator.FormatGitHub, "载荷格式：github、gitea 或 gitlab"),
+		secret: fs.String("secret", "", "签名密钥，默认使用配置中的 webhook.secret"),
+	}
+}
+
+// payload 构造推送载荷：指定了 --repo 时从提交范围生成，否则读取载荷文件
+func (p *pushFlags) payload(fs *flag.FlagSet) ([]byte, error) {
+	if *p.repo != "" {
+		return simulator.BuildPush(simulator.Options{
+			RepoDir: *p.repo,
+			Range:   *p.rng,
+			Branch:  *p.branch,
+			Format:  *p.format,
+		})
+	}
+
+	path := "-"
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	return readPayload(path)
+}
+
+// resolveSecret 返回签名密钥，未通过 --secret 指定时读取配置
+func (p *pushFlags) resolveSecret() (string, error) {
+	if *p.secret != "" {
+		return *p.secret, nil
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return "", err
+	}
+	return cfg.Webhook.Secret, nil
+}
+
+// runSign 执行 `hexo-autocd sign`，输出载荷的 X-Hub-Signature-256 签名
+// 默认使用配置文件中的 webhook.secret，与服务校验签名的方式完全相同
+func runSign(args []string) int {
+	fs := newFlagSet("sign", "[--repo 仓库 --range A..B [--format github]] [--out 文件] [载荷文件|-]")
+	flags := addPushFlags(fs)
+	out := fs.String("out", "", "将构造的载荷写入该文件，签名即针对该文件的内容")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	payload, err := flags.payload(fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	secret, err := flags.resolveSecret()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+
+	signature := fmt.Sprintf("X-Hub-Signature-256: %s\n", sign.Sign(payload, secret))
+	if *flags.repo != "" {
+		if *out != "" {
+			if err := os.WriteFile(*out, payload, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "✗ 写入载荷失败: %v\n", err)
+				return 1
+			}
+		} else {
+			// 标准输出只包含载荷本身，重定向到文件后与签名逐字节一致，签名输出到标准错误
+			fmt.Print(string(payload))
+			fmt.Fprint(os.Stderr, signature)
+			return 0
+		}
+	}
+
+	fmt.Print(signature)
+	return 0
+}
+
+// runSend 执行 `hexo-autocd send`，构造推送载荷并签名后发送给正在运行的服务
+// 指定 --curl 时只输出等价的 curl 命令
+func runSend(args []string) int {
+	fs := newFlagSet("send", "--url 地址 [--repo 仓库 --range A..B [--format github]] [--curl] [载荷文件|-]")
+	flags := addPushFlags(fs)
+	url := fs.String("url", "", "Webhook 地址，如 http://127.0.0.1:8080/webhook")
+	event := fs.String("event", "push", "事件类型")
+	curl := fs.Bool("curl", false, "只输出等价的 curl 命令，不发送")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if *url == "" {
+		fs.Usage()
+		return 2
+	}
+
+	payload, err := flags.payload(fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	secret, err := flags.resolveSecret()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+
+	headers, err := simulator.Headers(*flags.format, *event, payload, secret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	if *curl {
+		fmt.Println(simulator.Curl(*url, payload, headers))
+		return 0
+	}
+	return post(*url, payload, headers)
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// runToken 执行 `hexo-autocd token`，生成可用作 webhook.secret 的随机密钥
+func runToken(args []string) int {
+	fs := newFlagSet("token", "[--bytes 32]")
+	size := fs.Int("bytes", 32, "随机字节数，输出为两倍长度的十六进制字符串")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	if *size < 16 {
+		fmt.Println("✗ --bytes 不能小于 16")
+		return 2
+	}
+
+	b := make([]byte, *size)
+	if _, err := rand.Read(b); err != nil {
+		fmt.Printf("✗ 生成随机数失败: %v\n", err)
+		return 1
+	}
+	fmt.Println(hex.EncodeToString(b))
+	return 0
+}
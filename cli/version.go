@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version 版本号，构建时通过 -ldflags "-X Hexo-AutoCD/cli.Version=v1.2.3" 注入
+var Version = "dev"
+
+// runVersion 执行 `hexo-autocd version`
+func runVersion(args []string) int {
+	fs := newFlagSet("version", "")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	fmt.Printf("hexo-autocd %s\n", Version)
+
+	// 从构建信息中读取提交ID
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				fmt.Printf("  提交:   %s\n", setting.Value)
+			case "vcs.time":
+				fmt.Printf("  时间:   %s\n", setting.Value)
+			case "vcs.modified":
+				if setting.Value == "true" {
+					fmt.Println("  工作区: 有未提交的修改")
+				}
+			}
+		}
+	}
+	fmt.Printf("  Go:     %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return 0
+}
@@ -4,6 +4,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -16,10 +22,12 @@ type config struct {
 	} `mapstructure:"webhook"`
 
 	Scripts struct {
-		Path          string `mapstructure:"path"`
-		Push          string `mapstructure:"push"`
-		Timeout       string `mapstructure:"timeout"`
-		MaxConcurrent int    `mapstructure:"max_concurrent"`
+		Path          string  `mapstructure:"path"`
+		Push          string  `mapstructure:"push"`
+		Timeout       string  `mapstructure:"timeout"`
+		MaxConcurrent int     `mapstructure:"max_concurrent"`
+		Sandbox       Sandbox `mapstructure:"sandbox"` // 在本机执行时对脚本与步骤的限制
+		Retry         Retry   `mapstructure:"retry"`   // 部署脚本与未设置重试的步骤失败后的重试策略
 	} `mapstructure:"scripts"`
 
 	Logs struct {
@@ -36,32 +44,567 @@ type config struct {
 		CertFile string `mapstructure:"cert_file"`
 		KeyFile  string `mapstructure:"key_file"`
 	} `mapstructure:"ssl"`
+
+	Site struct {
+		Name    string `mapstructure:"name"`
+		RepoDir string `mapstructure:"repo_dir"` // 博客源码仓库目录
+	} `mapstructure:"site"`
+
+	Git struct {
+		Enabled     bool     `mapstructure:"enabled"`      // 部署前检出推送中的提交
+		Remote      string   `mapstructure:"remote"`       // 远程仓库名
+		Branches    []string `mapstructure:"branches"`     // 生产分支，支持通配符，推送到其他分支时不检出也不部署；为空时使用仓库的默认分支
+		RefuseDirty bool     `mapstructure:"refuse_dirty"` // 工作区有修改时拒绝部署，而不是丢弃修改
+		Timeout     string   `mapstructure:"timeout"`      // 获取与检出的超时时间
+		Retry       Retry    `mapstructure:"retry"`        // 无法连接或超时时的重试策略，exit_codes 不适用
+
+		VerifySignatures struct {
+			Enabled        bool   `mapstructure:"enabled"`
+			Scope          string `mapstructure:"scope"`           // head 只校验最新提交，all 校验推送中的所有提交
+			GPGHome        string `mapstructure:"gpg_home"`        // 存放受信任 GPG 公钥的目录
+			AllowedSigners string `mapstructure:"allowed_signers"` // SSH 签名的 allowed_signers 文件
+		} `mapstructure:"verify_signatures"`
+	} `mapstructure:"git"`
+
+	Executor Executor `mapstructure:"executor"`
+
+	Pipeline Pipeline `mapstructure:"pipeline"`
+
+	Posts Posts `mapstructure:"posts"`
+
+	Releases Releases `mapstructure:"releases"`
+
+	Smoke Smoke `mapstructure:"smoke"`
+
+	Publish Publish `mapstructure:"publish"`
+
+	Purge Purge `mapstructure:"purge"`
+
+	Redact Redact `mapstructure:"redact"`
+
+	Static Static `mapstructure:"static"`
+
+	Preview Preview `mapstructure:"preview"`
+
+	Triggers struct {
+		Release Trigger `mapstructure:"release"` // GitHub release 事件
+		Tag     Trigger `mapstructure:"tag"`     // 创建标签的 create 事件
+	} `mapstructure:"triggers"`
+
+	API struct {
+		Token string `mapstructure:"token"` // 管理接口的 Bearer 令牌，为空时不启用 /api
+	} `mapstructure:"api"`
+
+	Outbound struct {
+		Outbox      string           `mapstructure:"outbox"`
+		DeliveryLog string           `mapstructure:"delivery_log"`
+		MaxAttempts int              `mapstructure:"max_attempts"`
+		Backoff     string           `mapstructure:"backoff"`
+		Targets     []OutboundTarget `mapstructure:"targets"`
+	} `mapstructure:"outbound"`
+}
+
+// OutboundTarget 定义一个出站事件 Webhook 的接收方
+type OutboundTarget struct {
+	Name    string   `mapstructure:"name"`
+	URL     string   `mapstructure:"url"`
+	Secret  string   `mapstructure:"secret"`
+	Events  []string `mapstructure:"events"` // 为空表示接收所有事件
+	Timeout string   `mapstructure:"timeout"`
+}
+
+// Executor 定义部署脚本与流水线步骤的执行方式
+type Executor struct {
+	Type   string `mapstructure:"type"` // local 在本机执行，docker 在容器中执行，ssh 在远程主机上执行
+	Docker Docker `mapstructure:"docker"`
+	SSH    SSH    `mapstructure:"ssh"`
+}
+
+// Docker 定义容器执行器，通过 Docker Engine API 或 Podman 的兼容接口创建容器
+// 脚本目录、工作目录与博客仓库等目录以相同的路径挂载到容器中，脚本中的路径无需修改
+type Docker struct {
+	Host    string   `mapstructure:"host"`    // 接口地址，如 unix:///var/run/docker.sock、unix:///run/podman/podman.sock、tcp://127.0.0.1:2375
+	Image   string   `mapstructure:"image"`   // 镜像，需要包含 bash，如 node:20
+	Pull    string   `mapstructure:"pull"`    // 拉取策略：missing 本地没有时拉取，always 每次拉取，never 从不拉取
+	Mounts  []string `mapstructure:"mounts"`  // 额外挂载的目录，格式为 主机路径[:容器路径][:ro]
+	User    string   `mapstructure:"user"`    // 容器内的用户，默认与服务进程相同，生成的文件属主不变
+	Memory  string   `mapstructure:"memory"`  // 内存上限，如 512m、2g
+	CPUs    float64  `mapstructure:"cpus"`    // CPU 上限，如 1.5
+	Network string   `mapstructure:"network"` // 网络模式，如 bridge、host、none
+	Env     []string `mapstructure:"env"`     // 追加的环境变量，KEY=VALUE
+}
+
+// SSH 定义远程执行器，通过 SSH 在一台或多台主机上执行部署脚本与流水线步骤
+type SSH struct {
+	Hosts          []SSHHost `mapstructure:"hosts"`
+	User           string    `mapstructure:"user"`            // 默认的登录用户
+	IdentityFile   string    `mapstructure:"identity_file"`   // 私钥文件，不能设置密码，有密码的私钥请使用 ssh-agent
+	Agent          bool      `mapstructure:"agent"`           // 使用 SSH_AUTH_SOCK 指向的 ssh-agent 认证
+	KnownHosts     string    `mapstructure:"known_hosts"`     // 校验主机密钥的 known_hosts 文件，默认为 ~/.ssh/known_hosts
+	Strategy       string    `mapstructure:"strategy"`        // rolling 逐台执行，某台失败后不再继续；parallel 同时在所有主机上执行
+	ConnectTimeout string    `mapstructure:"connect_timeout"` // 连接超时时间
+	Env            []string  `mapstructure:"env"`             // 追加的环境变量，KEY=VALUE
+}
+
+// SSHHost 定义一台远程主机
+type SSHHost struct {
+	Name    string `mapstructure:"name"`    // 主机名称，用于日志与执行结果，默认为 address
+	Address string `mapstructure:"address"` // 地址，如 web1.example.com 或 10.0.0.2:2222
+	User    string `mapstructure:"user"`    // 登录用户，默认为 ssh.user
+	Dir     string `mapstructure:"dir"`     // 远程工作目录，为空时使用脚本或步骤的工作目录
+}
+
+// Sandbox 定义在本机执行脚本与步骤时的限制，只在 Linux 上支持
+// 设置了用户、资源限制、chroot 或 Landlock 时，服务先启动自身作为辅助进程，施加限制后再执行脚本
+type Sandbox struct {
+	User      string   `mapstructure:"user"`      // 执行脚本的用户，用户名或 UID，需要服务以 root 运行
+	Group     string   `mapstructure:"group"`     // 执行脚本的用户组，默认为用户的主组
+	Env       string   `mapstructure:"env"`       // inherit 继承服务的环境变量，clean 只保留 PATH、LANG 等基本变量与 env_allow 中的变量
+	EnvAllow  []string `mapstructure:"env_allow"` // env 为 clean 时额外保留的环境变量，支持通配符，如 NODE_*
+	CPU       string   `mapstructure:"cpu"`       // CPU 时间上限，如 10m，超过后脚本被终止
+	Memory    string   `mapstructure:"memory"`    // 数据段内存上限，如 2g
+	Files     int      `mapstructure:"files"`     // 同时打开的文件数上限
+	Processes int      `mapstructure:"processes"` // 用户的进程数上限，按用户计算，需要与 user 一起使用
+	Chroot    string   `mapstructure:"chroot"`    // 切换根目录，目录中需要包含 bash 与脚本用到的所有程序
+	Landlock  bool     `mapstructure:"landlock"`  // 使用 Landlock 限制只能写入工作目录、临时目录、writable 中的目录与 RELEASE_DIR、PREVIEW_DIR
+	Writable  []string `mapstructure:"writable"`  // 启用 Landlock 时额外允许写入的目录，如博客的生成目录
+}
+
+// Isolated 返回是否需要通过辅助进程施加限制，只设置了环境变量时直接执行脚本
+func (s Sandbox) Isolated() bool {
+	return s.User != "" || s.Group != "" || s.CPU != "" || s.Memory != "" ||
+		s.Files > 0 || s.Processes > 0 || s.Chroot != "" || s.Landlock
+}
+
+// ParseBytes 解析 512m、2g 形式的大小，单位为 1024 进制，没有单位时为字节
+func ParseBytes(value string) (int64, error) {
+	s := strings.TrimSpace(strings.ToLower(value))
+	units := []struct {
+		suffix string
+		size   int64
+	}{{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30}}
+	multiplier := int64(1)
+	for _, unit := range units {
+		if trimmed, ok := strings.CutSuffix(strings.TrimSuffix(s, "b"), unit.suffix); ok {
+			s, multiplier = trimmed, unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("不是合法的大小: %q（示例：512m、2g）", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Pipeline 定义部署流水线
+// 配置了 steps 时按顺序执行各步骤，否则执行 scripts.push 脚本
+type Pipeline struct {
+	AllowRepoFile bool   `mapstructure:"allow_repo_file"` // 是否允许使用博客仓库中的流水线文件覆盖 steps
+	File          string `mapstructure:"file"`            // 仓库中的流水线文件，相对于 site.repo_dir
+	Steps         []Step `mapstructure:"steps"`
+}
+
+// Step 定义流水线中的一个步骤
+type Step struct {
+	Name       string   `mapstructure:"name"`
+	Run        string   `mapstructure:"run"`         // 使用 bash -c 执行的命令
+	Uses       string   `mapstructure:"uses"`        // 内置步骤，如 posts/preprocess，与 run 二选一
+	Dir        string   `mapstructure:"dir"`         // 工作目录，相对路径基于 scripts.path
+	Env        []string `mapstructure:"env"`         // KEY=VALUE 形式的环境变量
+	Timeout    string   `mapstructure:"timeout"`     // 为空时使用 scripts.timeout
+	Retries    int      `mapstructure:"retries"`     // 失败后的重试次数，按固定间隔重试；需要退避时使用 retry
+	RetryDelay string   `mapstructure:"retry_delay"` // 重试间隔
+	Retry      *Retry   `mapstructure:"retry"`       // 重试策略，替换 scripts.retry，不能与 retries 同时设置
+	When       When     `mapstructure:"when"`        // 执行条件，为空表示总是执行
+	Sandbox    *Sandbox `mapstructure:"sandbox"`     // 替换 scripts.sandbox，仓库中的流水线文件不能设置
+}
+
+// Retry 定义失败后的重试策略，等待时间按 delay × multiplier^(n-1) 增长
+// 设置了 exit_codes 或 patterns 时只重试满足其一的失败，避免重试脚本本身的错误
+type Retry struct {
+	Retries    int      `mapstructure:"retries"`    // 失败后最多重试的次数，0 表示不重试
+	Delay      string   `mapstructure:"delay"`      // 第一次重试前的等待时间，默认为 10s
+	MaxDelay   string   `mapstructure:"max_delay"`  // 等待时间上限，默认为 5m
+	Multiplier float64  `mapstructure:"multiplier"` // 每次重试等待时间的倍数，默认为 2
+	Jitter     float64  `mapstructure:"jitter"`     // 随机抖动比例，0.2 表示在 ±20% 内浮动
+	ExitCodes  []int    `mapstructure:"exit_codes"` // 只在这些退出码时重试，超时的退出码为 -1
+	Patterns   []string `mapstructure:"patterns"`   // 只在输出或错误信息匹配任一正则表达式时重试，如 ETIMEDOUT
+}
+
+// When 定义步骤的执行条件
+// branches 与 skip_message 先行判断；paths 与 message 只要满足其一步骤就会执行
+type When struct {
+	Branches    []string `mapstructure:"branches"`     // 推送的分支，支持通配符，如 release/*
+	Paths       []string `mapstructure:"paths"`        // 变更文件匹配任一规则时执行，支持 **，如 source/_posts/**
+	Message     []string `mapstructure:"message"`      // 提交信息包含任一标记时执行，如 [full rebuild]
+	SkipMessage []string `mapstructure:"skip_message"` // 提交信息包含任一标记时跳过，如 [skip deploy]
+}
+
+// Posts 定义文章预处理规则，供内置的 posts/preprocess 步骤与 posts 命令使用
+type Posts struct {
+	SourceDir         string                 `mapstructure:"source_dir"`          // 文章仓库目录，默认为 site.repo_dir
+	DestDir           string                 `mapstructure:"dest_dir"`            // 处理后文章的输出目录，如 Hexo 的 source/_posts
+	InPlace           bool                   `mapstructure:"in_place"`            // 同时改写源文件
+	TagSeparator      string                 `mapstructure:"tag_separator"`       // 文件名中标题与标签的分隔符
+	CategoriesFromDir bool                   `mapstructure:"categories_from_dir"` // 使用所在目录作为分类
+	DateFormat        string                 `mapstructure:"date_format"`         // date 字段的格式（Go 时间格式）
+	Covers            []string               `mapstructure:"covers"`              // 封面图片地址，按文件路径固定选取其中一个
+	Overwrite         []string               `mapstructure:"overwrite"`           // 即使作者已填写也使用生成值覆盖的字段
+	Defaults          map[string]interface{} `mapstructure:"defaults"`            // 缺失时补充的其他字段，如 ai: true
+	Lint              Lint                   `mapstructure:"lint"`
 }
 
-var Config *config
+// Releases 定义发布目录
+// 每次部署构建到 releases/<版本> 中，全部步骤成功后再原子切换 current 符号链接
+type Releases struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Dir     string `mapstructure:"dir"`    // 发布根目录，其中包含 releases/ 与 current
+	Source  string `mapstructure:"source"` // 构建输出目录，如 Hexo 的 public；为空时由步骤直接写入 RELEASE_DIR
+	Keep    int    `mapstructure:"keep"`   // 保留的版本数量
+	// 构建到版本目录并切换 current 的事件：push、release、tag，默认为全部；
+	// 例如设置为 [release] 时推送只构建预发布站点，发布 release 后才更新正式站点
+	Events []string `mapstructure:"events"`
+}
+
+// Publish 定义内置的 site/publish 步骤，把生成目录同步到一个或多个目标
+// 只上传内容有变化的文件，并删除目标中已不存在于生成目录的文件
+type Publish struct {
+	Source       string            `mapstructure:"source"`        // 生成目录，默认为 site.repo_dir/public；启用 releases 且未设置 releases.source 时使用本次发布的版本目录
+	KeepStale    bool              `mapstructure:"keep_stale"`    // 为 true 时不删除目标中多余的文件
+	Exclude      []string          `mapstructure:"exclude"`       // 不同步的文件，支持 **，如 **/.DS_Store
+	CacheControl []CacheRule       `mapstructure:"cache_control"` // Cache-Control 规则，按顺序使用第一条匹配的规则
+	ContentTypes map[string]string `mapstructure:"content_types"` // 按扩展名补充或覆盖 Content-Type，如 .xml: application/rss+xml
+	Targets      []PublishTarget   `mapstructure:"targets"`
+}
+
+// CacheRule 为匹配的文件设置 Cache-Control
+type CacheRule struct {
+	Paths []string `mapstructure:"paths"` // 相对于生成目录的路径规则，支持 **
+	Value string   `mapstructure:"value"` // 如 public, max-age=31536000, immutable
+}
+
+// PublishTarget 定义一个同步目标
+type PublishTarget struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"` // local、sftp 或 s3
+	Path string `mapstructure:"path"` // local 与 sftp 的目标目录
+
+	// sftp
+	Address      string `mapstructure:"address"`       // 如 web1.example.com 或 10.0.0.2:2222
+	User         string `mapstructure:"user"`          // 登录用户
+	IdentityFile string `mapstructure:"identity_file"` // 私钥文件，不能设置密码
+	Agent        bool   `mapstructure:"agent"`         // 使用 ssh-agent 认证
+	KnownHosts   string `mapstructure:"known_hosts"`   // 默认为 ~/.ssh/known_hosts
+
+	// s3
+	Endpoint  string `mapstructure:"endpoint"`   // 如 https://s3.us-east-1.amazonaws.com、http://127.0.0.1:9000
+	Region    string `mapstructure:"region"`     // 签名使用的区域，默认为 us-east-1
+	Bucket    string `mapstructure:"bucket"`     // 存储桶
+	Prefix    string `mapstructure:"prefix"`     // 对象键前缀，如 blog/
+	AccessKey string `mapstructure:"access_key"` // 为空时使用 AWS_ACCESS_KEY_ID 环境变量
+	SecretKey string `mapstructure:"secret_key"` // 为空时使用 AWS_SECRET_ACCESS_KEY 环境变量
+	PathStyle bool   `mapstructure:"path_style"` // 使用 endpoint/bucket/key 形式的地址，MinIO 等需要开启
+}
+
+// Purge 定义部署成功后刷新 CDN 缓存
+// 根据推送中变更的文章计算受影响的页面（文章页、归档、标签与分类页），再调用各个刷新接口
+type Purge struct {
+	Enabled       bool           `mapstructure:"enabled"`
+	SiteURL       string         `mapstructure:"site_url"`       // 站点的公开地址，如 https://blog.example.com，刷新的 URL 都以它开头
+	Permalink     string         `mapstructure:"permalink"`      // 与 Hexo _config.yml 中的 permalink 相同，默认与 smoke.permalink 相同
+	Paths         []string       `mapstructure:"paths"`          // 每次部署都刷新的路径，默认为首页、归档页与订阅
+	ArchiveDir    string         `mapstructure:"archive_dir"`    // 与 Hexo 的 archive_dir 相同
+	TagDir        string         `mapstructure:"tag_dir"`        // 与 Hexo 的 tag_dir 相同
+	CategoryDir   string         `mapstructure:"category_dir"`   // 与 Hexo 的 category_dir 相同
+	PaginationDir string         `mapstructure:"pagination_dir"` // 与 Hexo 的 pagination_dir 相同
+	Timeout       string         `mapstructure:"timeout"`        // 单个请求的超时时间
+	Backends      []PurgeBackend `mapstructure:"backends"`
+}
+
+// PurgeBackend 定义一个刷新接口
+type PurgeBackend struct {
+	Name      string `mapstructure:"name"`
+	Type      string `mapstructure:"type"`       // cloudflare、http、tencent 或 aliyun
+	BaseURL   string `mapstructure:"base_url"`   // 接口地址，默认为服务商的正式地址；http 类型为缓存服务器地址，为空时直接请求页面地址
+	BatchSize int    `mapstructure:"batch_size"` // 每个请求中的 URL 数量，http 类型每个请求一个 URL
+
+	// cloudflare
+	ZoneID string `mapstructure:"zone_id"`
+	Token  string `mapstructure:"token"` // API 令牌，为空时使用 CLOUDFLARE_API_TOKEN 环境变量
+
+	// http
+	Method  string            `mapstructure:"method"`  // 请求方法，默认为 PURGE
+	Headers map[string]string `mapstructure:"headers"` // 附加的请求头，如认证信息
+
+	// tencent、aliyun
+	SecretID  string `mapstructure:"secret_id"`  // 腾讯云的 SecretId 或阿里云的 AccessKey ID
+	SecretKey string `mapstructure:"secret_key"` // 腾讯云的 SecretKey 或阿里云的 AccessKey Secret
+}
+
+// PurgeTypes 支持的刷新接口与默认地址
+var PurgeTypes = map[string]string{
+	"cloudflare": "https://api.cloudflare.com/client/v4",
+	"http":       "",
+	"tencent":    "https://cdn.tencentcloudapi.com",
+	"aliyun":     "https://cdn.aliyuncs.com",
+}
+
+// Smoke 定义部署后的冒烟检查，启用 releases 时检查失败会自动回滚到上一个版本
+type Smoke struct {
+	Enabled   bool         `mapstructure:"enabled"`
+	BaseURL   string       `mapstructure:"base_url"`   // 本地站点地址，如 http://127.0.0.1:4000
+	Timeout   string       `mapstructure:"timeout"`    // 单个请求的超时时间
+	Delay     string       `mapstructure:"delay"`      // 发布后等待多久再开始检查
+	Checks    []SmokeCheck `mapstructure:"checks"`     // HTTP 检查
+	Posts     bool         `mapstructure:"posts"`      // 检查推送中变更的文章页面能否访问
+	Permalink string       `mapstructure:"permalink"`  // 与 Hexo _config.yml 中的 permalink 相同
+	MinPages  int          `mapstructure:"min_pages"`  // 生成目录中至少应有的 HTML 页面数
+	PublicDir string       `mapstructure:"public_dir"` // 生成目录，启用 releases 时使用本次发布的版本目录
+}
+
+// SmokeCheck 定义一个 HTTP 检查
+type SmokeCheck struct {
+	Path       string `mapstructure:"path"`        // 请求路径，相对于 base_url
+	Status     int    `mapstructure:"status"`      // 期望的状态码
+	Contains   string `mapstructure:"contains"`    // 响应内容中应包含的文本
+	MaxLatency string `mapstructure:"max_latency"` // 最长响应时间
+}
+
+// Redact 定义脱敏规则
+// 脚本输出、日志、出站事件与接口响应中的密钥在写出前被替换
+type Redact struct {
+	Enabled  bool     `mapstructure:"enabled"`  // 默认开启
+	Mask     string   `mapstructure:"mask"`     // 替换成的内容
+	Env      []string `mapstructure:"env"`      // 名称匹配这些规则的环境变量与配置项的值会被替换，不区分大小写
+	Values   []string `mapstructure:"values"`   // 额外需要替换的值
+	Patterns []string `mapstructure:"patterns"` // 额外的正则表达式，含有分组时只替换第一个分组
+}
+
+// MinSecretLength 参与脱敏的值的最短长度，过短的值替换后会破坏大量正常输出
+const MinSecretLength = 4
+
+// Static 定义内置的静态文件服务，用于替代 hexo serve
+type Static struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Listen   string `mapstructure:"listen"`    // 监听地址，如 127.0.0.1:4080
+	Root     string `mapstructure:"root"`      // 站点目录，启用 releases 时默认为 releases.dir 下的 current
+	NotFound string `mapstructure:"not_found"` // 404 页面，相对于站点目录
+	MaxAge   string `mapstructure:"max_age"`   // HTML 以外的文件在浏览器中的缓存时间
+}
+
+// Preview 定义分支预览
+// 推送到非生产分支或收到拉取请求时，把博客构建到独立的预览目录中，由内置静态文件服务按路径或子域名提供
+type Preview struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Production   []string `mapstructure:"production"`    // 生产分支，推送到这些分支时正常部署
+	Branches     []string `mapstructure:"branches"`      // 构建预览的分支，支持通配符，为空表示所有非生产分支
+	PullRequests bool     `mapstructure:"pull_requests"` // 是否为拉取请求构建预览
+	Dir          string   `mapstructure:"dir"`           // 预览根目录，每个预览位于其中的 <预览名>
+	WorkDir      string   `mapstructure:"work_dir"`      // 启用 git 时检出预览分支的工作树目录
+	URL          string   `mapstructure:"url"`           // 预览地址模板，{name} 替换为预览名
+	Steps        []Step   `mapstructure:"steps"`         // 构建预览的步骤，需要把生成的站点写入 PREVIEW_DIR
+}
+
+// Trigger 定义 release 与 create（标签）事件触发的部署
+// 设置了 steps 时按步骤执行，否则执行 script 脚本；启用 git 时先检出对应的标签
+type Trigger struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Actions []string `mapstructure:"actions"` // release 事件的动作，如 published、edited、deleted，默认为 published
+	Tags    []string `mapstructure:"tags"`    // 标签名，支持通配符，如 v*，为空表示所有标签
+	Script  string   `mapstructure:"script"`  // scripts.path 中的脚本
+	Steps   []Step   `mapstructure:"steps"`
+}
+
+// ReleaseActions GitHub release 事件的动作
+var ReleaseActions = []string{"published", "unpublished", "created", "edited", "deleted", "prereleased", "released"}
+
+// Lint 定义文章检查规则，供内置的 posts/lint 步骤与 posts lint 命令使用
+type Lint struct {
+	Required    []string `mapstructure:"required"`     // 必须填写的 front-matter 字段
+	DateFormats []string `mapstructure:"date_formats"` // date、updated 字段允许的格式（Go 时间格式）
+	Categories  []string `mapstructure:"categories"`   // 允许的分类，为空表示不限制
+	MinTags     int      `mapstructure:"min_tags"`     // 最少标签数
+	MaxTags     int      `mapstructure:"max_tags"`     // 最多标签数，为 0 表示不限制
+	SourceRoot  string   `mapstructure:"source_root"`  // Hexo 的 source 目录，用于查找 /images/... 形式的图片
+	FailOn      string   `mapstructure:"fail_on"`      // error、warning 或 never，发现该级别的问题时步骤失败
+}
+
+// BuiltinSteps 可以通过 uses 使用的内置步骤
+var BuiltinSteps = []string{"posts/preprocess", "posts/lint", "site/publish"}
+
+// current 当前生效的配置，热加载时整体原子替换
+var current atomic.Pointer[config]
+
+// configFile 当前使用的配置文件路径
+var configFile string
+
+// Get 返回当前生效的配置
+// 每次使用配置时都应重新调用 Get，而不是长期持有返回值，这样热加载后的修改才能生效
+func Get() *config {
+	return current.Load()
+}
+
+// Set 替换当前生效的配置，供测试使用 Load 读取后按需修改的配置
+func Set(c *config) {
+	current.Store(c)
+}
+
+// EnvPrefix 环境变量覆盖配置时使用的前缀
+// 例如 HEXO_AUTOCD_WEBHOOK_SECRET 会覆盖 webhook.secret
+const EnvPrefix = "HEXO_AUTOCD"
 
 // InitConfig 初始化配置
+// path 为空时依次在当前目录、$XDG_CONFIG_HOME/hexo-autocd 与 /etc/hexo-autocd 中查找 config.yaml
 // 注意：因为日志系统依赖于配置，所以在配置加载时我们还不能使用日志系统
 // 因此这里使用标准库的日志包作为临时解决方案
-func InitConfig() {
+// keys 非空时只检查这些配置项的问题，供 rollback 等只用到部分配置的命令使用，
+// 与之无关的配置项（如部署脚本不存在）不会阻止它们运行
+func InitConfig(path string, keys ...string) {
 	// 设置标准库日志格式
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 	log.Println("开始加载配置...")
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
+	v, err := read(path)
+	if err != nil {
+		fmt.Printf("致命错误: %v\n", err)
+		os.Exit(1)
+	}
+	configFile = v.ConfigFileUsed()
 
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Printf("致命错误: 读取配置文件失败: %v\n", err)
+	config, problems, err := load(v)
+	if err != nil {
+		fmt.Printf("致命错误: %v\n", err)
 		os.Exit(1)
 	}
+	if len(keys) > 0 {
+		problems = problems.For(keys...)
+	}
+
+	for _, p := range problems.Warnings() {
+		log.Printf("警告: %s", p)
+	}
+	if fatal := problems.Fatal(); len(fatal) > 0 {
+		fmt.Printf("致命错误: %v\n", fatal)
+		os.Exit(1)
+	}
+
+	log.Printf("配置文件加载成功: %s", configFile)
+	current.Store(config)
+}
+
+// Load 读取配置文件并填充默认值，但不因校验问题而失败
+// 供 sign、token 等只用到部分配置项的命令使用，它们不要求部署脚本等文件存在
+func Load(path string) (*config, error) {
+	v, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := load(v)
+	return config, err
+}
+
+// Check 读取并校验配置文件，同时试加载 SSL 证书
+// path 为空时按默认路径查找配置文件，返回实际使用的配置文件路径与发现的全部问题
+func Check(path string) (string, Problems, error) {
+	v, err := read(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	config, problems, err := load(v)
+	if err != nil {
+		return v.ConfigFileUsed(), nil, err
+	}
+
+	// 只有证书与私钥文件都存在时才尝试加载
+	if !hasKeyPrefix(problems.Fatal(), "ssl.") {
+		if err := CheckTLS(config); err != nil {
+			problems = append(problems, Problem{Key: "ssl", Message: err.Error(), Fatal: true})
+		}
+	}
+
+	return v.ConfigFileUsed(), problems, nil
+}
+
+// hasKeyPrefix 判断问题列表中是否存在指定前缀的配置项
+func hasKeyPrefix(problems Problems, prefix string) bool {
+	for _, p := range problems {
+		if strings.HasPrefix(p.Key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// read 读取配置文件，并启用环境变量覆盖
+// path 为空时按 SearchPaths 的顺序查找 config.yaml
+func read(path string) (*viper.Viper, error) {
+	v := viper.New()
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("yaml")
+		for _, dir := range SearchPaths() {
+			v.AddConfigPath(dir)
+		}
+	}
+
+	// HEXO_AUTOCD_SCRIPTS_TIMEOUT 这样的环境变量会覆盖 scripts.timeout
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	bindEnvs(v, reflect.TypeOf(config{}), "")
+
+	// 布尔配置项默认为 true 时无法在 load 中根据零值判断，在这里设置默认值
+	v.SetDefault("posts.categories_from_dir", true)
+	v.SetDefault("redact.enabled", true)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	return v, nil
+}
+
+// SearchPaths 返回未指定配置文件时查找 config.yaml 的目录
+func SearchPaths() []string {
+	paths := []string{"."}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "hexo-autocd"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "hexo-autocd"))
+	}
+	return append(paths, "/etc/hexo-autocd")
+}
 
+// bindEnvs 为配置结构体中的每个配置项绑定环境变量
+// AutomaticEnv 只对 viper 已知的配置项生效，显式绑定后配置文件中缺失的项也能被环境变量设置
+func bindEnvs(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if field.Type.Kind() == reflect.Struct {
+			bindEnvs(v, field.Type, key)
+			continue
+		}
+		v.BindEnv(key)
+	}
+}
+
+// load 从已读取配置文件的 viper 实例中解析配置，填充默认值并进行校验
+// 返回的 Problems 包含全部校验问题，由调用方决定如何处理
+func load(v *viper.Viper) (*config, Problems, error) {
 	var config config
 
-	if err := viper.Unmarshal(&config); err != nil {
-		fmt.Printf("致命错误: 解析配置文件失败: %v\n", err)
-		os.Exit(1)
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
 	// 检查必要的配置项
@@ -80,6 +623,17 @@ func InitConfig() {
 		config.Logs.Path = "./logs/webhooks.log"
 	}
 
+	if config.Scripts.Timeout == "" {
+		config.Scripts.Timeout = "5m" // 默认超时5分钟
+	}
+
+	if config.Scripts.Sandbox.Env == "" {
+		config.Scripts.Sandbox.Env = "inherit"
+	}
+	if config.Scripts.MaxConcurrent == 0 {
+		config.Scripts.MaxConcurrent = 5 // 默认最大并发数
+	}
+
 	if config.Logs.Level == "" {
 		log.Println("警告: 日志级别未设置，使用默认级别info")
 		config.Logs.Level = "info"
@@ -98,6 +652,290 @@ func InitConfig() {
 		config.Logs.MaxAge = 30 // 默认保留30天
 	}
 
-	log.Println("配置文件加载成功")
-	Config = &config
+	if config.Site.Name == "" {
+		config.Site.Name = "blog"
+	}
+
+	if config.Git.Remote == "" {
+		config.Git.Remote = "origin"
+	}
+
+	if config.Git.Timeout == "" {
+		config.Git.Timeout = "2m"
+	}
+
+	if config.Git.VerifySignatures.Scope == "" {
+		config.Git.VerifySignatures.Scope = "head"
+	}
+
+	if config.Pipeline.File == "" {
+		config.Pipeline.File = ".hexo-autocd.yml"
+	}
+
+	if config.Posts.SourceDir == "" {
+		config.Posts.SourceDir = config.Site.RepoDir
+	}
+
+	if config.Posts.TagSeparator == "" {
+		config.Posts.TagSeparator = "&"
+	}
+
+	if config.Posts.DateFormat == "" {
+		config.Posts.DateFormat = "2006-01-02 15:04:05"
+	}
+
+	if len(config.Posts.Lint.Required) == 0 {
+		config.Posts.Lint.Required = []string{"title", "date"}
+	}
+
+	if len(config.Posts.Lint.DateFormats) == 0 {
+		config.Posts.Lint.DateFormats = []string{config.Posts.DateFormat, "2006-01-02 15:04", "2006-01-02", time.RFC3339}
+	}
+
+	if config.Posts.Lint.SourceRoot == "" && config.Posts.DestDir != "" {
+		config.Posts.Lint.SourceRoot = filepath.Dir(config.Posts.DestDir)
+	}
+
+	if config.Posts.Lint.FailOn == "" {
+		config.Posts.Lint.FailOn = "error"
+	}
+
+	if config.Releases.Keep == 0 {
+		config.Releases.Keep = 5
+	}
+
+	if config.Executor.Type == "" {
+		config.Executor.Type = "local"
+	}
+
+	if config.Executor.Docker.Host == "" {
+		config.Executor.Docker.Host = "unix:///var/run/docker.sock"
+	}
+
+	if config.Executor.Docker.Pull == "" {
+		config.Executor.Docker.Pull = "missing"
+	}
+
+	if config.Publish.Source == "" && config.Site.RepoDir != "" {
+		config.Publish.Source = filepath.Join(config.Site.RepoDir, "public")
+	}
+
+	for i, target := range config.Publish.Targets {
+		t := &config.Publish.Targets[i]
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("%s-%d", target.Type, i+1)
+		}
+		if t.Type == "sftp" && t.KnownHosts == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				t.KnownHosts = filepath.Join(home, ".ssh", "known_hosts")
+			}
+		}
+		if t.Type == "s3" {
+			if t.Region == "" {
+				t.Region = "us-east-1"
+			}
+			if t.AccessKey == "" {
+				t.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+			}
+			if t.SecretKey == "" {
+				t.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			}
+		}
+	}
+
+	if config.Executor.SSH.KnownHosts == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			config.Executor.SSH.KnownHosts = filepath.Join(home, ".ssh", "known_hosts")
+		}
+	}
+
+	if config.Executor.SSH.Strategy == "" {
+		config.Executor.SSH.Strategy = "rolling"
+	}
+
+	if config.Executor.SSH.ConnectTimeout == "" {
+		config.Executor.SSH.ConnectTimeout = "10s"
+	}
+
+	for i, host := range config.Executor.SSH.Hosts {
+		if host.Name == "" {
+			config.Executor.SSH.Hosts[i].Name = host.Address
+		}
+		if host.User == "" {
+			config.Executor.SSH.Hosts[i].User = config.Executor.SSH.User
+		}
+	}
+
+	if len(config.Releases.Events) == 0 {
+		config.Releases.Events = []string{"push", "release", "tag"}
+	}
+
+	if len(config.Triggers.Release.Actions) == 0 {
+		config.Triggers.Release.Actions = []string{"published"}
+	}
+
+	if config.Smoke.Timeout == "" {
+		config.Smoke.Timeout = "10s"
+	}
+
+	if config.Smoke.Permalink == "" {
+		config.Smoke.Permalink = ":year/:month/:day/:title/" // Hexo 的默认值
+	}
+
+	for i := range config.Smoke.Checks {
+		if config.Smoke.Checks[i].Status == 0 {
+			config.Smoke.Checks[i].Status = 200
+		}
+	}
+
+	if config.Purge.Permalink == "" {
+		config.Purge.Permalink = config.Smoke.Permalink
+	}
+
+	if config.Purge.ArchiveDir == "" {
+		config.Purge.ArchiveDir = "archives"
+	}
+
+	if config.Purge.TagDir == "" {
+		config.Purge.TagDir = "tags"
+	}
+
+	if config.Purge.CategoryDir == "" {
+		config.Purge.CategoryDir = "categories"
+	}
+
+	if config.Purge.PaginationDir == "" {
+		config.Purge.PaginationDir = "page"
+	}
+
+	if len(config.Purge.Paths) == 0 {
+		config.Purge.Paths = []string{"/", "/" + strings.Trim(config.Purge.ArchiveDir, "/") + "/", "/atom.xml", "/sitemap.xml"}
+	}
+
+	if config.Purge.Timeout == "" {
+		config.Purge.Timeout = "30s"
+	}
+
+	if config.Redact.Mask == "" {
+		config.Redact.Mask = "***"
+	}
+	if len(config.Redact.Env) == 0 {
+		config.Redact.Env = []string{"*TOKEN*", "*SECRET*", "*PASSWORD*", "*PASSWD*", "*_KEY", "*APIKEY*", "*CREDENTIAL*", "AUTHORIZATION"}
+	}
+
+	for i, backend := range config.Purge.Backends {
+		b := &config.Purge.Backends[i]
+		if b.Name == "" {
+			b.Name = fmt.Sprintf("%s-%d", backend.Type, i+1)
+		}
+		if b.BaseURL == "" {
+			b.BaseURL = PurgeTypes[backend.Type]
+		}
+		switch b.Type {
+		case "cloudflare":
+			if b.BatchSize == 0 {
+				b.BatchSize = 30 // 单个请求最多 30 个 URL
+			}
+			if b.Token == "" {
+				b.Token = os.Getenv("CLOUDFLARE_API_TOKEN")
+			}
+		case "http":
+			if b.Method == "" {
+				b.Method = "PURGE"
+			}
+		case "tencent":
+			if b.BatchSize == 0 {
+				b.BatchSize = 100
+			}
+			if b.SecretID == "" {
+				b.SecretID = os.Getenv("TENCENTCLOUD_SECRET_ID")
+			}
+			if b.SecretKey == "" {
+				b.SecretKey = os.Getenv("TENCENTCLOUD_SECRET_KEY")
+			}
+		case "aliyun":
+			if b.BatchSize == 0 {
+				b.BatchSize = 100
+			}
+			if b.SecretID == "" {
+				b.SecretID = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID")
+			}
+			if b.SecretKey == "" {
+				b.SecretKey = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET")
+			}
+		}
+	}
+
+	if config.Static.Listen == "" {
+		config.Static.Listen = "127.0.0.1:4080" // 避开 hexo serve 的默认端口 4000
+	}
+
+	if config.Static.Root == "" && config.Releases.Enabled && config.Releases.Dir != "" {
+		config.Static.Root = filepath.Join(config.Releases.Dir, "current")
+	}
+
+	if config.Static.NotFound == "" {
+		config.Static.NotFound = "404.html"
+	}
+
+	if config.Static.MaxAge == "" {
+		config.Static.MaxAge = "1h"
+	}
+
+	if len(config.Preview.Production) == 0 {
+		config.Preview.Production = []string{"main", "master"}
+	}
+
+	if config.Preview.WorkDir == "" && config.Preview.Dir != "" {
+		config.Preview.WorkDir = filepath.Clean(config.Preview.Dir) + "-work"
+	}
+
+	if config.Outbound.Outbox == "" {
+		config.Outbound.Outbox = filepath.Join(filepath.Dir(config.Logs.Path), "outbox")
+	}
+
+	if config.Outbound.DeliveryLog == "" {
+		config.Outbound.DeliveryLog = filepath.Join(filepath.Dir(config.Logs.Path), "deliveries.log")
+	}
+
+	if config.Outbound.MaxAttempts == 0 {
+		config.Outbound.MaxAttempts = 8 // 默认最多投递8次
+	}
+
+	if config.Outbound.Backoff == "" {
+		config.Outbound.Backoff = "2s" // 默认首次重试间隔2秒
+	}
+
+	return &config, append(unknownKeys(v), Validate(&config)...), nil
+}
+
+// LoadPipelineFile 读取博客仓库中的流水线文件
+// 文件格式与配置文件中的 pipeline 部分相同，返回 nil 表示文件不存在
+func LoadPipelineFile(path string) (*Pipeline, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取流水线文件失败: %v", err)
+	}
+
+	var pipeline Pipeline
+	if err := v.Unmarshal(&pipeline); err != nil {
+		return nil, fmt.Errorf("解析流水线文件失败: %v", err)
+	}
+	problems := ValidateSteps("steps", pipeline.Steps)
+	for i, step := range pipeline.Steps {
+		if step.Sandbox != nil {
+			// 仓库中的文件随推送变化，不能借此放宽服务端设置的限制
+			problems = append(problems, Problem{Key: fmt.Sprintf("steps[%d].sandbox", i), Message: "仓库中的流水线文件不能设置", Fatal: true})
+		}
+	}
+	if problems := problems.Fatal(); len(problems) > 0 {
+		return nil, problems
+	}
+	return &pipeline, nil
 }
@@ -0,0 +1,845 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Problem 描述配置中的一个问题
+type Problem struct {
+	Key     string // 出现问题的 YAML 配置项，如 scripts.push
+	Message string // 问题描述
+	Fatal   bool   // 是否为致命错误，存在致命错误时拒绝启动
+}
+
+// String 返回问题的可读描述
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+// Problems 配置校验发现的全部问题
+type Problems []Problem
+
+// Fatal 返回其中的致命错误
+func (ps Problems) Fatal() Problems {
+	var fatal Problems
+	for _, p := range ps {
+		if p.Fatal {
+			fatal = append(fatal, p)
+		}
+	}
+	return fatal
+}
+
+// Warnings 返回其中的警告
+func (ps Problems) Warnings() Problems {
+	var warnings Problems
+	for _, p := range ps {
+		if !p.Fatal {
+			warnings = append(warnings, p)
+		}
+	}
+	return warnings
+}
+
+// For 返回其中属于指定配置项（含其子项）的问题
+func (ps Problems) For(keys ...string) Problems {
+	var matched Problems
+	for _, p := range ps {
+		for _, key := range keys {
+			if p.Key == key || strings.HasPrefix(p.Key, key+".") || strings.HasPrefix(p.Key, key+"[") {
+				matched = append(matched, p)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Error 实现 error 接口，一次性列出所有问题
+func (ps Problems) Error() string {
+	lines := make([]string, 0, len(ps))
+	for _, p := range ps {
+		lines = append(lines, p.String())
+	}
+	return fmt.Sprintf("配置校验失败，共 %d 个问题:\n  - %s", len(ps), strings.Join(lines, "\n  - "))
+}
+
+// validator 收集校验过程中发现的问题
+type validator struct {
+	problems Problems
+}
+
+func (v *validator) fatalf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...), Fatal: true})
+}
+
+func (v *validator) warnf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// duration 校验时间间隔格式
+func (v *validator) duration(key, value string) {
+	if value == "" {
+		return
+	}
+	if d, err := time.ParseDuration(value); err != nil {
+		v.fatalf(key, "不是合法的时间间隔: %q（示例：30s、5m、1h）", value)
+	} else if d <= 0 {
+		v.fatalf(key, "必须大于 0: %q", value)
+	}
+}
+
+// patterns 校验通配符规则，** 作为整段时表示任意层级目录
+func (v *validator) patterns(key string, patterns []string) {
+	for _, pattern := range patterns {
+		for _, segment := range strings.Split(pattern, "/") {
+			if segment == "**" {
+				continue
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				v.fatalf(key, "不是合法的通配符: %q", pattern)
+				break
+			}
+		}
+	}
+}
+
+// retry 校验重试策略
+func (v *validator) retry(key string, r Retry) {
+	if r.Retries < 0 {
+		v.fatalf(key+".retries", "不能为负数: %d", r.Retries)
+	}
+	v.duration(key+".delay", r.Delay)
+	v.duration(key+".max_delay", r.MaxDelay)
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		v.fatalf(key+".multiplier", "不能小于 1: %v", r.Multiplier)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		v.fatalf(key+".jitter", "必须在 0 到 1 之间: %v", r.Jitter)
+	}
+	for i, pattern := range r.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.fatalf(fmt.Sprintf("%s.patterns[%d]", key, i), "不是合法的正则表达式: %v", err)
+		}
+	}
+	if r.Retries == 0 && (r.Delay != "" || len(r.ExitCodes) > 0 || len(r.Patterns) > 0) {
+		v.warnf(key+".retries", "为 0，不会重试")
+	}
+}
+
+// sandbox 校验脚本的执行限制
+func (v *validator) sandbox(key string, s Sandbox) {
+	if s.Env != "" && s.Env != "inherit" && s.Env != "clean" {
+		v.fatalf(key+".env", "不支持的环境变量策略: %s（可选：inherit、clean）", s.Env)
+	}
+	if len(s.EnvAllow) > 0 && s.Env != "clean" {
+		v.warnf(key+".env_allow", "只在 env 为 clean 时生效")
+	}
+	for _, pattern := range s.EnvAllow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			v.fatalf(key+".env_allow", "不是合法的通配符: %q", pattern)
+		}
+	}
+	if !s.Isolated() {
+		return
+	}
+	if runtime.GOOS != "linux" {
+		v.fatalf(key, "用户、资源限制、chroot 与 Landlock 只在 Linux 上支持")
+		return
+	}
+
+	if s.User != "" {
+		if _, err := lookupUser(s.User); err != nil {
+			v.fatalf(key+".user", "用户不存在: %s", s.User)
+		}
+	}
+	if s.Group != "" {
+		if _, err := lookupGroup(s.Group); err != nil {
+			v.fatalf(key+".group", "用户组不存在: %s", s.Group)
+		}
+	}
+	if (s.User != "" || s.Group != "" || s.Chroot != "") && os.Geteuid() != 0 {
+		v.warnf(key, "切换用户与 chroot 需要服务以 root 运行")
+	}
+	if s.Processes > 0 && s.User == "" {
+		v.warnf(key+".processes", "进程数按用户计算，未设置 user 时会把服务用户的所有进程计算在内")
+	}
+
+	v.duration(key+".cpu", s.CPU)
+	if s.Memory != "" {
+		if _, err := ParseBytes(s.Memory); err != nil {
+			v.fatalf(key+".memory", "%v", err)
+		}
+	}
+	if s.Files < 0 {
+		v.fatalf(key+".files", "不能为负数: %d", s.Files)
+	}
+	if s.Processes < 0 {
+		v.fatalf(key+".processes", "不能为负数: %d", s.Processes)
+	}
+
+	if s.Chroot != "" {
+		if !filepath.IsAbs(s.Chroot) {
+			v.fatalf(key+".chroot", "必须是绝对路径: %s", s.Chroot)
+		} else if info, err := os.Stat(s.Chroot); err != nil || !info.IsDir() {
+			v.fatalf(key+".chroot", "目录不存在: %s", s.Chroot)
+		} else if _, err := os.Stat(filepath.Join(s.Chroot, "bin", "bash")); err != nil {
+			v.warnf(key+".chroot", "目录中没有 /bin/bash，脚本无法执行")
+		}
+	}
+	// 工作目录、RELEASE_DIR 等可写路径是主机上的路径，chroot 之后会按新的根目录解析，指向其他目录或不存在
+	if s.Chroot != "" && s.Landlock {
+		v.fatalf(key+".landlock", "不能与 chroot 同时使用，可写路径在 chroot 之后无法对应到主机上的目录")
+	}
+	if len(s.Writable) > 0 && !s.Landlock {
+		v.warnf(key+".writable", "只在启用 landlock 时生效")
+	}
+	for i, dir := range s.Writable {
+		if !filepath.IsAbs(dir) {
+			v.fatalf(fmt.Sprintf("%s.writable[%d]", key, i), "必须是绝对路径: %s", dir)
+		} else if _, err := os.Stat(dir); err != nil {
+			v.warnf(fmt.Sprintf("%s.writable[%d]", key, i), "目录不存在: %s", dir)
+		}
+	}
+}
+
+// lookupUser 按用户名或 UID 查找用户
+func lookupUser(name string) (*user.User, error) {
+	if u, err := user.Lookup(name); err == nil {
+		return u, nil
+	}
+	return user.LookupId(name)
+}
+
+// lookupGroup 按组名或 GID 查找用户组
+func lookupGroup(name string) (*user.Group, error) {
+	if g, err := user.LookupGroup(name); err == nil {
+		return g, nil
+	}
+	return user.LookupGroupId(name)
+}
+
+// trigger 校验 release 或标签事件触发的部署
+func (v *validator) trigger(key string, t Trigger, scriptsPath string) {
+	if !t.Enabled {
+		return
+	}
+	v.patterns(key+".tags", t.Tags)
+	if len(t.Steps) > 0 {
+		v.problems = append(v.problems, ValidateSteps(key+".steps", t.Steps)...)
+		if t.Script != "" {
+			v.warnf(key+".script", "已配置 steps，不会执行脚本 %s", t.Script)
+		}
+		return
+	}
+	if t.Script == "" {
+		v.fatalf(key, "启用时需要配置 script 或 steps")
+		return
+	}
+	script := filepath.Join(scriptsPath, t.Script)
+	if info, err := os.Stat(script); err != nil {
+		v.fatalf(key+".script", "脚本不存在: %s", script)
+	} else if !info.Mode().IsRegular() {
+		v.fatalf(key+".script", "不是普通文件: %s", script)
+	} else if info.Mode().Perm()&0111 == 0 {
+		v.fatalf(key+".script", "脚本没有执行权限: %s（执行 chmod +x）", script)
+	}
+}
+
+// invalidKeys 匹配严格解析时报告的未知配置项，如 'outbound.targets[0]' has invalid keys: urll, tokn
+var invalidKeys = regexp.MustCompile(`(?m)^'([^']*)' has invalid keys: (.+)$`)
+
+// unknownKeys 返回配置文件中不存在的配置项，通常是拼写错误，如 scirpts 或 timout
+// 宽松解析会直接忽略它们，导致配置看似生效实际没有作用
+func unknownKeys(v *viper.Viper) Problems {
+	var c config
+	err := v.UnmarshalExact(&c)
+	if err == nil {
+		return nil
+	}
+	var problems Problems
+	for _, m := range invalidKeys.FindAllStringSubmatch(err.Error(), -1) {
+		for _, key := range strings.Split(m[2], ", ") {
+			if m[1] != "" {
+				key = m[1] + "." + key
+			}
+			problems = append(problems, Problem{Key: key, Message: "未知的配置项，请检查拼写", Fatal: true})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+	return problems
+}
+
+// Validate 校验配置，返回发现的全部问题
+// 只检查配置本身以及它引用的文件是否存在，不会加载证书
+func Validate(c *config) Problems {
+	v := &validator{}
+
+	// webhook
+	if c.Webhook.Port < 1 || c.Webhook.Port > 65535 {
+		v.fatalf("webhook.port", "端口必须在 1-65535 之间: %d", c.Webhook.Port)
+	}
+	if !strings.HasPrefix(c.Webhook.Path, "/") {
+		v.fatalf("webhook.path", "必须以 / 开头: %q", c.Webhook.Path)
+	}
+	if c.Webhook.Secret == "" {
+		v.fatalf("webhook.secret", "不能为空，否则无法校验 GitHub 签名")
+	} else if len(c.Webhook.Secret) < 16 {
+		v.warnf("webhook.secret", "长度只有 %d 个字符，建议至少 16 个字符", len(c.Webhook.Secret))
+	}
+
+	// scripts
+	if c.Scripts.Path == "" {
+		v.fatalf("scripts.path", "不能为空")
+	} else if info, err := os.Stat(c.Scripts.Path); err != nil {
+		v.fatalf("scripts.path", "目录不存在: %s", c.Scripts.Path)
+	} else if !info.IsDir() {
+		v.fatalf("scripts.path", "不是目录: %s", c.Scripts.Path)
+	} else if c.Scripts.Push == "" && len(c.Pipeline.Steps) == 0 {
+		v.fatalf("scripts.push", "未配置 pipeline.steps 时不能为空")
+	} else if c.Scripts.Push == "" {
+		// 使用流水线时不需要部署脚本
+	} else {
+		script := filepath.Join(c.Scripts.Path, c.Scripts.Push)
+		if info, err := os.Stat(script); err != nil {
+			v.fatalf("scripts.push", "脚本不存在: %s", script)
+		} else if !info.Mode().IsRegular() {
+			v.fatalf("scripts.push", "不是普通文件: %s", script)
+		} else if info.Mode().Perm()&0111 == 0 {
+			v.fatalf("scripts.push", "脚本没有执行权限: %s（执行 chmod +x）", script)
+		}
+	}
+	v.duration("scripts.timeout", c.Scripts.Timeout)
+	if c.Scripts.MaxConcurrent < 0 {
+		v.fatalf("scripts.max_concurrent", "不能为负数: %d", c.Scripts.MaxConcurrent)
+	}
+	v.retry("scripts.retry", c.Scripts.Retry)
+	v.sandbox("scripts.sandbox", c.Scripts.Sandbox)
+	if c.Executor.Type != "local" && (c.Scripts.Sandbox.Isolated() || c.Scripts.Sandbox.Env == "clean") {
+		v.warnf("scripts.sandbox", "只在本机执行时生效，executor.type 为 %s 时不起作用", c.Executor.Type)
+	}
+
+	// logs
+	if _, err := logrus.ParseLevel(c.Logs.Level); err != nil {
+		v.fatalf("logs.level", "不是合法的日志级别: %q（可选：trace、debug、info、warn、error、fatal、panic）", c.Logs.Level)
+	}
+	if format := strings.ToLower(c.Logs.Format); format != "" && format != "text" && format != "json" {
+		v.warnf("logs.format", "未知的日志格式 %q，将使用 text", c.Logs.Format)
+	}
+	if c.Logs.MaxSize < 0 || c.Logs.MaxBackups < 0 || c.Logs.MaxAge < 0 {
+		v.fatalf("logs", "max_size、max_backups、max_age 不能为负数")
+	}
+
+	// ssl
+	if c.SSL.Enabled {
+		if c.SSL.CertFile == "" {
+			v.fatalf("ssl.cert_file", "启用 SSL 时不能为空")
+		} else if _, err := os.Stat(c.SSL.CertFile); err != nil {
+			v.fatalf("ssl.cert_file", "证书文件不存在: %s", c.SSL.CertFile)
+		}
+		if c.SSL.KeyFile == "" {
+			v.fatalf("ssl.key_file", "启用 SSL 时不能为空")
+		} else if _, err := os.Stat(c.SSL.KeyFile); err != nil {
+			v.fatalf("ssl.key_file", "私钥文件不存在: %s", c.SSL.KeyFile)
+		}
+	}
+
+	// site
+	if c.Site.RepoDir != "" {
+		if info, err := os.Stat(c.Site.RepoDir); err != nil || !info.IsDir() {
+			v.warnf("site.repo_dir", "目录不存在: %s", c.Site.RepoDir)
+		}
+	}
+
+	// git
+	if c.Git.Enabled && c.Site.RepoDir == "" {
+		v.fatalf("git.enabled", "需要设置 site.repo_dir")
+	}
+	v.duration("git.timeout", c.Git.Timeout)
+	v.patterns("git.branches", c.Git.Branches)
+	v.retry("git.retry", c.Git.Retry)
+	if len(c.Git.Retry.ExitCodes) > 0 {
+		v.warnf("git.retry.exit_codes", "检出失败没有退出码，不会生效")
+	}
+	if verify := c.Git.VerifySignatures; verify.Enabled {
+		if !c.Git.Enabled {
+			v.fatalf("git.verify_signatures.enabled", "需要同时启用 git.enabled")
+		}
+		if verify.Scope != "head" && verify.Scope != "all" {
+			v.fatalf("git.verify_signatures.scope", "只能是 head 或 all: %q", verify.Scope)
+		}
+		if verify.GPGHome == "" && verify.AllowedSigners == "" {
+			v.fatalf("git.verify_signatures", "gpg_home 与 allowed_signers 至少需要设置一个")
+		}
+		if verify.GPGHome != "" {
+			if info, err := os.Stat(verify.GPGHome); err != nil || !info.IsDir() {
+				v.fatalf("git.verify_signatures.gpg_home", "目录不存在: %s", verify.GPGHome)
+			}
+		}
+		if verify.AllowedSigners != "" {
+			if _, err := os.Stat(verify.AllowedSigners); err != nil {
+				v.fatalf("git.verify_signatures.allowed_signers", "文件不存在: %s", verify.AllowedSigners)
+			}
+		}
+	}
+
+	// pipeline
+	v.problems = append(v.problems, ValidateSteps("pipeline.steps", c.Pipeline.Steps)...)
+	if c.Pipeline.AllowRepoFile && c.Site.RepoDir == "" {
+		v.warnf("pipeline.allow_repo_file", "未设置 site.repo_dir，仓库中的流水线文件不会生效")
+	}
+
+	switch c.Posts.Lint.FailOn {
+	case "error", "warning", "never":
+	default:
+		v.fatalf("posts.lint.fail_on", "只能是 error、warning 或 never: %q", c.Posts.Lint.FailOn)
+	}
+	if c.Posts.Lint.MinTags < 0 || c.Posts.Lint.MaxTags < 0 {
+		v.fatalf("posts.lint", "min_tags、max_tags 不能为负数")
+	} else if c.Posts.Lint.MaxTags > 0 && c.Posts.Lint.MinTags > c.Posts.Lint.MaxTags {
+		v.fatalf("posts.lint.min_tags", "不能大于 max_tags")
+	}
+
+	// releases
+	if c.Releases.Enabled {
+		if c.Releases.Dir == "" {
+			v.fatalf("releases.dir", "启用 releases 时不能为空")
+		} else if !filepath.IsAbs(c.Releases.Dir) {
+			v.fatalf("releases.dir", "必须是绝对路径: %s", c.Releases.Dir)
+		}
+		if c.Releases.Keep < 1 {
+			v.fatalf("releases.keep", "必须大于 0: %d", c.Releases.Keep)
+		}
+		if c.Releases.Source != "" && c.Releases.Source == c.Releases.Dir {
+			v.fatalf("releases.source", "不能与 releases.dir 相同")
+		}
+		for _, event := range c.Releases.Events {
+			if event != "push" && event != "release" && event != "tag" {
+				v.fatalf("releases.events", "不支持的事件: %s（可选：push、release、tag）", event)
+			}
+		}
+	}
+
+	// executor
+	switch c.Executor.Type {
+	case "local":
+	case "docker":
+		d := c.Executor.Docker
+		if u, err := url.Parse(d.Host); err != nil || (u.Scheme != "unix" && u.Scheme != "tcp") {
+			v.fatalf("executor.docker.host", "不是合法的地址: %q（示例：unix:///var/run/docker.sock、tcp://127.0.0.1:2375）", d.Host)
+		} else if u.Scheme == "unix" {
+			if _, err := os.Stat(u.Path); err != nil {
+				v.warnf("executor.docker.host", "套接字不存在: %s", u.Path)
+			}
+		}
+		if d.Image == "" {
+			v.fatalf("executor.docker.image", "使用 docker 执行器时不能为空")
+		}
+		if d.Pull != "missing" && d.Pull != "always" && d.Pull != "never" {
+			v.fatalf("executor.docker.pull", "不支持的拉取策略: %s（可选：missing、always、never）", d.Pull)
+		}
+		for i, mount := range d.Mounts {
+			if host, _, _ := strings.Cut(mount, ":"); !filepath.IsAbs(host) {
+				v.fatalf(fmt.Sprintf("executor.docker.mounts[%d]", i), "主机路径必须是绝对路径: %s", mount)
+			}
+		}
+		if d.Memory != "" {
+			if _, err := ParseBytes(d.Memory); err != nil {
+				v.fatalf("executor.docker.memory", "%v", err)
+			}
+		}
+		if d.CPUs < 0 {
+			v.fatalf("executor.docker.cpus", "不能为负数: %v", d.CPUs)
+		}
+	case "ssh":
+		s := c.Executor.SSH
+		if len(s.Hosts) == 0 {
+			v.fatalf("executor.ssh.hosts", "使用 ssh 执行器时至少需要一台主机")
+		}
+		names := make(map[string]bool)
+		for i, host := range s.Hosts {
+			key := fmt.Sprintf("executor.ssh.hosts[%d]", i)
+			if host.Address == "" {
+				v.fatalf(key+".address", "不能为空")
+			}
+			if host.User == "" {
+				v.fatalf(key+".user", "不能为空，也可以设置 executor.ssh.user")
+			}
+			if names[host.Name] {
+				v.fatalf(key+".name", "主机名称重复: %s", host.Name)
+			}
+			names[host.Name] = true
+		}
+		if s.IdentityFile == "" && !s.Agent {
+			v.fatalf("executor.ssh.identity_file", "需要设置私钥文件或启用 executor.ssh.agent")
+		}
+		if s.IdentityFile != "" {
+			if _, err := os.Stat(s.IdentityFile); err != nil {
+				v.fatalf("executor.ssh.identity_file", "无法读取私钥文件: %v", err)
+			}
+		}
+		if s.Agent && os.Getenv("SSH_AUTH_SOCK") == "" {
+			v.warnf("executor.ssh.agent", "未设置 SSH_AUTH_SOCK 环境变量，无法使用 ssh-agent")
+		}
+		if _, err := os.Stat(s.KnownHosts); err != nil {
+			v.fatalf("executor.ssh.known_hosts", "无法读取 known_hosts 文件: %v（可用 ssh-keyscan 生成）", err)
+		}
+		if s.Strategy != "rolling" && s.Strategy != "parallel" {
+			v.fatalf("executor.ssh.strategy", "不支持的策略: %s（可选：rolling、parallel）", s.Strategy)
+		}
+		v.duration("executor.ssh.connect_timeout", s.ConnectTimeout)
+	default:
+		v.fatalf("executor.type", "不支持的执行器: %s（可选：local、docker、ssh）", c.Executor.Type)
+	}
+
+	// publish
+	targets := make(map[string]bool)
+	for i, target := range c.Publish.Targets {
+		key := fmt.Sprintf("publish.targets[%d]", i)
+		if targets[target.Name] {
+			v.fatalf(key+".name", "名称重复: %q", target.Name)
+		}
+		targets[target.Name] = true
+		switch target.Type {
+		case "local":
+			if !filepath.IsAbs(target.Path) {
+				v.fatalf(key+".path", "必须是绝对路径: %q", target.Path)
+			}
+		case "sftp":
+			if target.Address == "" {
+				v.fatalf(key+".address", "不能为空")
+			}
+			if target.User == "" {
+				v.fatalf(key+".user", "不能为空")
+			}
+			if !path.IsAbs(target.Path) {
+				v.fatalf(key+".path", "必须是绝对路径: %q", target.Path)
+			}
+			if target.IdentityFile == "" && !target.Agent {
+				v.fatalf(key+".identity_file", "需要设置私钥文件或启用 agent")
+			}
+			if _, err := os.Stat(target.KnownHosts); err != nil {
+				v.fatalf(key+".known_hosts", "无法读取 known_hosts 文件: %v（可用 ssh-keyscan 生成）", err)
+			}
+		case "s3":
+			if u, err := url.Parse(target.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.fatalf(key+".endpoint", "不是合法的地址: %q", target.Endpoint)
+			}
+			if target.Bucket == "" {
+				v.fatalf(key+".bucket", "不能为空")
+			}
+			if target.AccessKey == "" || target.SecretKey == "" {
+				v.fatalf(key+".access_key", "未设置访问密钥，也可以使用 AWS_ACCESS_KEY_ID 与 AWS_SECRET_ACCESS_KEY 环境变量")
+			}
+		default:
+			v.fatalf(key+".type", "不支持的类型: %q（可选：local、sftp、s3）", target.Type)
+		}
+	}
+	v.patterns("publish.exclude", c.Publish.Exclude)
+	for i, rule := range c.Publish.CacheControl {
+		key := fmt.Sprintf("publish.cache_control[%d]", i)
+		if len(rule.Paths) == 0 {
+			v.fatalf(key+".paths", "不能为空")
+		}
+		v.patterns(key+".paths", rule.Paths)
+		if rule.Value == "" {
+			v.fatalf(key+".value", "不能为空")
+		}
+	}
+	for ext := range c.Publish.ContentTypes {
+		if !strings.HasPrefix(ext, ".") {
+			v.fatalf("publish.content_types", "扩展名需要以 . 开头: %q", ext)
+		}
+	}
+
+	// triggers
+	v.trigger("triggers.release", c.Triggers.Release, c.Scripts.Path)
+	for _, action := range c.Triggers.Release.Actions {
+		if !slices.Contains(ReleaseActions, action) {
+			v.fatalf("triggers.release.actions", "不支持的动作: %s（可选：%s）", action, strings.Join(ReleaseActions, "、"))
+		}
+	}
+	v.trigger("triggers.tag", c.Triggers.Tag, c.Scripts.Path)
+
+	// smoke
+	if c.Smoke.Enabled {
+		needsURL := c.Smoke.Posts || len(c.Smoke.Checks) > 0
+		if c.Smoke.BaseURL == "" && needsURL {
+			v.fatalf("smoke.base_url", "配置了 checks 或 posts 时不能为空")
+		} else if c.Smoke.BaseURL != "" {
+			if u, err := url.Parse(c.Smoke.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.fatalf("smoke.base_url", "不是合法的 http(s) 地址: %s", c.Smoke.BaseURL)
+			}
+		}
+		v.duration("smoke.timeout", c.Smoke.Timeout)
+		v.duration("smoke.delay", c.Smoke.Delay)
+		for i, check := range c.Smoke.Checks {
+			key := fmt.Sprintf("smoke.checks[%d]", i)
+			if !strings.HasPrefix(check.Path, "/") {
+				v.fatalf(key+".path", "必须以 / 开头: %q", check.Path)
+			}
+			if check.Status < 100 || check.Status > 599 {
+				v.fatalf(key+".status", "不是合法的状态码: %d", check.Status)
+			}
+			v.duration(key+".max_latency", check.MaxLatency)
+		}
+		if c.Smoke.Posts && c.Posts.SourceDir == "" {
+			v.fatalf("smoke.posts", "需要设置 posts.source_dir 或 site.repo_dir")
+		}
+		if c.Smoke.MinPages < 0 {
+			v.fatalf("smoke.min_pages", "不能为负数: %d", c.Smoke.MinPages)
+		} else if c.Smoke.MinPages > 0 && c.Smoke.PublicDir == "" && !c.Releases.Enabled {
+			v.fatalf("smoke.min_pages", "未启用 releases 时需要设置 smoke.public_dir")
+		}
+		if !c.Releases.Enabled {
+			v.warnf("smoke.enabled", "未启用 releases，检查失败时只会把部署标记为失败，无法自动回滚")
+		}
+	}
+
+	// purge
+	if c.Purge.Enabled {
+		if u, err := url.Parse(c.Purge.SiteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.fatalf("purge.site_url", "不是合法的 http(s) 地址: %q", c.Purge.SiteURL)
+		}
+		for _, p := range c.Purge.Paths {
+			if !strings.HasPrefix(p, "/") {
+				v.fatalf("purge.paths", "必须以 / 开头: %q", p)
+			}
+		}
+		v.duration("purge.timeout", c.Purge.Timeout)
+		if len(c.Purge.Backends) == 0 {
+			v.fatalf("purge.backends", "启用 purge 时不能为空")
+		}
+		backends := make(map[string]bool)
+		for i, backend := range c.Purge.Backends {
+			key := fmt.Sprintf("purge.backends[%d]", i)
+			if backends[backend.Name] {
+				v.fatalf(key+".name", "名称重复: %q", backend.Name)
+			}
+			backends[backend.Name] = true
+			if _, ok := PurgeTypes[backend.Type]; !ok {
+				v.fatalf(key+".type", "不支持的类型: %q（可选：cloudflare、http、tencent、aliyun）", backend.Type)
+				continue
+			}
+			if backend.BaseURL != "" {
+				if u, err := url.Parse(backend.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					v.fatalf(key+".base_url", "不是合法的 http(s) 地址: %q", backend.BaseURL)
+				}
+			}
+			if backend.BatchSize < 0 {
+				v.fatalf(key+".batch_size", "不能为负数: %d", backend.BatchSize)
+			}
+			switch backend.Type {
+			case "cloudflare":
+				if backend.ZoneID == "" {
+					v.fatalf(key+".zone_id", "不能为空")
+				}
+				if backend.Token == "" {
+					v.fatalf(key+".token", "未设置 API 令牌，也可以使用 CLOUDFLARE_API_TOKEN 环境变量")
+				}
+			case "http":
+				if backend.Method == "" || strings.ContainsAny(backend.Method, " \t\r\n") {
+					v.fatalf(key+".method", "不是合法的请求方法: %q", backend.Method)
+				}
+			case "tencent":
+				if backend.SecretID == "" || backend.SecretKey == "" {
+					v.fatalf(key+".secret_id", "未设置密钥，也可以使用 TENCENTCLOUD_SECRET_ID 与 TENCENTCLOUD_SECRET_KEY 环境变量")
+				}
+			case "aliyun":
+				if backend.SecretID == "" || backend.SecretKey == "" {
+					v.fatalf(key+".secret_id", "未设置密钥，也可以使用 ALIBABA_CLOUD_ACCESS_KEY_ID 与 ALIBABA_CLOUD_ACCESS_KEY_SECRET 环境变量")
+				}
+			}
+		}
+		if c.Posts.SourceDir == "" {
+			v.warnf("purge.enabled", "未设置 posts.source_dir 或 site.repo_dir，只会刷新 purge.paths 中的页面")
+		}
+	}
+
+	// redact
+	if c.Redact.Enabled {
+		for _, pattern := range c.Redact.Env {
+			if _, err := path.Match(strings.ToUpper(pattern), ""); err != nil {
+				v.fatalf("redact.env", "不是合法的通配符: %q", pattern)
+			}
+		}
+		for i, value := range c.Redact.Values {
+			if len(value) < MinSecretLength {
+				v.warnf(fmt.Sprintf("redact.values[%d]", i), "少于 %d 个字符，不会被替换", MinSecretLength)
+			}
+		}
+		for i, pattern := range c.Redact.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				v.fatalf(fmt.Sprintf("redact.patterns[%d]", i), "不是合法的正则表达式: %v", err)
+			}
+		}
+	} else {
+		v.warnf("redact.enabled", "已关闭脱敏，脚本输出中的密钥会原样写入日志与出站事件")
+	}
+
+	// static
+	if c.Static.Enabled {
+		if _, port, err := net.SplitHostPort(c.Static.Listen); err != nil {
+			v.fatalf("static.listen", "不是合法的监听地址: %q（示例：127.0.0.1:4080）", c.Static.Listen)
+		} else if port == strconv.Itoa(c.Webhook.Port) {
+			v.fatalf("static.listen", "不能与 webhook.port 使用同一个端口: %s", port)
+		}
+		if c.Static.Root == "" {
+			v.fatalf("static.root", "未启用 releases 时不能为空")
+		} else if _, err := os.Stat(c.Static.Root); err != nil {
+			// 启用 releases 时第一次发布前 current 还不存在
+			v.warnf("static.root", "目录不存在: %s", c.Static.Root)
+		}
+		if c.Static.MaxAge != "0" && c.Static.MaxAge != "0s" {
+			v.duration("static.max_age", c.Static.MaxAge)
+		}
+		if !c.Releases.Enabled {
+			v.warnf("static.enabled", "未启用 releases，hexo generate 原地写入期间访问者可能读到不完整的站点")
+		}
+	}
+
+	// preview
+	if c.Preview.Enabled {
+		// 未启用 git 时没有独立的工作树，预览会构建在生产仓库中并覆盖生产的生成目录
+		if !c.Git.Enabled {
+			v.fatalf("preview.enabled", "需要同时启用 git.enabled")
+		}
+		if c.Preview.Dir == "" {
+			v.fatalf("preview.dir", "启用 preview 时不能为空")
+		} else if !filepath.IsAbs(c.Preview.Dir) {
+			v.fatalf("preview.dir", "必须是绝对路径: %s", c.Preview.Dir)
+		}
+		if u, err := url.Parse(strings.ReplaceAll(c.Preview.URL, "{name}", "x")); err != nil || u.Host == "" {
+			v.fatalf("preview.url", "不是合法的地址: %q（示例：https://example.com/preview/{name}/）", c.Preview.URL)
+		} else if strings.Count(c.Preview.URL, "{name}") != 1 {
+			v.fatalf("preview.url", "必须包含一个 {name}: %s", c.Preview.URL)
+		}
+		v.patterns("preview.production", c.Preview.Production)
+		v.patterns("preview.branches", c.Preview.Branches)
+		if len(c.Preview.Steps) == 0 {
+			v.fatalf("preview.steps", "启用 preview 时不能为空")
+		}
+		v.problems = append(v.problems, ValidateSteps("preview.steps", c.Preview.Steps)...)
+		for i, step := range c.Preview.Steps {
+			// 内置文章步骤读写的是生产环境的文章目录
+			if step.Uses != "" {
+				v.fatalf(fmt.Sprintf("preview.steps[%d].uses", i), "预览步骤不能使用内置步骤: %s", step.Uses)
+			}
+		}
+		if !c.Static.Enabled {
+			v.warnf("preview.enabled", "未启用 static，需要自行配置 Web 服务器提供 %s 中的预览", c.Preview.Dir)
+		}
+	}
+
+	// api
+	if c.API.Token != "" && len(c.API.Token) < 16 {
+		v.warnf("api.token", "长度只有 %d 个字符，建议至少 16 个字符", len(c.API.Token))
+	}
+	if c.API.Token != "" && strings.HasPrefix(c.Webhook.Path, "/api/") {
+		v.fatalf("webhook.path", "启用 api.token 时不能以 /api/ 开头: %s", c.Webhook.Path)
+	}
+
+	// outbound
+	v.duration("outbound.backoff", c.Outbound.Backoff)
+	if c.Outbound.MaxAttempts < 1 {
+		v.fatalf("outbound.max_attempts", "必须大于 0: %d", c.Outbound.MaxAttempts)
+	}
+	names := make(map[string]bool)
+	for i, target := range c.Outbound.Targets {
+		prefix := fmt.Sprintf("outbound.targets[%d]", i)
+		if target.Name == "" {
+			v.fatalf(prefix+".name", "不能为空")
+		} else if names[target.Name] {
+			v.fatalf(prefix+".name", "名称重复: %q", target.Name)
+		}
+		names[target.Name] = true
+		if u, err := url.Parse(target.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.fatalf(prefix+".url", "不是合法的 http(s) 地址: %q", target.URL)
+		}
+		if target.Secret == "" {
+			v.warnf(prefix+".secret", "未设置，发送的事件将不带签名")
+		}
+		v.duration(prefix+".timeout", target.Timeout)
+	}
+
+	return v.problems
+}
+
+// ValidateSteps 校验流水线步骤，key 为问题中使用的配置项前缀
+func ValidateSteps(key string, steps []Step) Problems {
+	v := &validator{}
+	names := make(map[string]bool)
+	for i, step := range steps {
+		prefix := fmt.Sprintf("%s[%d]", key, i)
+		if step.Name == "" {
+			v.fatalf(prefix+".name", "不能为空")
+		} else if names[step.Name] {
+			v.fatalf(prefix+".name", "名称重复: %q", step.Name)
+		}
+		names[step.Name] = true
+		switch {
+		case step.Uses != "" && strings.TrimSpace(step.Run) != "":
+			v.fatalf(prefix+".uses", "不能与 run 同时设置")
+		case step.Uses != "":
+			known := false
+			for _, name := range BuiltinSteps {
+				known = known || name == step.Uses
+			}
+			if !known {
+				v.fatalf(prefix+".uses", "未知的内置步骤 %q（可选：%s）", step.Uses, strings.Join(BuiltinSteps, "、"))
+			}
+		case strings.TrimSpace(step.Run) == "":
+			v.fatalf(prefix+".run", "不能为空")
+		}
+		for _, env := range step.Env {
+			if k, _, ok := strings.Cut(env, "="); !ok || k == "" {
+				v.fatalf(prefix+".env", "必须是 KEY=VALUE 形式: %q", env)
+			}
+		}
+		v.duration(prefix+".timeout", step.Timeout)
+		v.duration(prefix+".retry_delay", step.RetryDelay)
+		if step.Retries < 0 {
+			v.fatalf(prefix+".retries", "不能为负数: %d", step.Retries)
+		}
+		if step.Retry != nil {
+			if step.Retries != 0 || step.RetryDelay != "" {
+				v.fatalf(prefix+".retry", "不能与 retries、retry_delay 同时设置")
+			}
+			v.retry(prefix+".retry", *step.Retry)
+		}
+		v.patterns(prefix+".when.branches", step.When.Branches)
+		v.patterns(prefix+".when.paths", step.When.Paths)
+		if step.Sandbox != nil {
+			v.sandbox(prefix+".sandbox", *step.Sandbox)
+		}
+	}
+	return v.problems
+}
+
+// CheckTLS 试加载 SSL 证书与私钥，确认二者可以配对使用
+func CheckTLS(c *config) error {
+	if !c.SSL.Enabled {
+		return nil
+	}
+	if _, err := tls.LoadX509KeyPair(c.SSL.CertFile, c.SSL.KeyFile); err != nil {
+		return fmt.Errorf("加载证书失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeConfig 在临时目录中写入配置文件与可执行的部署脚本，{{dir}} 替换为该目录
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "deploy.sh"), []byte("#!/bin/bash\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(content, "{{dir}}", dir)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// validConfig 返回可以通过校验的配置，scripts 与 extra 分别追加到 scripts 部分与文件末尾
+func validConfig(scripts, extra string) string {
+	return "webhook:\n  secret: 0123456789abcdef0123\n" +
+		"logs:\n  path: {{dir}}/logs/webhooks.log\n" +
+		"scripts:\n  path: {{dir}}\n  push: deploy.sh\n" + scripts + extra
+}
+
+func loadProblems(t *testing.T, content string) Problems {
+	t.Helper()
+	v, err := read(writeConfig(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, problems, err := load(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return problems
+}
+
+func TestValidateValid(t *testing.T) {
+	if fatal := loadProblems(t, validConfig("", "")).Fatal(); len(fatal) > 0 {
+		t.Fatalf("valid config has fatal problems: %v", fatal)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name, scripts, extra string
+		key                  string // 应出现的致命错误的配置项
+	}{
+		// 未知的配置项
+		{name: "unknown top-level key", extra: "webhok:\n  port: 8080\n", key: "webhok"},
+		{name: "unknown nested key", scripts: "  timout: 5m\n", key: "scripts.timout"},
+		{name: "unknown key in list", extra: "outbound:\n  targets:\n    - name: a\n      url: https://example.com\n      secrte: x\n", key: "outbound.targets[0].secrte"},
+
+		// 时间间隔
+		{name: "bad timeout", scripts: "  timeout: 5 minutes\n", key: "scripts.timeout"},
+		{name: "negative backoff", extra: "outbound:\n  backoff: -1s\n", key: "outbound.backoff"},
+		{name: "bad retry delay", scripts: "  retry:\n    retries: 2\n    delay: soon\n", key: "scripts.retry.delay"},
+
+		// 地址
+		{name: "target url scheme", extra: "outbound:\n  targets:\n    - name: a\n      url: ftp://example.com/hook\n", key: "outbound.targets[0].url"},
+		{name: "smoke base url", extra: "smoke:\n  enabled: true\n  base_url: example.com\n  checks:\n    - path: /\n      status: 200\n", key: "smoke.base_url"},
+
+		// 配置项之间的约束
+		{name: "missing script", scripts: "  push: missing.sh\n", key: "scripts.push"},
+		{name: "release source equals dir", extra: "releases:\n  enabled: true\n  dir: {{dir}}/site\n  source: {{dir}}/site\n", key: "releases.source"},
+		{name: "smoke pages without public dir", extra: "smoke:\n  enabled: true\n  min_pages: 1\n", key: "smoke.min_pages"},
+		{name: "smoke checks without base url", extra: "smoke:\n  enabled: true\n  checks:\n    - path: /\n      status: 200\n", key: "smoke.base_url"},
+		{name: "relative releases dir", extra: "releases:\n  enabled: true\n  dir: site\n", key: "releases.dir"},
+	}
+	if runtime.GOOS == "linux" {
+		tests = append(tests, struct {
+			name, scripts, extra string
+			key                  string
+		}{name: "chroot with landlock", scripts: "  sandbox:\n    chroot: {{dir}}\n    landlock: true\n", key: "scripts.sandbox.landlock"})
+	}
+
+	for _, tt := range tests {
+		content := validConfig(tt.scripts, tt.extra)
+		if strings.Contains(tt.scripts, "  push: ") {
+			content = strings.Replace(content, "  push: deploy.sh\n", "", 1)
+		}
+		fatal := loadProblems(t, content).Fatal()
+		found := false
+		for _, p := range fatal {
+			if p.Key == tt.key {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: fatal problems = %v, want one for %s", tt.name, fatal, tt.key)
+		}
+	}
+}
+
+// TestExampleConfig 示例配置中的每一项都是已知的配置项
+func TestExampleConfig(t *testing.T) {
+	v, err := read(filepath.Join("..", "config_example.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := unknownKeys(v); len(problems) > 0 {
+		t.Errorf("config_example.yaml has unknown keys: %v", problems)
+	}
+}
+
+func TestProblemsFor(t *testing.T) {
+	problems := Problems{
+		{Key: "releases.dir", Fatal: true},
+		{Key: "releases_extra"},
+		{Key: "outbound.targets[0].url", Fatal: true},
+		{Key: "outbound"},
+		{Key: "scripts.push", Fatal: true},
+	}
+	got := problems.For("releases", "outbound")
+	if len(got) != 3 || got[0].Key != "releases.dir" || got[1].Key != "outbound.targets[0].url" || got[2].Key != "outbound" {
+		t.Errorf("For() = %v", got)
+	}
+	if len(got.Fatal()) != 2 || len(got.Warnings()) != 1 {
+		t.Errorf("Fatal() = %v, Warnings() = %v", got.Fatal(), got.Warnings())
+	}
+}
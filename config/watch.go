@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ReloadResult 描述一次热加载的结果
+type ReloadResult struct {
+	Trigger  string   // 触发来源：文件变化或 SIGHUP
+	Changed  bool     // 配置内容是否发生变化
+	Restart  []string // 已修改但需要重启服务才能生效的配置项
+	Warnings Problems // 新配置中的非致命问题
+	Err      error    // 加载或校验失败的原因，此时旧配置继续生效
+}
+
+// reloadMu 保证同一时间只有一次热加载在进行
+var reloadMu sync.Mutex
+
+// Reload 重新读取配置文件，校验通过后原子替换当前配置
+// 如果新配置无法解析或校验失败，则保留旧配置并返回错误
+func Reload() ReloadResult {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	v, err := read(configFile)
+	if err != nil {
+		return ReloadResult{Err: err}
+	}
+
+	next, problems, err := load(v)
+	if err != nil {
+		return ReloadResult{Err: err}
+	}
+	if fatal := problems.Fatal(); len(fatal) > 0 {
+		return ReloadResult{Err: fatal}
+	}
+
+	prev := Get()
+	if reflect.DeepEqual(prev, next) {
+		return ReloadResult{}
+	}
+
+	current.Store(next)
+	return ReloadResult{Changed: true, Restart: restartRequired(prev, next), Warnings: problems.Warnings()}
+}
+
+// Watch 监听配置文件变化与 SIGHUP 信号
+// 每次触发都会调用 Reload，并把结果交给 notify 处理
+func Watch(notify func(ReloadResult)) {
+	watcher := viper.New()
+	watcher.SetConfigFile(configFile)
+	watcher.OnConfigChange(func(e fsnotify.Event) {
+		// 删除或重命名时文件可能暂不存在，等待下一次写入事件
+		if e.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+			return
+		}
+		result := Reload()
+		result.Trigger = "文件变化"
+		notify(result)
+	})
+	watcher.WatchConfig()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			result := Reload()
+			result.Trigger = "SIGHUP"
+			notify(result)
+		}
+	}()
+}
+
+// restartRequired 列出已修改但无法在运行中生效的配置项
+func restartRequired(prev, next *config) []string {
+	var keys []string
+	if prev.Webhook.Port != next.Webhook.Port {
+		keys = append(keys, "webhook.port")
+	}
+	if prev.SSL != next.SSL {
+		keys = append(keys, "ssl")
+	}
+	if prev.Logs.Path != next.Logs.Path || prev.Logs.MaxSize != next.Logs.MaxSize ||
+		prev.Logs.MaxBackups != next.Logs.MaxBackups || prev.Logs.MaxAge != next.Logs.MaxAge {
+		keys = append(keys, "logs.path/max_size/max_backups/max_age")
+	}
+	if prev.Static.Enabled != next.Static.Enabled || prev.Static.Listen != next.Static.Listen {
+		keys = append(keys, "static.enabled/listen")
+	}
+	if prev.Outbound.Outbox != next.Outbound.Outbox || prev.Outbound.DeliveryLog != next.Outbound.DeliveryLog {
+		keys = append(keys, "outbound.outbox/delivery_log")
+	}
+	return keys
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"Hexo-AutoCD/config"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeDeployFinished  = "deploy.finished"     // 部署结束（无论成功或失败）
+	TypeRolledBack      = "release.rolled_back" // current 被手动切换到另一个版本
+	TypePreviewDeployed = "preview.deployed"    // 分支预览构建结束（无论成功或失败）
+	TypePreviewRemoved  = "preview.removed"     // 分支被删除或拉取请求已关闭，预览已删除
+)
+
+// 部署结果
+const (
+	OutcomeSuccess  = "success"
+	OutcomeFailure  = "failure"
+	OutcomeRejected = "rejected" // 未通过提交签名校验，拒绝部署
+)
+
+// ChangedFiles 本次部署涉及的文件变更
+type ChangedFiles struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// Event 定义发送给下游系统的事件
+// 事件会被序列化为 JSON，并使用接收方配置的密钥进行签名
+type Event struct {
+	ID           string                 `json:"id"`              // 事件唯一标识
+	Type         string                 `json:"type"`            // 事件类型，如 deploy.finished
+	Site         string                 `json:"site"`            // 站点名称
+	RunID        string                 `json:"run_id"`          // 部署运行ID
+	Commit       string                 `json:"commit"`          // 部署的提交ID
+	Outcome      string                 `json:"outcome"`         // 部署结果：success、failure 或 rejected
+	ExitCode     int                    `json:"exit_code"`       // 脚本退出码
+	Error        string                 `json:"error,omitempty"` // 失败原因
+	DurationMs   int64                  `json:"duration_ms"`     // 部署耗时（毫秒）
+	ChangedFiles ChangedFiles           `json:"changed_files"`   // 变更的文件
+	Data         map[string]interface{} `json:"data,omitempty"`  // 事件附加信息
+	Timestamp    string                 `json:"timestamp"`       // 事件产生时间（RFC3339）
+}
+
+// NewEvent 创建一个带有ID、站点与时间戳的事件
+func NewEvent(eventType string) Event {
+	return Event{
+		ID:        newID(),
+		Type:      eventType,
+		Site:      config.Get().Site.Name,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+}
+
+// newID 生成随机的唯一标识
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// 随机数生成失败时退化为时间戳
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
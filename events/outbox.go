@@ -0,0 +1,403 @@
+package events
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/redact"
+	"Hexo-AutoCD/signature"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 最大重试间隔，避免指数退避无限增长
+const maxBackoff = time.Hour
+
+// delivery 定义发件箱中的一条待投递记录
+// 每条记录对应一个事件与一个接收方，以 JSON 文件的形式持久化，
+// 因此服务重启后未完成的投递会继续进行
+type delivery struct {
+	ID          string          `json:"id"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Target      string          `json:"target"` // 接收方名称，投递时从当前配置中查找
+	Body        json.RawMessage `json:"body"`   // 事件的原始 JSON，保证每次重试签名内容一致
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// deliveryRecord 定义投递日志中的一行
+type deliveryRecord struct {
+	Time       string `json:"time"`
+	DeliveryID string `json:"delivery_id"`
+	EventID    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	Target     string `json:"target"`
+	URL        string `json:"url"`
+	Attempt    int    `json:"attempt"`
+	StatusCode int    `json:"status_code,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Result     string `json:"result"` // delivered、retry 或 failed
+	Error      string `json:"error,omitempty"`
+}
+
+// Dispatcher 负责持久化并投递出站事件
+type Dispatcher struct {
+	dir     string        // 发件箱目录
+	logPath string        // 投递日志路径
+	logMu   sync.Mutex    // 保护投递日志的并发写入
+	wake    chan struct{} // 有新事件时唤醒投递循环
+	client  *http.Client
+}
+
+var dispatcher *Dispatcher
+
+// Init 初始化出站事件投递器，并在后台开始投递发件箱中的事件
+func Init() error {
+	cfg := config.Get()
+	dir := cfg.Outbound.Outbox
+	if err := os.MkdirAll(filepath.Join(dir, "failed"), 0755); err != nil {
+		return fmt.Errorf("创建发件箱目录失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Outbound.DeliveryLog), 0755); err != nil {
+		return fmt.Errorf("创建投递日志目录失败: %v", err)
+	}
+
+	dispatcher = &Dispatcher{
+		dir:     dir,
+		logPath: cfg.Outbound.DeliveryLog,
+		wake:    make(chan struct{}, 1),
+		client:  &http.Client{},
+	}
+
+	pending, _ := dispatcher.pending()
+	logger.WithFields(logrus.Fields{
+		"发件箱":   dir,
+		"待投递数":  len(pending),
+		"接收方数量": len(cfg.Outbound.Targets),
+	}).Info("出站事件投递器初始化成功")
+
+	go dispatcher.loop()
+	return nil
+}
+
+// Publish 将事件写入发件箱，投递给所有订阅了该事件类型的接收方
+// 投递在后台异步进行，调用方不会被阻塞
+func Publish(evt Event) {
+	if dispatcher == nil {
+		return
+	}
+
+	// 保证文件列表序列化为数组而不是 null
+	if evt.ChangedFiles.Added == nil {
+		evt.ChangedFiles.Added = []string{}
+	}
+	if evt.ChangedFiles.Modified == nil {
+		evt.ChangedFiles.Modified = []string{}
+	}
+	if evt.ChangedFiles.Removed == nil {
+		evt.ChangedFiles.Removed = []string{}
+	}
+
+	// 步骤输出已在执行时脱敏，这里再处理错误信息、每次重试的错误等其他字段
+	body, err := json.Marshal(redact.Value(evt))
+	if err != nil {
+		logger.WithError(err).Error("序列化出站事件失败")
+		return
+	}
+
+	queued := 0
+	for _, target := range config.Get().Outbound.Targets {
+		if !subscribed(target, evt.Type) {
+			continue
+		}
+		d := &delivery{
+			ID:          newID(),
+			EventID:     evt.ID,
+			EventType:   evt.Type,
+			Target:      target.Name,
+			Body:        body,
+			NextAttempt: time.Now(),
+			CreatedAt:   time.Now(),
+		}
+		if err := dispatcher.save(d); err != nil {
+			logger.WithError(err).WithField("接收方", target.Name).Error("写入发件箱失败")
+			continue
+		}
+		queued++
+	}
+
+	if queued == 0 {
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"事件ID": evt.ID,
+		"事件类型": evt.Type,
+		"接收方数": queued,
+	}).Info("出站事件已加入发件箱")
+
+	select {
+	case dispatcher.wake <- struct{}{}:
+	default:
+	}
+}
+
+// subscribed 判断接收方是否订阅了指定事件类型
+func subscribed(target config.OutboundTarget, eventType string) bool {
+	if len(target.Events) == 0 {
+		return true
+	}
+	for _, e := range target.Events {
+		if e == eventType || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// findTarget 在当前配置中查找接收方
+func findTarget(name string) (config.OutboundTarget, bool) {
+	for _, target := range config.Get().Outbound.Targets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return config.OutboundTarget{}, false
+}
+
+// loop 投递循环，定期检查发件箱中到期的事件
+func (d *Dispatcher) loop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		d.flush()
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// flush 投递所有到期的事件
+func (d *Dispatcher) flush() {
+	pending, err := d.pending()
+	if err != nil {
+		logger.WithError(err).Error("读取发件箱失败")
+		return
+	}
+
+	now := time.Now()
+	for _, item := range pending {
+		if item.NextAttempt.After(now) {
+			continue
+		}
+		d.attempt(item)
+	}
+}
+
+// attempt 尝试投递一次事件
+func (d *Dispatcher) attempt(item *delivery) {
+	target, ok := findTarget(item.Target)
+	if !ok {
+		logger.WithFields(logrus.Fields{
+			"投递ID": item.ID,
+			"接收方":  item.Target,
+		}).Warn("接收方已从配置中移除，丢弃该投递")
+		d.remove(item)
+		return
+	}
+
+	item.Attempts++
+	start := time.Now()
+	statusCode, err := d.send(target, item)
+	record := deliveryRecord{
+		Time:       start.Format(time.RFC3339),
+		DeliveryID: item.ID,
+		EventID:    item.EventID,
+		EventType:  item.EventType,
+		Target:     target.Name,
+		URL:        target.URL,
+		Attempt:    item.Attempts,
+		StatusCode: statusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	deliveryLogger := logger.WithFields(logrus.Fields{
+		"投递ID": item.ID,
+		"事件类型": item.EventType,
+		"接收方":  target.Name,
+		"尝试次数": item.Attempts,
+	})
+
+	if err == nil {
+		record.Result = "delivered"
+		d.record(record)
+		d.remove(item)
+		deliveryLogger.WithField("状态码", statusCode).Info("出站事件投递成功")
+		return
+	}
+
+	item.LastError = err.Error()
+	record.Error = item.LastError
+
+	if item.Attempts >= config.Get().Outbound.MaxAttempts {
+		record.Result = "failed"
+		d.record(record)
+		d.fail(item)
+		deliveryLogger.WithError(err).Error("出站事件投递失败，已达到最大重试次数")
+		return
+	}
+
+	wait := backoff(item.Attempts)
+	item.NextAttempt = time.Now().Add(wait)
+	record.Result = "retry"
+	d.record(record)
+	if err := d.save(item); err != nil {
+		deliveryLogger.WithError(err).Error("更新发件箱失败")
+	}
+	deliveryLogger.WithError(err).WithField("下次重试", wait.String()).Warn("出站事件投递失败，稍后重试")
+}
+
+// send 发送签名后的事件
+func (d *Dispatcher) send(target config.OutboundTarget, item *delivery) (int, error) {
+	timeout := 10 * time.Second
+	if target.Timeout != "" {
+		if parsed, err := time.ParseDuration(target.Timeout); err == nil {
+			timeout = parsed
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(item.Body))
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Hexo-AutoCD")
+	req.Header.Set("X-Hexo-AutoCD-Event", item.EventType)
+	req.Header.Set("X-Hexo-AutoCD-Delivery", item.ID)
+	if target.Secret != "" {
+		req.Header.Set("X-Hub-Signature-256", signature.Sign(item.Body, target.Secret))
+	}
+
+	client := *d.client
+	client.Timeout = timeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("接收方返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 计算第 attempts 次失败后的重试间隔（指数退避）
+func backoff(attempts int) time.Duration {
+	base, err := time.ParseDuration(config.Get().Outbound.Backoff)
+	if err != nil || base <= 0 {
+		base = 2 * time.Second
+	}
+
+	wait := base
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return wait
+}
+
+// pending 读取发件箱中所有待投递的记录，按创建时间排序
+func (d *Dispatcher) pending() ([]*delivery, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*delivery
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var item delivery
+		if err := json.Unmarshal(data, &item); err != nil {
+			logger.WithError(err).WithField("文件", entry.Name()).Warn("发件箱中存在无法解析的记录")
+			continue
+		}
+		items = append(items, &item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items, nil
+}
+
+// save 将投递记录原子地写入发件箱
+func (d *Dispatcher) save(item *delivery) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(d.dir, item.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// remove 从发件箱中删除投递记录
+func (d *Dispatcher) remove(item *delivery) {
+	os.Remove(filepath.Join(d.dir, item.ID+".json"))
+}
+
+// fail 将多次投递失败的记录移入 failed 目录，便于人工排查
+func (d *Dispatcher) fail(item *delivery) {
+	if err := d.save(item); err != nil {
+		return
+	}
+	os.Rename(filepath.Join(d.dir, item.ID+".json"), filepath.Join(d.dir, "failed", item.ID+".json"))
+}
+
+// record 追加一行投递日志
+func (d *Dispatcher) record(record deliveryRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	d.logMu.Lock()
+	defer d.logMu.Unlock()
+
+	f, err := os.OpenFile(d.logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.WithError(err).Error("写入投递日志失败")
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
@@ -0,0 +1,207 @@
+package events
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/signature"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// receiver 记录收到的投递，按 statuses 的顺序返回状态码，用完后返回最后一个
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+	status := r.statuses[0]
+	if len(r.statuses) > 1 {
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// setup 使用默认配置与临时发件箱，返回与重启后相同的新投递器
+func setup(t *testing.T, targets []config.OutboundTarget, maxAttempts int) func() *Dispatcher {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("site:\n  name: blog\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Outbound.Outbox = filepath.Join(dir, "outbox")
+	cfg.Outbound.DeliveryLog = filepath.Join(dir, "deliveries.log")
+	cfg.Outbound.Backoff = "10ms"
+	cfg.Outbound.MaxAttempts = maxAttempts
+	cfg.Outbound.Targets = targets
+	config.Set(cfg)
+	if err := os.MkdirAll(filepath.Join(cfg.Outbound.Outbox, "failed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dispatcher = nil })
+
+	// 与 Init 相同，但不启动后台投递循环，由测试调用 flush
+	restart := func() *Dispatcher {
+		dispatcher = &Dispatcher{
+			dir:     cfg.Outbound.Outbox,
+			logPath: cfg.Outbound.DeliveryLog,
+			wake:    make(chan struct{}, 1),
+			client:  &http.Client{},
+		}
+		return dispatcher
+	}
+	restart()
+	return restart
+}
+
+// results 返回投递日志中每一行的 result
+func results(t *testing.T) []string {
+	t.Helper()
+	data, err := os.ReadFile(config.Get().Outbound.DeliveryLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record deliveryRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid delivery log line %q: %v", line, err)
+		}
+		got = append(got, record.Result)
+	}
+	return got
+}
+
+func TestOutboxRedeliveryAfterRestart(t *testing.T) {
+	recv := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusOK}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	restart := setup(t, []config.OutboundTarget{
+		{Name: "hook", URL: server.URL, Secret: "s3cret", Events: []string{TypeDeployFinished}},
+		{Name: "previews", URL: server.URL, Events: []string{TypePreviewRemoved}},
+	}, 5)
+
+	evt := NewEvent(TypeDeployFinished)
+	evt.ChangedFiles.Added = []string{"source/_posts/a.md"}
+	Publish(evt)
+
+	// 只为订阅了该事件的接收方入队，并持久化到发件箱
+	pending, err := dispatcher.pending()
+	if err != nil || len(pending) != 1 || pending[0].Target != "hook" {
+		t.Fatalf("pending() = %+v, %v", pending, err)
+	}
+	var body Event
+	if err := json.Unmarshal(pending[0].Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != evt.ID || body.Site != "blog" || body.ChangedFiles.Removed == nil {
+		t.Errorf("queued body = %s", pending[0].Body)
+	}
+
+	// 第一次投递失败，记录重试时间后留在发件箱中
+	dispatcher.flush()
+	pending, _ = dispatcher.pending()
+	if len(pending) != 1 || pending[0].Attempts != 1 || !strings.Contains(pending[0].LastError, "500") || !pending[0].NextAttempt.After(time.Now()) {
+		t.Fatalf("after failure pending = %+v", pending)
+	}
+	// 未到重试时间时不投递
+	dispatcher.flush()
+	if len(recv.requests) != 1 {
+		t.Fatalf("delivered %d times before the backoff elapsed", len(recv.requests))
+	}
+
+	// 重启后从发件箱中读取未完成的投递继续进行
+	time.Sleep(20 * time.Millisecond)
+	restart().flush()
+	if pending, _ := dispatcher.pending(); len(pending) != 0 {
+		t.Errorf("after redelivery pending = %+v", pending)
+	}
+	if len(recv.requests) != 2 {
+		t.Fatalf("received %d requests, want 2", len(recv.requests))
+	}
+	// 每次重试的内容、投递ID与签名都相同
+	for i, req := range recv.requests {
+		if recv.bodies[i] != recv.bodies[0] || req.Header.Get("X-Hexo-AutoCD-Delivery") != recv.requests[0].Header.Get("X-Hexo-AutoCD-Delivery") {
+			t.Errorf("request %d differs from the first one", i)
+		}
+		if got := req.Header.Get("X-Hub-Signature-256"); got != signature.Sign([]byte(recv.bodies[i]), "s3cret") {
+			t.Errorf("request %d signature = %s", i, got)
+		}
+		if req.Header.Get("X-Hexo-AutoCD-Event") != TypeDeployFinished {
+			t.Errorf("request %d event header = %s", i, req.Header.Get("X-Hexo-AutoCD-Event"))
+		}
+	}
+	if got := results(t); strings.Join(got, ",") != "retry,delivered" {
+		t.Errorf("delivery log results = %v", got)
+	}
+}
+
+func TestOutboxGivesUp(t *testing.T) {
+	recv := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	setup(t, []config.OutboundTarget{{Name: "hook", URL: server.URL}}, 2)
+	Publish(NewEvent(TypeRolledBack))
+	dispatcher.flush()
+	time.Sleep(20 * time.Millisecond)
+	dispatcher.flush()
+
+	// 达到最大次数后移入 failed 目录，不再重试
+	if pending, _ := dispatcher.pending(); len(pending) != 0 {
+		t.Fatalf("pending = %+v", pending)
+	}
+	failed, _ := filepath.Glob(filepath.Join(dispatcher.dir, "failed", "*.json"))
+	if len(failed) != 1 {
+		t.Fatalf("failed = %v", failed)
+	}
+	data, _ := os.ReadFile(failed[0])
+	var item delivery
+	if err := json.Unmarshal(data, &item); err != nil || item.Attempts != 2 || !strings.Contains(item.LastError, "503") {
+		t.Errorf("failed delivery = %s, %v", data, err)
+	}
+	if got := results(t); strings.Join(got, ",") != "retry,failed" {
+		t.Errorf("delivery log results = %v", got)
+	}
+}
+
+func TestOutboxDropsRemovedTarget(t *testing.T) {
+	setup(t, []config.OutboundTarget{{Name: "hook", URL: "http://127.0.0.1:0"}}, 3)
+	Publish(NewEvent(TypeDeployFinished))
+
+	// 热加载后接收方已被删除，投递直接丢弃
+	config.Get().Outbound.Targets = nil
+	dispatcher.flush()
+	if pending, _ := dispatcher.pending(); len(pending) != 0 {
+		t.Errorf("pending = %+v", pending)
+	}
+}
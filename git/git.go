@@ -0,0 +1,360 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// 错误类型，会记录在执行结果中，便于区分失败原因
+const (
+	ErrAuth       = "auth_failed"    // 认证失败，如密钥错误或没有权限
+	ErrMissingRef = "missing_ref"    // 远程仓库中找不到分支或提交
+	ErrDirtyTree  = "dirty_tree"     // 工作区有未提交的修改
+	ErrDeleted    = "branch_deleted" // 推送删除了分支，没有可部署的提交
+	ErrMismatch   = "head_mismatch"  // 检出后 HEAD 与推送的提交不一致
+	ErrNetwork    = "network"        // 无法连接远程仓库
+	ErrTimeout    = "timeout"        // 操作超时
+	ErrGit        = "git_error"      // 其他 git 错误
+)
+
+// zeroSHA 删除分支时 after 字段使用的全零提交ID
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// Error 描述一次 git 操作失败
+type Error struct {
+	Kind    string // 错误类型，取值为上面的 Err* 常量
+	Op      string // 失败的操作，如 fetch、checkout
+	Message string // 错误详情，通常是 git 的错误输出
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s 失败（%s）: %s", e.Op, e.Kind, e.Message)
+}
+
+// Kind 返回错误类型，err 不是 *Error 时返回 ErrGit
+func Kind(err error) string {
+	var gitErr *Error
+	if errors.As(err, &gitErr) {
+		return gitErr.Kind
+	}
+	return ErrGit
+}
+
+// Options 定义一次同步的参数
+type Options struct {
+	Dir     string        // 本地仓库目录
+	Remote  string        // 远程仓库名，默认为 origin
+	Branch  string        // 推送的分支，为空时使用仓库当前分支
+	Tag     string        // 要检出的标签，设置时忽略 Branch，以分离头指针检出
+	Commit  string        // 要检出的提交，为空时检出远程分支的最新提交
+	Clean   bool          // 是否丢弃工作区中的修改与未跟踪文件，为 false 时工作区不干净则拒绝部署
+	Timeout time.Duration // 整个同步过程的超时时间
+	Verify  *VerifyPolicy // 检出前校验提交签名，为 nil 时不校验
+}
+
+// Result 描述一次同步的结果
+type Result struct {
+	Branch   string   // 检出的分支
+	Previous string   // 同步前的 HEAD
+	Head     string   // 同步后的 HEAD
+	Forced   bool     // 新提交不是旧 HEAD 的后代，即发生了强制推送
+	Discard  []string // 被丢弃的本地修改
+
+	Signatures []Signature // 签名校验结果，校验失败时也会返回已校验的部分
+}
+
+// Sync 从远程仓库获取指定提交，并把本地分支强制指向它
+// 与 git pull 不同，检出的始终是推送中的提交，而不是同步时远程分支的最新提交；
+// 强制推送时同样直接指向新提交，不会产生合并
+// 失败时返回的 Result 可能为 nil；签名校验失败时 Result 中包含签名校验结果
+func Sync(opts Options) (*Result, error) {
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+	if opts.Commit == zeroSHA {
+		return nil, &Error{Kind: ErrDeleted, Op: "sync", Message: fmt.Sprintf("分支 %s 已被删除", opts.Branch)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	g := &runner{ctx: ctx, dir: opts.Dir}
+	if opts.Verify != nil && opts.Verify.GPGHome != "" {
+		g.env = append(g.env, "GNUPGHOME="+opts.Verify.GPGHome)
+	}
+
+	if _, err := g.run("check", "rev-parse", "--git-dir"); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Branch: opts.Branch}
+	result.Previous, _ = g.run("rev-parse", "rev-parse", "--verify", "--quiet", "HEAD")
+	ref := "refs/heads/" + opts.Branch
+	if opts.Tag != "" {
+		result.Branch, ref = "", "refs/tags/"+opts.Tag
+	} else if result.Branch == "" {
+		branch, err := g.run("rev-parse", "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil || branch == "HEAD" {
+			return nil, &Error{Kind: ErrMissingRef, Op: "rev-parse", Message: "仓库处于分离头指针状态，且推送中没有分支信息"}
+		}
+		result.Branch = branch
+		ref = "refs/heads/" + branch
+	}
+
+	// 检查工作区
+	status, err := g.run("status", "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		changes := strings.Split(status, "\n")
+		if !opts.Clean {
+			return nil, &Error{Kind: ErrDirtyTree, Op: "status", Message: fmt.Sprintf("工作区有 %d 处未提交的修改: %s", len(changes), strings.Join(first(changes, 5), "; "))}
+		}
+		result.Discard = changes
+	}
+
+	head, err := g.fetch(opts.Remote, ref, opts.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Tag == "" && result.Previous != "" && result.Previous != head {
+		if _, err := g.run("merge-base", "merge-base", "--is-ancestor", result.Previous, head); err != nil {
+			result.Forced = true
+		}
+	}
+
+	// 签名校验在检出之前进行，未通过时工作区保持原样
+	if opts.Verify != nil {
+		result.Signatures, err = g.verify(opts.Verify, head)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// 强制检出，丢弃本地修改；标签以分离头指针检出，下一次推送部署时再切回分支
+	checkout := []string{"checkout", "--force", "-B", result.Branch, head}
+	if opts.Tag != "" {
+		checkout = []string{"checkout", "--force", "--detach", head}
+	}
+	if _, err := g.run("checkout", checkout...); err != nil {
+		return nil, err
+	}
+	if opts.Clean {
+		if _, err := g.run("clean", "clean", "-fd"); err != nil {
+			return nil, err
+		}
+	}
+
+	// 确认检出结果与推送一致
+	result.Head, err = g.run("rev-parse", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if result.Head != head {
+		return nil, &Error{Kind: ErrMismatch, Op: "verify", Message: fmt.Sprintf("期望 %s，实际 %s", head, result.Head)}
+	}
+	return result, nil
+}
+
+// Worktree 在独立的工作树 path 中检出推送的提交，用于构建分支预览
+// 部署用的仓库 opts.Dir 只获取提交，工作区与当前分支保持不变；工作树已存在时直接切换并清理未跟踪文件，
+// 被 .gitignore 忽略的文件（如 node_modules）会保留以加快下一次构建
+func Worktree(opts Options, path string) (*Result, error) {
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+	if opts.Commit == zeroSHA {
+		return nil, &Error{Kind: ErrDeleted, Op: "sync", Message: fmt.Sprintf("分支 %s 已被删除", opts.Branch)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	g := &runner{ctx: ctx, dir: opts.Dir}
+	if opts.Verify != nil && opts.Verify.GPGHome != "" {
+		g.env = append(g.env, "GNUPGHOME="+opts.Verify.GPGHome)
+	}
+
+	head, err := g.fetch(opts.Remote, "refs/heads/"+opts.Branch, opts.Commit)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Branch: opts.Branch}
+	if opts.Verify != nil {
+		result.Signatures, err = g.verify(opts.Verify, head)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// 清理已被手动删除的工作树留下的记录，否则无法在原路径重新创建
+		g.run("worktree", "worktree", "prune")
+		if _, err := g.run("worktree", "worktree", "add", "--force", "--detach", path, head); err != nil {
+			return nil, err
+		}
+	} else {
+		w := &runner{ctx: ctx, dir: path, env: g.env}
+		result.Previous, _ = w.run("rev-parse", "rev-parse", "--verify", "--quiet", "HEAD")
+		if _, err := w.run("checkout", "checkout", "--force", "--detach", head); err != nil {
+			return nil, err
+		}
+		if _, err := w.run("clean", "clean", "-fd"); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &runner{ctx: ctx, dir: path}
+	result.Head, err = w.run("rev-parse", "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if result.Head != head {
+		return nil, &Error{Kind: ErrMismatch, Op: "verify", Message: fmt.Sprintf("期望 %s，实际 %s", head, result.Head)}
+	}
+	return result, nil
+}
+
+// RemoveWorktree 删除 Worktree 创建的工作树
+func RemoveWorktree(dir, path string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	g := &runner{ctx: ctx, dir: dir}
+	if _, err := os.Stat(path); err == nil {
+		if _, err := g.run("worktree", "worktree", "remove", "--force", path); err != nil {
+			return err
+		}
+	}
+	_, err := g.run("worktree", "worktree", "prune")
+	return err
+}
+
+// Show 读取仓库中某个提交里的文件，用于获取推送前的文件内容
+// commit 为空或全零时返回错误
+func Show(dir, commit, file string, timeout time.Duration) ([]byte, error) {
+	if commit == "" || commit == zeroSHA {
+		return nil, &Error{Kind: ErrMissingRef, Op: "show", Message: "没有可读取的提交"}
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	g := &runner{ctx: ctx, dir: dir}
+	// 文件内容原样返回，不去掉首尾的空白
+	return g.output("show", "show", commit+":"+file)
+}
+
+// fetch 获取远程分支或标签 ref 并返回要检出的提交
+// 推送的提交已不在分支上时（例如紧接着又一次强制推送）再按提交ID获取；commit 为空时返回 ref 指向的提交
+func (g *runner) fetch(remote, ref, commit string) (string, error) {
+	local := ref
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		local = "refs/remotes/" + remote + "/" + branch
+	}
+	if _, err := g.run("fetch", "fetch", "--force", "--prune", "--no-tags", remote, "+"+ref+":"+local); err != nil {
+		return "", err
+	}
+	target := commit
+	if target == "" {
+		target = local
+	} else if _, err := g.run("rev-parse", "cat-file", "-e", target+"^{commit}"); err != nil {
+		if _, err := g.run("fetch", "fetch", "--force", "--no-tags", remote, target); err != nil {
+			return "", err
+		}
+	}
+	return g.run("rev-parse", "rev-parse", "--verify", target+"^{commit}")
+}
+
+// runner 在指定目录中执行 git 命令
+type runner struct {
+	ctx context.Context
+	dir string
+	env []string // 追加的环境变量
+}
+
+// run 执行 git 命令并返回去除首尾空白的标准输出，失败时返回 *Error
+func (g *runner) run(op string, args ...string) (string, error) {
+	out, err := g.output(op, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// output 执行 git 命令并返回未经处理的标准输出
+func (g *runner) output(op string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(g.ctx, "git", args...)
+	cmd.Dir = g.dir
+	// 禁止交互式输入用户名密码，否则认证失败时会一直等待
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND="+sshCommand())
+	cmd.Env = append(cmd.Env, g.env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		if g.ctx.Err() == context.DeadlineExceeded {
+			return nil, &Error{Kind: ErrTimeout, Op: op, Message: message}
+		}
+		return nil, &Error{Kind: classify(message), Op: op, Message: message}
+	}
+	return out, nil
+}
+
+// sshCommand 返回 ssh 命令，保留用户自定义的 GIT_SSH_COMMAND 并禁止交互
+func sshCommand() string {
+	if custom := os.Getenv("GIT_SSH_COMMAND"); custom != "" {
+		return custom
+	}
+	return "ssh -o BatchMode=yes"
+}
+
+// classify 根据 git 的错误输出判断错误类型
+func classify(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case containsAny(lower, "authentication failed", "permission denied", "could not read username",
+		"could not read password", "access denied", "host key verification failed", "returned error: 403"):
+		return ErrAuth
+	case containsAny(lower, "couldn't find remote ref", "not our ref", "unknown revision",
+		"needed a single revision", "bad object", "not a valid object", "no such remote ref"):
+		return ErrMissingRef
+	case containsAny(lower, "could not resolve host", "connection refused", "connection timed out",
+		"network is unreachable", "could not read from remote repository", "unable to access"):
+		return ErrNetwork
+	default:
+		return ErrGit
+	}
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func first(items []string, n int) []string {
+	if len(items) > n {
+		return append(items[:n:n], "...")
+	}
+	return items
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitCmd 在 dir 中执行 git 命令，返回其输出
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestShow(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	// 首尾的空行与空白是文件内容的一部分
+	content := "\n---\ntitle: 文章\n---\n正文  \n\n"
+	if err := os.MkdirAll(filepath.Join(dir, "source", "_posts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "source", "_posts", "a.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitCmd(t, dir, "init", "-q")
+	gitCmd(t, dir, "add", "-A")
+	gitCmd(t, dir, "commit", "-q", "-m", "init")
+	commit := gitCmd(t, dir, "rev-parse", "HEAD")[:40]
+
+	got, err := Show(dir, commit, "source/_posts/a.md", 0)
+	if err != nil || string(got) != content {
+		t.Errorf("Show() = %q, %v, want %q", got, err, content)
+	}
+
+	if _, err := Show(dir, commit, "source/_posts/missing.md", 0); err == nil {
+		t.Error("Show() of missing file error = nil")
+	}
+	for _, commit := range []string{"", zeroSHA} {
+		if _, err := Show(dir, commit, "source/_posts/a.md", 0); Kind(err) != ErrMissingRef {
+			t.Errorf("Show(%q) = %v, want %s", commit, err, ErrMissingRef)
+		}
+	}
+}
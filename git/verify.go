@@ -0,0 +1,122 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 签名校验失败时的错误类型
+const (
+	ErrUnsigned  = "unsigned"            // 提交没有签名
+	ErrUntrusted = "untrusted_signature" // 签名无效，或签名密钥不在信任列表中
+)
+
+// VerifyPolicy 定义提交签名的校验策略
+type VerifyPolicy struct {
+	All            bool     // 校验推送中的所有提交，为 false 时只校验最新提交
+	GPGHome        string   // 存放受信任 GPG 公钥的 GNUPGHOME 目录
+	AllowedSigners string   // SSH 签名使用的 allowed_signers 文件
+	Before         string   // 推送前的提交，用于确定推送包含哪些提交
+	Commits        []string // 载荷中列出的提交，无法通过 Before 确定范围时使用
+}
+
+// Signature 描述一个提交的签名校验结果
+type Signature struct {
+	Commit string // 提交ID
+	Status string // git 的 %G? 结果，G 表示有效签名
+	Signer string // 签名者
+	Key    string // 签名密钥指纹
+}
+
+// verify 校验检出前的提交签名，任一提交不满足策略即返回错误
+func (g *runner) verify(policy *VerifyPolicy, head string) ([]Signature, error) {
+	commits, err := g.pushedCommits(policy, head)
+	if err != nil {
+		return nil, err
+	}
+
+	var signatures []Signature
+	for _, commit := range commits {
+		args := []string{"log", "-1", "--format=%G?%x00%GS%x00%GF%x00%GK", commit}
+		if policy.AllowedSigners != "" {
+			args = append([]string{"-c", "gpg.ssh.allowedSignersFile=" + policy.AllowedSigners}, args...)
+		}
+		out, err := g.run("verify-signature", args...)
+		if err != nil {
+			return signatures, err
+		}
+		fields := strings.SplitN(out, "\x00", 4)
+		for len(fields) < 4 {
+			fields = append(fields, "")
+		}
+		sig := Signature{Commit: commit, Status: fields[0], Signer: fields[1], Key: fields[2]}
+		if sig.Key == "" {
+			// 公钥不在密钥环中时没有指纹，只有签名中的密钥ID
+			sig.Key = fields[3]
+		}
+		signatures = append(signatures, sig)
+
+		if err := checkSignature(sig, policy.GPGHome); err != nil {
+			return signatures, err
+		}
+	}
+	return signatures, nil
+}
+
+// checkSignature 判断签名是否可以接受
+// 设置了 gpgHome 时 GPG 公钥只要在其中即可，未设置信任级别时 git 报告为 U，同样接受；
+// 未设置时使用的是服务用户默认的密钥环，其中可能有与部署无关的公钥，U 视为不受信任；
+// SSH 签名的密钥不在 allowed_signers 中时也报告为 U，此时拒绝
+func checkSignature(sig Signature, gpgHome string) error {
+	short := sig.Commit
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	switch sig.Status {
+	case "G":
+		return nil
+	case "U":
+		if strings.HasPrefix(sig.Key, "SHA256:") {
+			return &Error{Kind: ErrUntrusted, Op: "verify-signature", Message: fmt.Sprintf("提交 %s 的 SSH 签名密钥 %s 不在 allowed_signers 中", short, sig.Key)}
+		}
+		if gpgHome == "" {
+			return &Error{Kind: ErrUntrusted, Op: "verify-signature", Message: fmt.Sprintf("提交 %s 的 GPG 签名密钥 %s 未被信任，请设置 git.verify_signatures.gpg_home 或在密钥环中信任该公钥", short, sig.Key)}
+		}
+		return nil
+	case "N":
+		return &Error{Kind: ErrUnsigned, Op: "verify-signature", Message: fmt.Sprintf("提交 %s 没有签名", short)}
+	case "E":
+		return &Error{Kind: ErrUntrusted, Op: "verify-signature", Message: fmt.Sprintf("提交 %s 的签名无法校验，签名密钥 %s 不在受信任的密钥环中", short, sig.Key)}
+	case "B":
+		return &Error{Kind: ErrUntrusted, Op: "verify-signature", Message: fmt.Sprintf("提交 %s 的签名无效", short)}
+	default:
+		// X、Y：签名或密钥已过期；R：密钥已吊销
+		return &Error{Kind: ErrUntrusted, Op: "verify-signature", Message: fmt.Sprintf("提交 %s 的签名密钥 %s 已过期或被吊销（%s）", short, sig.Key, sig.Status)}
+	}
+}
+
+// pushedCommits 返回需要校验的提交
+// 优先使用 Before..head 的范围；Before 不存在（新建分支或强制推送）时使用载荷中列出的提交
+func (g *runner) pushedCommits(policy *VerifyPolicy, head string) ([]string, error) {
+	if !policy.All {
+		return []string{head}, nil
+	}
+
+	if policy.Before != "" && policy.Before != zeroSHA {
+		if _, err := g.run("rev-parse", "merge-base", "--is-ancestor", policy.Before, head); err == nil {
+			out, err := g.run("rev-list", "rev-list", policy.Before+".."+head)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Fields(out), nil
+		}
+	}
+
+	commits := []string{head}
+	for _, commit := range policy.Commits {
+		if commit != "" && commit != head {
+			commits = append(commits, commit)
+		}
+	}
+	return commits, nil
+}
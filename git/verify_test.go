@@ -0,0 +1,27 @@
+package git
+
+import "testing"
+
+func TestCheckSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		sig     Signature
+		gpgHome string
+		kind    string
+	}{
+		{"good", Signature{Status: "G", Key: "ABCD"}, "", ""},
+		{"gpg unknown validity in gpg_home", Signature{Status: "U", Key: "ABCD"}, "/etc/hexo-autocd/gnupg", ""},
+		{"gpg unknown validity in default keyring", Signature{Status: "U", Key: "ABCD"}, "", ErrUntrusted},
+		{"ssh key not allowed", Signature{Status: "U", Key: "SHA256:abc"}, "/etc/hexo-autocd/gnupg", ErrUntrusted},
+		{"unsigned", Signature{Status: "N"}, "", ErrUnsigned},
+		{"missing key", Signature{Status: "E", Key: "ABCD"}, "/etc/hexo-autocd/gnupg", ErrUntrusted},
+		{"bad", Signature{Status: "B"}, "", ErrUntrusted},
+		{"revoked", Signature{Status: "R"}, "", ErrUntrusted},
+	}
+	for _, tt := range tests {
+		err := checkSignature(tt.sig, tt.gpgHome)
+		if tt.kind == "" && err != nil || tt.kind != "" && Kind(err) != tt.kind {
+			t.Errorf("%s: checkSignature() = %v, want kind %q", tt.name, err, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.map", "app.js.map", true},
+		{"**/*.map", "js/vendor/app.js.map", true},
+		{"css/**", "css/a/b.css", true},
+		{"css/**", "js/a.js", false},
+		{"*.html", "posts/a.html", false},
+		{"posts/*/index.html", "posts/a/index.html", true},
+		{"source/**/**/*.md", "source/a.md", true},
+		{"feature/*", "feature/a/b", false},
+		{"feature/**", "feature/a/b", true},
+		{"main", "main", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+	if !MatchAny([]string{"docs/**", "*.md"}, "a.md") || MatchAny(nil, "a.md") {
+		t.Error("MatchAny() mismatch")
+	}
+}
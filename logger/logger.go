@@ -2,6 +2,7 @@ package logger
 
 import (
 	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/redact"
 	"bytes"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/natefinch/lumberjack"
 	"github.com/sirupsen/logrus"
@@ -107,10 +109,15 @@ func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// fileHook 当前的文件输出钩子，热加载时需要替换它的格式化器
+var fileHook *FileHook
+
 // Init 初始化日志系统
 func Init() error {
+	cfg := config.Get()
+
 	// 创建日志目录
-	logDir := filepath.Dir(config.Config.Logs.Path)
+	logDir := filepath.Dir(cfg.Logs.Path)
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return fmt.Errorf("创建日志目录失败: %v", err)
 	}
@@ -118,20 +125,45 @@ func Init() error {
 	// 创建日志实例
 	Log = logrus.New()
 
+	// 脱敏钩子需要在写入文件的钩子之前执行
+	Log.AddHook(&RedactHook{})
+
+	// 添加调用者信息的钩子
+	Log.AddHook(&CallerHook{})
+
+	// 创建文件输出钩子
+	fileHook = NewFileHook(
+		cfg.Logs.Path,
+		cfg.Logs.MaxSize,
+		cfg.Logs.MaxBackups,
+		cfg.Logs.MaxAge,
+		nil)
+	Log.AddHook(fileHook)
+
+	// 应用日志级别与格式
+	Apply()
+
+	// 记录初始化成功日志
+	Log.Info("日志系统初始化成功")
+	return nil
+}
+
+// Apply 根据当前配置设置日志级别与格式
+// 配置热加载后调用，无需重建日志实例
+func Apply() {
+	cfg := config.Get()
+
 	// 配置日志级别
-	level, err := logrus.ParseLevel(config.Config.Logs.Level)
+	level, err := logrus.ParseLevel(cfg.Logs.Level)
 	if err != nil {
 		level = logrus.InfoLevel // 默认使用 info 级别
 	}
 	Log.SetLevel(level)
 
-	// 添加调用者信息的钩子
-	Log.AddHook(&CallerHook{})
-
 	// 根据配置选择格式化器
 	var consoleFormatter, fileFormatter logrus.Formatter
 
-	if strings.ToLower(config.Config.Logs.Format) == "json" {
+	if strings.ToLower(cfg.Logs.Format) == "json" {
 		// JSON格式
 		jsonFormatter := &logrus.JSONFormatter{
 			TimestampFormat: "2006-01-02 15:04:05",
@@ -157,19 +189,7 @@ func Init() error {
 
 	// 设置控制台输出配置
 	Log.SetFormatter(consoleFormatter)
-
-	// 创建文件输出钩子
-	fileHook := NewFileHook(
-		config.Config.Logs.Path,
-		config.Config.Logs.MaxSize,
-		config.Config.Logs.MaxBackups,
-		config.Config.Logs.MaxAge,
-		fileFormatter)
-	Log.AddHook(fileHook)
-
-	// 记录初始化成功日志
-	Log.Info("日志系统初始化成功")
-	return nil
+	fileHook.SetFormatter(fileFormatter)
 }
 
 // CallerHook 是一个自定义的logrus钩子，用于添加调用者信息
@@ -204,6 +224,28 @@ func (hook *CallerHook) Fire(entry *logrus.Entry) error {
 	return nil
 }
 
+// RedactHook 替换日志消息与字段中的密钥
+type RedactHook struct{}
+
+// Levels 指定钩子适用于哪些日志级别
+func (hook *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 在日志写出前替换消息与字符串、错误类型字段中的密钥
+func (hook *RedactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = redact.String(entry.Message)
+	for k, v := range entry.Data {
+		switch value := v.(type) {
+		case string:
+			entry.Data[k] = redact.String(value)
+		case error:
+			entry.Data[k] = redact.String(value.Error())
+		}
+	}
+	return nil
+}
+
 // WithField 返回带有指定字段的日志实例
 func WithField(key string, value interface{}) *logrus.Entry {
 	return Log.WithField(key, value)
@@ -294,6 +336,7 @@ func Panicf(format string, args ...interface{}) {
 // FileHook 文件日志钩子
 type FileHook struct {
 	writer    io.Writer
+	mu        sync.RWMutex // 保护 formatter 的并发替换
 	formatter logrus.Formatter
 }
 
@@ -318,9 +361,20 @@ func (h *FileHook) Levels() []logrus.Level {
 	return logrus.AllLevels
 }
 
+// SetFormatter 替换文件输出使用的格式化器
+func (h *FileHook) SetFormatter(formatter logrus.Formatter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.formatter = formatter
+}
+
 // Fire 将日志写入文件
 func (h *FileHook) Fire(entry *logrus.Entry) error {
-	line, err := h.formatter.Format(entry)
+	h.mu.RLock()
+	formatter := h.formatter
+	h.mu.RUnlock()
+
+	line, err := formatter.Format(entry)
 	if err != nil {
 		return err
 	}
@@ -1,18 +1,27 @@
 package middlewares
 
 import (
+	"Hexo-AutoCD/config"
 	"Hexo-AutoCD/logger"
+	"crypto/subtle"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func DenyScan() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
-		// 如果请求路径不是/webhooks，则返回错误信息和IP地址
-		if c.Request.URL.Path != "/webhook" {
+		// 启用管理接口时由 APIAuth 校验令牌
+		if config.Get().API.Token != "" && strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+		// 如果请求路径不是配置的 webhook 路径，则返回错误信息和IP地址
+		if c.Request.URL.Path != config.Get().Webhook.Path {
 			logger.Warnf("检测到扫描请求: %s %s 来自 %s", c.Request.Method, c.Request.URL.Path, c.ClientIP())
 			c.JSON(http.StatusNotFound, gin.H{"message": "请不要扫描我的博客！", "ip": c.ClientIP()})
+			c.Abort()
 			return
 		}
 		// 记录请求日志
@@ -20,3 +29,18 @@ func DenyScan() gin.HandlerFunc {
 		c.Next()
 	})
 }
+
+// APIAuth 校验管理接口的 Authorization: Bearer 令牌
+func APIAuth() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		expected := config.Get().API.Token
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			logger.Warnf("管理接口认证失败: %s %s 来自 %s", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"错误": "令牌无效"})
+			return
+		}
+		logger.Infof("接收到管理接口请求: %s %s 来自 %s", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+		c.Next()
+	})
+}
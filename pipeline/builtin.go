@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/posts"
+	"Hexo-AutoCD/publish"
+	"Hexo-AutoCD/scripts"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runBuiltin 在进程内执行内置步骤，失败时与其他步骤一样按配置重试
+// 输出与失败原因同样记录在步骤结果中，失败的退出码为 1
+func (r *Runner) runBuiltin(step config.Step, ctx *Context) scripts.StepResult {
+	stepLogger := logger.WithFields(logrus.Fields{
+		"步骤":   step.Name,
+		"内置步骤": step.Uses,
+	})
+	startTime := time.Now()
+	stepResult, attempts := scripts.Retry(r.stepRetryPolicy(step), stepLogger, func() (scripts.StepResult, scripts.Outcome) {
+		stepResult := scripts.StepResult{Name: step.Name}
+		var output string
+		var err error
+		switch step.Uses {
+		case "posts/preprocess":
+			output, err = r.preprocess(ctx)
+		case "posts/lint":
+			output, stepResult.Findings, err = r.lint(ctx)
+		case "site/publish":
+			output, stepResult.Sync, err = r.publish()
+		default:
+			err = fmt.Errorf("未知的内置步骤: %s", step.Uses)
+		}
+		stepResult.Output = output
+		if err != nil {
+			stepResult.ExitCode = 1
+			stepResult.Error = err.Error()
+		}
+		return stepResult, scripts.Outcome{ExitCode: stepResult.ExitCode, Output: output, Error: stepResult.Error}
+	})
+	stepResult.StartedAt = startTime.Format(time.RFC3339)
+	stepResult.DurationMs = time.Since(startTime).Milliseconds()
+	stepResult.Attempts = len(attempts)
+	if len(attempts) > 1 {
+		stepResult.History = attempts
+	}
+
+	if stepResult.Error != "" {
+		stepResult.Status = scripts.StepFailed
+		stepLogger.WithFields(logrus.Fields{
+			"错误信息": stepResult.Error,
+			"执行次数": stepResult.Attempts,
+		}).Error("步骤执行失败")
+	} else {
+		stepResult.Status = scripts.StepSuccess
+		stepLogger.WithField("耗时", time.Since(startTime).String()).Info("步骤执行成功")
+	}
+	return stepResult
+}
+
+// preprocess 为推送中变更的文章补全 front-matter
+func (r *Runner) preprocess(ctx *Context) (string, error) {
+	if r.config.Posts.SourceDir == "" {
+		return "", fmt.Errorf("未设置 posts.source_dir 或 site.repo_dir")
+	}
+	p := posts.NewPreprocessor(r.config.Posts)
+
+	var b strings.Builder
+	var failed int
+	for _, result := range p.Apply(r.postFiles(ctx)) {
+		if result.Error != nil {
+			failed++
+			fmt.Fprintf(&b, "✗ %s: %v\n", result.Source, result.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s\n", result.Action, result.Source)
+	}
+	if failed > 0 {
+		return b.String(), fmt.Errorf("%d 篇文章处理失败", failed)
+	}
+	return b.String(), nil
+}
+
+// lint 检查推送中变更的文章，按 posts.lint.fail_on 决定是否使步骤失败
+func (r *Runner) lint(ctx *Context) (string, []scripts.Finding, error) {
+	if r.config.Posts.SourceDir == "" {
+		return "", nil, fmt.Errorf("未设置 posts.source_dir 或 site.repo_dir")
+	}
+	l := posts.NewLinter(r.config.Posts)
+
+	// 设置了输出目录时检查预处理后的文章，预处理会把文章平铺到输出目录中
+	var files []string
+	for _, file := range r.postFiles(ctx) {
+		if !posts.IsPost(file) {
+			continue
+		}
+		if r.config.Posts.DestDir != "" {
+			file = filepath.Base(filepath.FromSlash(file))
+		}
+		files = append(files, file)
+	}
+
+	found, err := l.Lint(files)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	findings := make([]scripts.Finding, 0, len(found))
+	var errorCount int
+	for _, f := range found {
+		if f.Severity == posts.SeverityError {
+			errorCount++
+		}
+		fmt.Fprintln(&b, f.String())
+		findings = append(findings, scripts.Finding{File: f.File, Line: f.Line, Rule: f.Rule, Severity: f.Severity, Message: f.Message})
+	}
+	fmt.Fprintf(&b, "检查了 %d 篇文章，%d 个错误，%d 个警告\n", len(files), errorCount, len(found)-errorCount)
+	if l.Failed(found) {
+		return b.String(), findings, fmt.Errorf("文章检查未通过：%d 个错误，%d 个警告", errorCount, len(found)-errorCount)
+	}
+	return b.String(), findings, nil
+}
+
+// publish 把生成目录同步到 publish.targets 中的各个目标
+func (r *Runner) publish() (string, []scripts.SyncResult, error) {
+	source := r.config.PublicDir
+	if source == "" {
+		source = r.config.Publish.Source
+	}
+	if source == "" {
+		return "", nil, fmt.Errorf("未设置 publish.source 或 site.repo_dir")
+	}
+
+	results, err := publish.New(r.config.Publish).Sync(source)
+	var b strings.Builder
+	synced := make([]scripts.SyncResult, 0, len(results))
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(&b, "✗ %s: %s\n", result.Target, result.Error)
+		} else {
+			fmt.Fprintf(&b, "%s: 上传 %d，删除 %d，未变 %d\n", result.Target, result.Uploaded, result.Deleted, result.Unchanged)
+		}
+		synced = append(synced, scripts.SyncResult{
+			Target:     result.Target,
+			Type:       result.Type,
+			Uploaded:   result.Uploaded,
+			Deleted:    result.Deleted,
+			Unchanged:  result.Unchanged,
+			Bytes:      result.Bytes,
+			Error:      result.Error,
+			DurationMs: result.Duration.Milliseconds(),
+		})
+	}
+	return b.String(), synced, err
+}
+
+// postFiles 把推送中相对于博客仓库的文件路径转换为相对于文章目录的路径
+func (r *Runner) postFiles(ctx *Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	return posts.Relative(r.config.Posts.SourceDir, r.config.RepoDir, ctx.Changed)
+}
@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/scripts"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Runner 按顺序执行流水线中的各个步骤
+// 它实现了 scripts.ScriptExecutor 接口，可以替代单个部署脚本
+type Runner struct {
+	executor scripts.CommandRunner // 实际执行命令的执行器，可以在本机或容器中执行
+	config   Config
+
+	deferred []config.Step // 推迟到 Publish 中执行的 site/publish 步骤
+	ctx      *Context
+}
+
+// Config 定义流水线执行器配置
+type Config struct {
+	Steps   []config.Step // 要执行的步骤
+	BaseDir string        // 步骤相对工作目录的基准目录
+	RepoDir string        // 博客仓库目录，推送中的文件路径相对于它
+	Posts   config.Posts  // 内置文章步骤使用的配置
+
+	Publish   config.Publish // 内置同步步骤使用的配置
+	PublicDir string         // 同步的生成目录，为空时使用 publish.source
+
+	Retry config.Retry // 未设置重试的步骤使用的重试策略，即 scripts.retry
+}
+
+// New 创建流水线执行器
+func New(executor scripts.CommandRunner, config Config) *Runner {
+	return &Runner{
+		executor: executor,
+		config:   config,
+	}
+}
+
+// Steps 返回本次部署要执行的步骤以及它们的来源
+// 允许使用仓库中的流水线文件且文件存在时，以文件中的步骤为准，否则使用配置文件中的 pipeline.steps
+func Steps(cfg *config.Pipeline, repoDir string) ([]config.Step, string, error) {
+	if cfg.AllowRepoFile && repoDir != "" {
+		path := cfg.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repoDir, path)
+		}
+		file, err := config.LoadPipelineFile(path)
+		if err != nil {
+			return nil, path, err
+		}
+		if file != nil && len(file.Steps) > 0 {
+			return file.Steps, path, nil
+		}
+	}
+	return cfg.Steps, "pipeline.steps", nil
+}
+
+// Execute 依次执行所有步骤
+// payload 为 *Context 时会先判断每个步骤的执行条件，不满足条件的步骤被标记为跳过
+// 某个步骤失败后，后续步骤都会被标记为跳过；整体的退出码与错误信息取自失败的步骤
+func (r *Runner) Execute(event string, payload interface{}) (*scripts.ExecutionResult, error) {
+	result := &scripts.ExecutionResult{}
+	var failed *scripts.StepResult
+	ctx, _ := payload.(*Context)
+
+	for _, step := range r.config.Steps {
+		if failed != nil {
+			result.Steps = append(result.Steps, scripts.StepResult{
+				Name:   step.Name,
+				Status: scripts.StepSkipped,
+				Reason: fmt.Sprintf("前置步骤 %s 失败", failed.Name),
+			})
+			continue
+		}
+
+		if reason := skipReason(step.When, ctx); reason != "" {
+			logger.WithFields(logrus.Fields{
+				"步骤": step.Name,
+				"原因": reason,
+			}).Info("不满足执行条件，跳过步骤")
+			result.Steps = append(result.Steps, scripts.StepResult{
+				Name:   step.Name,
+				Status: scripts.StepSkipped,
+				Reason: reason,
+			})
+			continue
+		}
+
+		// 同步到远程目标后无法撤回，推迟到发布版本并通过冒烟检查后执行
+		if step.Uses == "site/publish" {
+			r.deferred = append(r.deferred, step)
+			r.ctx = ctx
+			continue
+		}
+
+		var stepResult scripts.StepResult
+		var logs []string
+		if step.Uses != "" {
+			stepResult = r.runBuiltin(step, ctx)
+		} else {
+			stepResult, logs = r.runStep(step)
+		}
+		result.Steps = append(result.Steps, stepResult)
+		result.Logs = append(result.Logs, logs...)
+		if stepResult.Output != "" {
+			result.Output += stepResult.Output
+		}
+
+		if stepResult.Status == scripts.StepFailed {
+			failed = &result.Steps[len(result.Steps)-1]
+			result.ExitCode = stepResult.ExitCode
+			result.Error = fmt.Sprintf("步骤 %s 失败: %s", step.Name, stepResult.Error)
+		}
+	}
+
+	return result, nil
+}
+
+// Publish 执行 Execute 中推迟的 site/publish 步骤，部署在切换版本并通过冒烟检查后调用
+// reason 不为空时不执行，这些步骤以 reason 为原因标记为跳过
+func (r *Runner) Publish(reason string) []scripts.StepResult {
+	var results []scripts.StepResult
+	for _, step := range r.deferred {
+		if reason != "" {
+			results = append(results, scripts.StepResult{
+				Name:   step.Name,
+				Status: scripts.StepSkipped,
+				Reason: reason,
+			})
+			continue
+		}
+		results = append(results, r.runBuiltin(step, r.ctx))
+	}
+	r.deferred = nil
+	return results
+}
+
+// runStep 执行单个步骤，失败时按配置重试
+func (r *Runner) runStep(step config.Step) (scripts.StepResult, []string) {
+	stepLogger := logger.WithField("步骤", step.Name)
+
+	// 时间间隔已在加载配置时校验过
+	timeout, _ := time.ParseDuration(step.Timeout)
+
+	dir := step.Dir
+	if dir == "" {
+		dir = r.config.BaseDir
+	} else if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.config.BaseDir, dir)
+	}
+
+	// 步骤中的 sandbox 替换 scripts.sandbox
+	var sandbox *scripts.SandboxConfig
+	if step.Sandbox != nil {
+		sandbox = Sandbox(*step.Sandbox)
+	}
+
+	stepResult := scripts.StepResult{
+		Name:      step.Name,
+		StartedAt: time.Now().Format(time.RFC3339),
+	}
+	startTime := time.Now()
+
+	var logs []string
+	res, attempts, err := scripts.RetryExecution(r.stepRetryPolicy(step), stepLogger, func() (*scripts.ExecutionResult, error) {
+		res, err := r.executor.Run(scripts.Command{
+			Name:    step.Name,
+			Path:    "/bin/bash",
+			Args:    []string{"-c", step.Run},
+			Dir:     dir,
+			Env:     step.Env,
+			Timeout: timeout,
+			Sandbox: sandbox,
+		})
+		if err == nil {
+			logs = append(logs, res.Logs...)
+		}
+		return res, err
+	})
+	stepResult.Attempts = len(attempts)
+	if len(attempts) > 1 {
+		stepResult.History = attempts
+	}
+	if err != nil {
+		stepResult.ExitCode = -1
+		stepResult.Error = err.Error()
+	} else {
+		stepResult.Output = res.Output
+		stepResult.ExitCode = res.ExitCode
+		stepResult.Error = res.Error
+		stepResult.Hosts = res.Hosts
+	}
+
+	stepResult.DurationMs = time.Since(startTime).Milliseconds()
+	if stepResult.ExitCode == 0 && stepResult.Error == "" {
+		stepResult.Status = scripts.StepSuccess
+		stepLogger.WithField("耗时", time.Since(startTime).String()).Info("步骤执行成功")
+	} else {
+		stepResult.Status = scripts.StepFailed
+		stepLogger.WithFields(logrus.Fields{
+			"退出码":  stepResult.ExitCode,
+			"错误信息": strings.TrimSpace(stepResult.Error),
+			"执行次数": stepResult.Attempts,
+		}).Error("步骤执行失败")
+	}
+	return stepResult, logs
+}
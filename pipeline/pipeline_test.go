@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/scripts"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// fakeRunner 记录执行的命令，按步骤名依次返回 results 中的结果
+type fakeRunner struct {
+	commands []scripts.Command
+	results  map[string][]fakeResult
+}
+
+type fakeResult struct {
+	exitCode int
+	err      error
+}
+
+func (f *fakeRunner) Run(command scripts.Command) (*scripts.ExecutionResult, error) {
+	f.commands = append(f.commands, command)
+	results := f.results[command.Name]
+	if len(results) == 0 {
+		return &scripts.ExecutionResult{Output: command.Name + " ok\n"}, nil
+	}
+	r := results[0]
+	f.results[command.Name] = results[1:]
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &scripts.ExecutionResult{ExitCode: r.exitCode, Output: command.Name + " output\n"}, nil
+}
+
+// statuses 返回各步骤的名称与状态，如 build=success
+func statuses(steps []scripts.StepResult) []string {
+	var got []string
+	for _, s := range steps {
+		got = append(got, s.Name+"="+s.Status)
+	}
+	return got
+}
+
+func TestExecuteOrder(t *testing.T) {
+	runner := &fakeRunner{}
+	r := New(runner, Config{
+		BaseDir: "/srv/scripts",
+		Steps: []config.Step{
+			{Name: "install", Run: "npm ci", Dir: "blog", Env: []string{"NODE_ENV=production"}},
+			{Name: "build", Run: "hexo generate", Dir: "/home/hexo/blog", Timeout: "5m"},
+			{Name: "notify", Run: "echo done"},
+		},
+	})
+	result, err := r.Execute("push", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := statuses(result.Steps); !reflect.DeepEqual(got, []string{"install=success", "build=success", "notify=success"}) {
+		t.Errorf("steps = %v", got)
+	}
+	if result.ExitCode != 0 || result.Output != "install ok\nbuild ok\nnotify ok\n" {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 按顺序执行，相对工作目录基于 BaseDir
+	var dirs []string
+	for _, c := range runner.commands {
+		dirs = append(dirs, c.Dir)
+	}
+	if !reflect.DeepEqual(dirs, []string{"/srv/scripts/blog", "/home/hexo/blog", "/srv/scripts"}) {
+		t.Errorf("dirs = %v", dirs)
+	}
+	first := runner.commands[0]
+	if first.Path != "/bin/bash" || !reflect.DeepEqual(first.Args, []string{"-c", "npm ci"}) || !reflect.DeepEqual(first.Env, []string{"NODE_ENV=production"}) {
+		t.Errorf("command = %+v", first)
+	}
+	if runner.commands[1].Timeout.String() != "5m0s" {
+		t.Errorf("timeout = %s", runner.commands[1].Timeout)
+	}
+}
+
+func TestExecuteStopsOnFailure(t *testing.T) {
+	runner := &fakeRunner{results: map[string][]fakeResult{"build": {{exitCode: 3}}}}
+	r := New(runner, Config{Steps: []config.Step{
+		{Name: "install", Run: "npm ci"},
+		{Name: "build", Run: "hexo generate"},
+		{Name: "deploy", Run: "rsync"},
+		{Name: "notify", Run: "echo done"},
+	}})
+	result, _ := r.Execute("push", nil)
+	if got := statuses(result.Steps); !reflect.DeepEqual(got, []string{"install=success", "build=failed", "deploy=skipped", "notify=skipped"}) {
+		t.Errorf("steps = %v", got)
+	}
+	if len(runner.commands) != 2 {
+		t.Errorf("ran %d commands, want 2", len(runner.commands))
+	}
+	if result.ExitCode != 3 || !strings.Contains(result.Error, "步骤 build 失败") {
+		t.Errorf("exit code = %d, error = %q", result.ExitCode, result.Error)
+	}
+	if reason := result.Steps[2].Reason; reason != "前置步骤 build 失败" {
+		t.Errorf("skip reason = %q", reason)
+	}
+}
+
+func TestExecuteRetries(t *testing.T) {
+	runner := &fakeRunner{results: map[string][]fakeResult{
+		"install": {{exitCode: 1}, {}},
+		"build":   {{err: errors.New("容器启动失败")}, {err: errors.New("容器启动失败")}},
+	}}
+	r := New(runner, Config{Steps: []config.Step{
+		{Name: "install", Run: "npm ci", Retries: 2, RetryDelay: "1ms"},
+		{Name: "build", Run: "hexo generate", Retries: 1, RetryDelay: "1ms"},
+	}})
+	result, _ := r.Execute("push", nil)
+	install, build := result.Steps[0], result.Steps[1]
+	if install.Status != scripts.StepSuccess || install.Attempts != 2 || len(install.History) != 2 {
+		t.Errorf("install = %+v", install)
+	}
+	// 执行器本身出错时退出码为 -1
+	if build.Status != scripts.StepFailed || build.Attempts != 2 || build.ExitCode != -1 || build.Error != "容器启动失败" {
+		t.Errorf("build = %+v", build)
+	}
+}
+
+func TestExecuteBuiltin(t *testing.T) {
+	runner := &fakeRunner{}
+	r := New(runner, Config{Steps: []config.Step{
+		{Name: "publish", Uses: "site/publish"},
+		{Name: "unknown", Uses: "site/unknown"},
+		{Name: "after", Run: "echo"},
+	}})
+	result, _ := r.Execute("push", &Context{})
+
+	// site/publish 推迟到 Publish 中执行，内置步骤不经过执行器
+	if got := statuses(result.Steps); !reflect.DeepEqual(got, []string{"unknown=failed", "after=skipped"}) {
+		t.Errorf("steps = %v", got)
+	}
+	if len(runner.commands) != 0 {
+		t.Errorf("builtin steps ran %d commands", len(runner.commands))
+	}
+	if s := result.Steps[0]; s.ExitCode != 1 || !strings.Contains(s.Error, "未知的内置步骤") || result.ExitCode != 1 {
+		t.Errorf("unknown builtin = %+v", s)
+	}
+
+	// 原因不为空时跳过，否则执行；未设置生成目录时同步失败
+	if got := r.Publish(""); len(got) != 1 || got[0].Status != scripts.StepFailed || !strings.Contains(got[0].Error, "publish.source") {
+		t.Errorf("Publish() = %+v", got)
+	}
+	r.Execute("push", &Context{})
+	if got := r.Publish("冒烟检查失败"); len(got) != 1 || got[0].Status != scripts.StepSkipped || got[0].Reason != "冒烟检查失败" {
+		t.Errorf("Publish(reason) = %+v", got)
+	}
+	if got := r.Publish(""); len(got) != 0 {
+		t.Errorf("Publish() ran deferred steps twice: %+v", got)
+	}
+}
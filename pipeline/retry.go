@@ -0,0 +1,52 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/scripts"
+	"regexp"
+	"time"
+)
+
+// RetryPolicy 把 scripts.retry、git.retry 或步骤中的 retry 转换为重试策略，不重试时返回 nil
+func RetryPolicy(cfg config.Retry) *scripts.RetryPolicy {
+	if cfg.Retries <= 0 {
+		return nil
+	}
+	// 时间间隔与正则表达式已在加载配置时校验过
+	policy := &scripts.RetryPolicy{
+		Retries:    cfg.Retries,
+		Delay:      10 * time.Second,
+		MaxDelay:   5 * time.Minute,
+		Multiplier: cfg.Multiplier,
+		Jitter:     cfg.Jitter,
+		ExitCodes:  cfg.ExitCodes,
+	}
+	if d, err := time.ParseDuration(cfg.Delay); err == nil {
+		policy.Delay = d
+	}
+	if d, err := time.ParseDuration(cfg.MaxDelay); err == nil {
+		policy.MaxDelay = d
+	}
+	if policy.Multiplier == 0 {
+		policy.Multiplier = 2
+	}
+	for _, pattern := range cfg.Patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			policy.Patterns = append(policy.Patterns, re)
+		}
+	}
+	return policy
+}
+
+// stepRetryPolicy 返回步骤的重试策略
+// 步骤中的 retry 优先，其次是 retries 与 retry_delay 定义的固定间隔重试，都未设置时使用 scripts.retry
+func (r *Runner) stepRetryPolicy(step config.Step) *scripts.RetryPolicy {
+	if step.Retry != nil {
+		return RetryPolicy(*step.Retry)
+	}
+	if step.Retries > 0 {
+		retryDelay, _ := time.ParseDuration(step.RetryDelay)
+		return &scripts.RetryPolicy{Retries: step.Retries, Delay: retryDelay, Multiplier: 1}
+	}
+	return RetryPolicy(r.config.Retry)
+}
@@ -0,0 +1,27 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/scripts"
+	"time"
+)
+
+// Sandbox 把 scripts.sandbox 或步骤中的 sandbox 转换为执行器使用的限制
+func Sandbox(cfg config.Sandbox) *scripts.SandboxConfig {
+	// 时间间隔与大小已在加载配置时校验过
+	cpu, _ := time.ParseDuration(cfg.CPU)
+	memory, _ := config.ParseBytes(cfg.Memory)
+	return &scripts.SandboxConfig{
+		User:      cfg.User,
+		Group:     cfg.Group,
+		CleanEnv:  cfg.Env == "clean",
+		EnvAllow:  cfg.EnvAllow,
+		CPU:       cpu,
+		Memory:    memory,
+		Files:     cfg.Files,
+		Processes: cfg.Processes,
+		Chroot:    cfg.Chroot,
+		Landlock:  cfg.Landlock,
+		Writable:  cfg.Writable,
+	}
+}
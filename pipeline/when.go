@@ -0,0 +1,64 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/glob"
+	"fmt"
+	"strings"
+)
+
+// Context 描述触发本次部署的推送，用于判断步骤的执行条件
+// 作为 Execute 的 payload 传入；payload 不是 Context 时所有条件都视为满足
+type Context struct {
+	Branch   string   // 推送的分支名，不含 refs/heads/ 前缀
+	Messages []string // 推送中所有提交的提交信息
+	Changed  []string // 推送中所有新增、修改与删除的文件
+}
+
+// skipReason 判断步骤是否应该执行，返回非空字符串表示跳过及其原因
+func skipReason(when config.When, ctx *Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if len(when.Branches) > 0 && !glob.MatchAny(when.Branches, ctx.Branch) {
+		return fmt.Sprintf("分支 %s 不匹配 %s", ctx.Branch, strings.Join(when.Branches, ", "))
+	}
+
+	if marker := findMarker(when.SkipMessage, ctx.Messages); marker != "" {
+		return fmt.Sprintf("提交信息包含 %s", marker)
+	}
+
+	if len(when.Paths) == 0 && len(when.Message) == 0 {
+		return ""
+	}
+	for _, file := range ctx.Changed {
+		if glob.MatchAny(when.Paths, file) {
+			return ""
+		}
+	}
+	if findMarker(when.Message, ctx.Messages) != "" {
+		return ""
+	}
+
+	var conditions []string
+	if len(when.Paths) > 0 {
+		conditions = append(conditions, "没有文件匹配 "+strings.Join(when.Paths, ", "))
+	}
+	if len(when.Message) > 0 {
+		conditions = append(conditions, "提交信息不包含 "+strings.Join(when.Message, ", "))
+	}
+	return strings.Join(conditions, "，")
+}
+
+// findMarker 返回第一个出现在任一提交信息中的标记，标记不区分大小写
+func findMarker(markers, messages []string) string {
+	for _, marker := range markers {
+		for _, message := range messages {
+			if strings.Contains(strings.ToLower(message), strings.ToLower(marker)) {
+				return marker
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"Hexo-AutoCD/config"
+	"reflect"
+	"testing"
+)
+
+func TestSkipReason(t *testing.T) {
+	ctx := &Context{
+		Branch:   "release/2024/05",
+		Messages: []string{"更新文章", "修复样式 [Skip Deploy]"},
+		Changed:  []string{"source/_posts/技术/go.md", "themes/next/_config.yml"},
+	}
+	tests := []struct {
+		name string
+		when config.When
+		skip bool
+	}{
+		{"no conditions", config.When{}, false},
+
+		// 分支
+		{"branch exact", config.When{Branches: []string{"main", "release/2024/05"}}, false},
+		{"branch single level", config.When{Branches: []string{"release/*"}}, true},
+		{"branch any depth", config.When{Branches: []string{"release/**"}}, false},
+		{"branch mismatch", config.When{Branches: []string{"main"}}, true},
+
+		// 文件路径
+		{"paths any depth", config.When{Paths: []string{"source/_posts/**"}}, false},
+		{"paths ** in the middle", config.When{Paths: []string{"**/_config.yml"}}, false},
+		{"paths single level", config.When{Paths: []string{"source/_posts/*"}}, true},
+		{"paths mismatch", config.When{Paths: []string{"source/about/**", "*.yml"}}, true},
+
+		// 提交信息中的标记，不区分大小写
+		{"skip marker", config.When{SkipMessage: []string{"[skip deploy]"}}, true},
+		{"skip marker absent", config.When{SkipMessage: []string{"[skip ci]"}}, false},
+		{"skip marker wins over paths", config.When{Paths: []string{"source/**"}, SkipMessage: []string{"[skip deploy]"}}, true},
+		{"message marker", config.When{Message: []string{"[SKIP DEPLOY]"}}, false},
+		{"message marker absent", config.When{Message: []string{"[full rebuild]"}}, true},
+		// paths 与 message 满足其一即可
+		{"paths or message", config.When{Paths: []string{"scaffolds/**"}, Message: []string{"修复样式"}}, false},
+		// branches 与 paths 都需满足
+		{"branch and paths", config.When{Branches: []string{"main"}, Paths: []string{"source/**"}}, true},
+	}
+	for _, tt := range tests {
+		if reason := skipReason(tt.when, ctx); (reason != "") != tt.skip {
+			t.Errorf("%s: skipReason() = %q, want skip = %v", tt.name, reason, tt.skip)
+		}
+	}
+
+	// 没有推送信息时所有条件都视为满足
+	if reason := skipReason(config.When{Branches: []string{"main"}, SkipMessage: []string{"x"}}, nil); reason != "" {
+		t.Errorf("skipReason() without context = %q", reason)
+	}
+
+	// 标签推送没有分支，设置了 branches 的步骤跳过
+	if reason := skipReason(config.When{Branches: []string{"**"}}, &Context{}); reason != "" {
+		t.Errorf("** matched no branch: %q", reason)
+	}
+	if reason := skipReason(config.When{Branches: []string{"main"}}, &Context{}); reason == "" {
+		t.Error("tag push ran a step restricted to main")
+	}
+}
+
+func TestExecuteWhen(t *testing.T) {
+	runner := &fakeRunner{}
+	r := New(runner, Config{Steps: []config.Step{
+		{Name: "posts", Run: "a", When: config.When{Paths: []string{"source/_posts/**"}}},
+		{Name: "theme", Run: "b", When: config.When{Paths: []string{"themes/**"}}},
+		{Name: "deploy", Run: "c", When: config.When{SkipMessage: []string{"[skip deploy]"}}},
+	}})
+	result, _ := r.Execute("push", &Context{
+		Branch:   "main",
+		Messages: []string{"新文章 [skip deploy]"},
+		Changed:  []string{"source/_posts/a/b.md"},
+	})
+	want := []string{"posts=success", "theme=skipped", "deploy=skipped"}
+	if got := statuses(result.Steps); !reflect.DeepEqual(got, want) {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+	if result.Steps[1].Reason != "没有文件匹配 themes/**" || result.Steps[2].Reason != "提交信息包含 [skip deploy]" {
+		t.Errorf("reasons = %q, %q", result.Steps[1].Reason, result.Steps[2].Reason)
+	}
+	// 因条件跳过的步骤不影响整体结果
+	if result.ExitCode != 0 || len(runner.commands) != 1 {
+		t.Errorf("exit code = %d, commands = %d", result.ExitCode, len(runner.commands))
+	}
+}
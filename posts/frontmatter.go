@@ -0,0 +1,184 @@
+package posts
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter 文章的 YAML front-matter
+// 使用 yaml.Node 保存，写回时保留作者原有字段的顺序、注释与写法
+type FrontMatter struct {
+	node *yaml.Node // 映射节点
+}
+
+// Split 把文章拆分为 front-matter 与正文
+// 文章以 --- 开头且存在结束的 ---（或 ...）时才视为有 front-matter，否则整个文件都是正文
+// 返回的 line 为正文在文件中的起始行号（从 1 开始）
+func Split(content []byte) (front []byte, body []byte, line int, ok bool) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) == 0 || strings.TrimRight(string(lines[0]), "\r\n") != "---" {
+		return nil, content, 1, false
+	}
+	offset := len(lines[0])
+	for i := 1; i < len(lines); i++ {
+		text := strings.TrimRight(string(lines[i]), "\r\n")
+		if text == "---" || text == "..." {
+			return content[len(lines[0]):offset], content[offset+len(lines[i]):], i + 2, true
+		}
+		offset += len(lines[i])
+	}
+	return nil, content, 1, false
+}
+
+// ParseFrontMatter 解析 front-matter，内容为空时返回空的映射
+func ParseFrontMatter(front []byte) (*FrontMatter, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(front, &doc); err != nil {
+		return nil, fmt.Errorf("front-matter 不是合法的 YAML: %v", err)
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return &FrontMatter{node: &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("front-matter 必须是键值对，第 %d 行", root.Line)
+	}
+	return &FrontMatter{node: root}, nil
+}
+
+// Has 判断是否存在指定字段，值为空（null、空字符串或空列表）时视为不存在
+func (f *FrontMatter) Has(key string) bool {
+	value := f.lookup(key)
+	if value == nil {
+		return false
+	}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return value.Tag != "!!null" && value.Value != ""
+	case yaml.SequenceNode, yaml.MappingNode:
+		return len(value.Content) > 0
+	}
+	return true
+}
+
+// String 返回字符串字段的值
+func (f *FrontMatter) String(key string) string {
+	if value := f.lookup(key); value != nil && value.Kind == yaml.ScalarNode && value.Tag != "!!null" {
+		return value.Value
+	}
+	return ""
+}
+
+// Strings 返回列表字段的值，字段是单个字符串时返回只有一个元素的列表
+func (f *FrontMatter) Strings(key string) []string {
+	value := f.lookup(key)
+	if value == nil {
+		return nil
+	}
+	return flatten(value)
+}
+
+// Line 返回字段在 front-matter 中的行号（从 1 开始），字段不存在时返回 0
+func (f *FrontMatter) Line(key string) int {
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if f.node.Content[i].Value == key {
+			return f.node.Content[i].Line
+		}
+	}
+	return 0
+}
+
+// Keys 返回所有字段名
+func (f *FrontMatter) Keys() []string {
+	keys := make([]string, 0, len(f.node.Content)/2)
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		keys = append(keys, f.node.Content[i].Value)
+	}
+	return keys
+}
+
+// Set 设置字段，已存在时原地替换值，否则追加到末尾
+func (f *FrontMatter) Set(key string, value interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+	f.setNode(key, &node)
+	return nil
+}
+
+// SetPlain 设置字符串字段，不加引号时仍读作字符串或日期的值不加引号
+// 用于日期等字段，避免写成 date: "2024-01-01 10:00:00"；2024、true、null 等值加上引号，避免被读作数字、布尔值或空值
+func (f *FrontMatter) SetPlain(key, value string) {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if tag := node.ShortTag(); tag != "!!str" && tag != "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	f.setNode(key, node)
+}
+
+func (f *FrontMatter) setNode(key string, node *yaml.Node) {
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if f.node.Content[i].Value == key {
+			f.node.Content[i+1] = node
+			return
+		}
+	}
+	f.node.Content = append(f.node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		node,
+	)
+}
+
+// Render 把 front-matter 与正文重新组合为完整的文章
+func (f *FrontMatter) Render(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	if len(f.node.Content) > 0 {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(f.node); err != nil {
+			return nil, err
+		}
+		enc.Close()
+	}
+	buf.WriteString("---\n")
+	if len(body) > 0 && !bytes.HasPrefix(body, []byte("\n")) && !bytes.HasPrefix(body, []byte("\r\n")) {
+		buf.WriteString("\n")
+	}
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// lookup 返回字段对应的值节点
+func (f *FrontMatter) lookup(key string) *yaml.Node {
+	for i := 0; i+1 < len(f.node.Content); i += 2 {
+		if f.node.Content[i].Value == key {
+			return f.node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// flatten 把标量或（嵌套的）列表展开为字符串列表
+// Hexo 的分类可以写成嵌套列表表示并列的多个分类层级
+func flatten(node *yaml.Node) []string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" || node.Value == "" {
+			return nil
+		}
+		return []string{node.Value}
+	case yaml.SequenceNode:
+		var values []string
+		for _, item := range node.Content {
+			values = append(values, flatten(item)...)
+		}
+		return values
+	}
+	return nil
+}
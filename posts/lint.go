@@ -0,0 +1,402 @@
+package posts
+
+import (
+	"Hexo-AutoCD/config"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 问题级别
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// 检查规则
+const (
+	RuleFrontMatter    = "front_matter"    // front-matter 缺失或不是合法的 YAML
+	RuleRequired       = "required"        // 缺少必填字段
+	RuleDate           = "date"            // 日期格式不正确
+	RuleCategory       = "category"        // 分类不在允许的列表中
+	RuleTags           = "tags"            // 标签数量不符合要求
+	RuleDuplicateTitle = "duplicate_title" // 与其他文章标题重复
+	RuleDuplicateSlug  = "duplicate_slug"  // 与其他文章的 slug 重复，生成时会互相覆盖
+	RuleMissingImage   = "missing_image"   // 引用的本地图片不存在
+	RuleBrokenLink     = "broken_link"     // 链接到不存在的文章
+)
+
+// Finding 描述检查发现的一个问题
+type Finding struct {
+	File     string // 文章路径，相对于检查目录
+	Line     int    // 行号，从 1 开始；为 0 表示整个文件
+	Rule     string
+	Severity string
+	Message  string
+}
+
+func (f Finding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("%s: [%s] %s（%s）", location, f.Severity, f.Message, f.Rule)
+}
+
+// Linter 检查文章的 front-matter 与正文中的本地引用
+type Linter struct {
+	Dir        string // 文章目录
+	SourceRoot string // Hexo 的 source 目录，为空时不检查 / 开头的图片
+	Rules      config.Lint
+
+	index map[string]postInfo // 目录中所有文章的标题与 slug，用于检查重复
+}
+
+// postInfo 文章的标题与 slug
+type postInfo struct {
+	title string
+	slug  string
+}
+
+// NewLinter 根据配置创建检查器
+// 设置了 posts.dest_dir 时检查预处理后的文章，即 Hexo 实际生成的内容，否则检查文章仓库
+func NewLinter(cfg config.Posts) *Linter {
+	dir := cfg.DestDir
+	if dir == "" {
+		dir = cfg.SourceDir
+	}
+	return &Linter{Dir: dir, SourceRoot: cfg.Lint.SourceRoot, Rules: cfg.Lint}
+}
+
+// Failed 判断按 fail_on 配置，这些问题是否应使部署失败
+func (l *Linter) Failed(findings []Finding) bool {
+	for _, f := range findings {
+		switch l.Rules.FailOn {
+		case "warning":
+			return true
+		case "error":
+			if f.Severity == SeverityError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Lint 检查指定的文章，files 为相对于 Dir 的路径
+// 标题与 slug 的重复检查会与目录中的所有文章比较，但只报告 files 中的文章
+func (l *Linter) Lint(files []string) ([]Finding, error) {
+	if err := l.buildIndex(); err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(l.Dir, filepath.FromSlash(file)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return findings, err
+		}
+		findings = append(findings, l.lintFile(file, content)...)
+	}
+	return findings, nil
+}
+
+// All 返回检查目录中的所有文章
+func (l *Linter) All() ([]string, error) {
+	p := &Preprocessor{SourceDir: l.Dir}
+	return p.All()
+}
+
+// buildIndex 读取目录中所有文章的标题与 slug
+func (l *Linter) buildIndex() error {
+	files, err := l.All()
+	if err != nil {
+		return fmt.Errorf("读取文章目录失败: %v", err)
+	}
+	l.index = make(map[string]postInfo, len(files))
+	for _, file := range files {
+		info := postInfo{slug: slugOf(file, nil)}
+		if content, err := os.ReadFile(filepath.Join(l.Dir, filepath.FromSlash(file))); err == nil {
+			if front, _, _, ok := Split(content); ok {
+				if fm, err := ParseFrontMatter(front); err == nil {
+					info.title = fm.String("title")
+					info.slug = slugOf(file, fm)
+				}
+			}
+		}
+		l.index[file] = info
+	}
+	return nil
+}
+
+// lintFile 检查单篇文章
+func (l *Linter) lintFile(file string, content []byte) []Finding {
+	var findings []Finding
+	add := func(line int, rule, severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{File: file, Line: line, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	front, body, bodyLine, ok := Split(content)
+	if !ok {
+		add(1, RuleFrontMatter, SeverityError, "缺少 front-matter")
+	} else if fm, err := ParseFrontMatter(front); err != nil {
+		add(2, RuleFrontMatter, SeverityError, "%v", err)
+	} else {
+		findings = append(findings, l.lintFrontMatter(file, fm)...)
+	}
+
+	findings = append(findings, l.lintBody(file, body, bodyLine)...)
+	return findings
+}
+
+// lintFrontMatter 按配置的规则检查 front-matter
+func (l *Linter) lintFrontMatter(file string, fm *FrontMatter) []Finding {
+	var findings []Finding
+	// front-matter 从文件第 2 行开始
+	line := func(key string) int {
+		if n := fm.Line(key); n > 0 {
+			return n + 1
+		}
+		return 1
+	}
+	add := func(key, rule, severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{File: file, Line: line(key), Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, key := range l.Rules.Required {
+		if !fm.Has(key) {
+			add(key, RuleRequired, SeverityError, "缺少必填字段 %s", key)
+		}
+	}
+
+	for _, key := range []string{"date", "updated"} {
+		if value := fm.String(key); value != "" && !parseDate(value, l.Rules.DateFormats) {
+			add(key, RuleDate, SeverityError, "%s 的格式不正确: %q（允许的格式：%s）", key, value, strings.Join(l.Rules.DateFormats, "、"))
+		}
+	}
+
+	if len(l.Rules.Categories) > 0 {
+		for _, category := range fm.Strings("categories") {
+			if !contains(l.Rules.Categories, category) {
+				add("categories", RuleCategory, SeverityError, "分类 %q 不在允许的列表中", category)
+			}
+		}
+	}
+
+	tags := fm.Strings("tags")
+	if len(tags) < l.Rules.MinTags {
+		add("tags", RuleTags, SeverityError, "至少需要 %d 个标签，当前 %d 个", l.Rules.MinTags, len(tags))
+	}
+	if l.Rules.MaxTags > 0 && len(tags) > l.Rules.MaxTags {
+		add("tags", RuleTags, SeverityError, "最多 %d 个标签，当前 %d 个", l.Rules.MaxTags, len(tags))
+	}
+
+	title, slug := fm.String("title"), slugOf(file, fm)
+	for _, other := range sortedKeys(l.index) {
+		if other == file {
+			continue
+		}
+		info := l.index[other]
+		if title != "" && strings.EqualFold(info.title, title) {
+			add("title", RuleDuplicateTitle, SeverityWarning, "标题与 %s 重复: %q", other, title)
+		}
+		if strings.EqualFold(info.slug, slug) {
+			add("slug", RuleDuplicateSlug, SeverityError, "slug 与 %s 重复: %q，生成时会互相覆盖", other, slug)
+		}
+	}
+	return findings
+}
+
+var (
+	// markdownLink 匹配 [文字](地址) 与 ![图片](地址 "标题")
+	markdownLink = regexp.MustCompile(`(!?)\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+["'][^"']*["'])?\s*\)`)
+	// htmlImage 匹配 <img src="地址">
+	htmlImage = regexp.MustCompile(`(?i)<img\s[^>]*src\s*=\s*["']([^"']+)["']`)
+	// postLink 匹配 Hexo 的 {% post_link slug %}
+	postLink = regexp.MustCompile(`\{%\s*post_link\s+("[^"]+"|\S+)`)
+	// assetImage 匹配 Hexo 的 {% asset_img 文件名 %}
+	assetImage = regexp.MustCompile(`\{%\s*asset_img\s+("[^"]+"|\S+)`)
+)
+
+// lintBody 检查正文中引用的本地图片与文章链接，代码块中的内容不检查
+func (l *Linter) lintBody(file string, body []byte, firstLine int) []Finding {
+	var findings []Finding
+	add := func(line int, rule, format string, args ...interface{}) {
+		severity := SeverityError
+		if rule == RuleMissingImage {
+			severity = SeverityWarning
+		}
+		findings = append(findings, Finding{File: file, Line: line, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	inCode := false
+	for i, raw := range bytes.Split(body, []byte("\n")) {
+		line := firstLine + i
+		text := string(raw)
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		text = stripInlineCode(text)
+
+		for _, m := range markdownLink.FindAllStringSubmatch(text, -1) {
+			target, ok := localTarget(m[2])
+			if !ok {
+				continue
+			}
+			if m[1] == "!" {
+				if !l.imageExists(file, target) {
+					add(line, RuleMissingImage, "图片不存在: %s", m[2])
+				}
+			} else if strings.EqualFold(filepath.Ext(target), ".md") && !l.postExists(file, target) {
+				add(line, RuleBrokenLink, "链接的文章不存在: %s", m[2])
+			}
+		}
+		for _, m := range htmlImage.FindAllStringSubmatch(text, -1) {
+			if target, ok := localTarget(m[1]); ok && !l.imageExists(file, target) {
+				add(line, RuleMissingImage, "图片不存在: %s", m[1])
+			}
+		}
+		for _, m := range postLink.FindAllStringSubmatch(text, -1) {
+			slug := strings.Trim(m[1], `"`)
+			if !l.slugExists(slug) {
+				add(line, RuleBrokenLink, "post_link 引用的文章不存在: %s", slug)
+			}
+		}
+		for _, m := range assetImage.FindAllStringSubmatch(text, -1) {
+			name := strings.Trim(m[1], `"`)
+			asset := filepath.Join(l.Dir, strings.TrimSuffix(filepath.FromSlash(file), filepath.Ext(file)), name)
+			if !exists(asset) {
+				add(line, RuleMissingImage, "asset_img 引用的图片不存在: %s", name)
+			}
+		}
+	}
+	return findings
+}
+
+// imageExists 依次在文章所在目录、文章的资源目录（post_asset_folder）与 Hexo 的 source 目录中查找图片
+func (l *Linter) imageExists(file, target string) bool {
+	if strings.HasPrefix(target, "/") {
+		return l.SourceRoot == "" || exists(filepath.Join(l.SourceRoot, filepath.FromSlash(target)))
+	}
+	dir := filepath.Join(l.Dir, filepath.Dir(filepath.FromSlash(file)))
+	assets := filepath.Join(l.Dir, strings.TrimSuffix(filepath.FromSlash(file), filepath.Ext(file)))
+	candidates := []string{filepath.Join(dir, target), filepath.Join(assets, target)}
+	if l.SourceRoot != "" {
+		candidates = append(candidates, filepath.Join(l.SourceRoot, target))
+	}
+	for _, candidate := range candidates {
+		if exists(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// postExists 判断相对链接指向的文章是否存在
+// 预处理会把文章平铺到输出目录中，因此同名文章存在也视为有效
+func (l *Linter) postExists(file, target string) bool {
+	if exists(filepath.Join(l.Dir, filepath.Dir(filepath.FromSlash(file)), filepath.FromSlash(target))) {
+		return true
+	}
+	name := filepath.Base(target)
+	for other := range l.index {
+		if filepath.Base(other) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// slugExists 判断是否存在指定 slug 的文章
+func (l *Linter) slugExists(slug string) bool {
+	slug = strings.TrimSuffix(slug, ".md")
+	for other, info := range l.index {
+		if strings.EqualFold(info.slug, slug) || strings.EqualFold(strings.TrimSuffix(other, filepath.Ext(other)), slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// localTarget 判断链接是否指向本地文件，并返回去掉锚点、查询参数并解码后的路径
+func localTarget(link string) (string, bool) {
+	lower := strings.ToLower(link)
+	for _, prefix := range []string{"http://", "https://", "//", "data:", "mailto:", "#", "tel:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return "", false
+		}
+	}
+	if i := strings.IndexAny(link, "#?"); i >= 0 {
+		link = link[:i]
+	}
+	if link == "" {
+		return "", false
+	}
+	if decoded, err := url.PathUnescape(link); err == nil {
+		link = decoded
+	}
+	return link, true
+}
+
+// slugOf 返回文章的 slug：front-matter 中的 slug，否则为不含扩展名的文件名
+func slugOf(file string, fm *FrontMatter) string {
+	if fm != nil {
+		if slug := fm.String("slug"); slug != "" {
+			return slug
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+// parseDate 判断日期是否符合任一格式
+func parseDate(value string, formats []string) bool {
+	for _, format := range formats {
+		if _, err := time.Parse(format, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stripInlineCode 去掉行内代码，避免把代码示例中的链接当作引用
+func stripInlineCode(text string) string {
+	for {
+		start := strings.Index(text, "`")
+		if start < 0 {
+			return text
+		}
+		end := strings.Index(text[start+1:], "`")
+		if end < 0 {
+			return text
+		}
+		text = text[:start] + text[start+1+end+1:]
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func sortedKeys(m map[string]postInfo) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,140 @@
+package posts
+
+import (
+	"Hexo-AutoCD/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// writePosts 在临时目录中写入文章，返回目录
+func writePosts(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// summarize 把问题转换为 文件:行号 规则 级别，便于比较
+func summarize(findings []Finding) []string {
+	got := make([]string, 0, len(findings))
+	for _, f := range findings {
+		got = append(got, f.File+":"+strconv.Itoa(f.Line)+" "+f.Rule+" "+f.Severity)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestLintFrontMatter(t *testing.T) {
+	dir := writePosts(t, map[string]string{
+		"ok.md":         "---\ntitle: 正常\ndate: 2024-01-02 10:00:00\ntags: [Go]\ncategories: [技术]\n---\n正文\n",
+		"no-front.md":   "只有正文\n",
+		"bad-yaml.md":   "---\ntitle: [未闭合\n---\n",
+		"missing.md":    "---\ntitle: 缺少日期\ntags: [Go]\n---\n",
+		"bad-date.md":   "---\ntitle: 日期\ndate: 2024/01/02\nupdated: 2024-01-03\ntags: [Go]\n---\n",
+		"tags.md":       "---\ntitle: 标签\ndate: 2024-01-02\ntags: [a, b, c, d]\ncategories: [生活]\n---\n",
+		"no-tags.md":    "---\ntitle: 没有标签\ndate: 2024-01-02\n---\n",
+		"dup/title.md":  "---\ntitle: 正常\ndate: 2024-01-02\ntags: [Go]\n---\n",
+		"dup/slug.md":   "---\ntitle: 别名\nslug: OK\ndate: 2024-01-02\ntags: [Go]\n---\n",
+		"other/ok.md":   "---\ntitle: 同名文件\ndate: 2024-01-02\ntags: [Go]\n---\n",
+		"unreported.md": "没有 front-matter，但不在检查列表中\n",
+	})
+	l := &Linter{Dir: dir, Rules: config.Lint{
+		Required:    []string{"title", "date"},
+		DateFormats: []string{"2006-01-02 15:04:05", "2006-01-02"},
+		Categories:  []string{"技术"},
+		MinTags:     1,
+		MaxTags:     3,
+		FailOn:      "error",
+	}}
+
+	findings, err := l.Lint([]string{"ok.md", "no-front.md", "bad-yaml.md", "missing.md", "bad-date.md", "tags.md", "no-tags.md", "dup/title.md", "dup/slug.md", "deleted.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"bad-date.md:3 date error",
+		"bad-yaml.md:2 front_matter error",
+		"dup/slug.md:3 duplicate_slug error", // slug OK 与 ok.md、other/ok.md 的文件名相同，不区分大小写
+		"dup/slug.md:3 duplicate_slug error",
+		"dup/title.md:2 duplicate_title warning",
+		"missing.md:1 required error",
+		"no-front.md:1 front_matter error",
+		"no-tags.md:1 tags error",
+		"ok.md:1 duplicate_slug error", // 没有 slug 字段时由文件名得出
+		"ok.md:1 duplicate_slug error",
+		"ok.md:2 duplicate_title warning",
+		"tags.md:4 tags error",
+		"tags.md:5 category error",
+	}
+	if got := summarize(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+	if !l.Failed(findings) {
+		t.Error("Failed() = false with errors")
+	}
+}
+
+func TestLintBody(t *testing.T) {
+	dir := writePosts(t, map[string]string{
+		"post.md": "---\ntitle: a\n---\n" +
+			"![存在](images/a.png) ![不存在](images/b.png)\n" +
+			"[相邻文章](other.md) [不存在的文章](missing.md#part) [外部](https://example.com/x.md)\n" +
+			"```\n![代码块中的图片](none.png)\n```\n" +
+			"`![行内代码](none.png)` <img src=\"gone.jpg\">\n" +
+			"{% post_link other %} {% post_link nothing %} {% asset_img pic.png %}\n",
+		"images/a.png": "",
+		"post/pic.png": "",
+		"other.md":     "---\ntitle: b\n---\n",
+	})
+	l := &Linter{Dir: dir, Rules: config.Lint{FailOn: "error"}}
+	findings, err := l.Lint([]string{"post.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"post.md:10 broken_link error",
+		"post.md:4 missing_image warning",
+		"post.md:5 broken_link error",
+		"post.md:9 missing_image warning",
+	}
+	if got := summarize(findings); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLintFailOn(t *testing.T) {
+	warning := []Finding{{Severity: SeverityWarning}}
+	errors := []Finding{{Severity: SeverityWarning}, {Severity: SeverityError}}
+	tests := []struct {
+		failOn          string
+		warning, errors bool
+	}{
+		{"error", false, true},
+		{"warning", true, true},
+		{"never", false, false},
+	}
+	for _, tt := range tests {
+		l := &Linter{Rules: config.Lint{FailOn: tt.failOn}}
+		if got := l.Failed(warning); got != tt.warning {
+			t.Errorf("fail_on %s: Failed(warnings) = %v", tt.failOn, got)
+		}
+		if got := l.Failed(errors); got != tt.errors {
+			t.Errorf("fail_on %s: Failed(errors) = %v", tt.failOn, got)
+		}
+		if l.Failed(nil) {
+			t.Errorf("fail_on %s: Failed(nil) = true", tt.failOn)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package posts
+
+import (
+	"Hexo-AutoCD/config"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Rules 定义如何根据文件名与目录生成 front-matter
+type Rules struct {
+	TagSeparator      string                 // 文件名中标题与标签的分隔符，如 "&"
+	CategoriesFromDir bool                   // 使用所在目录作为分类
+	DateFormat        string                 // date 字段的格式
+	Covers            []string               // 封面图片地址
+	Overwrite         []string               // 即使作者已填写也覆盖的字段
+	Defaults          map[string]interface{} // 缺失时补充的其他字段
+}
+
+// RulesFromConfig 根据配置生成预处理规则
+func RulesFromConfig(cfg config.Posts) Rules {
+	return Rules{
+		TagSeparator:      cfg.TagSeparator,
+		CategoriesFromDir: cfg.CategoriesFromDir,
+		DateFormat:        cfg.DateFormat,
+		Covers:            cfg.Covers,
+		Overwrite:         cfg.Overwrite,
+		Defaults:          cfg.Defaults,
+	}
+}
+
+// Derived 根据文件路径推导出的字段
+type Derived struct {
+	Title      string
+	Tags       []string
+	Categories []string
+	Cover      string
+}
+
+// Derive 根据相对于文章仓库的路径推导标题、标签、分类与封面
+// 例如 "技术/Go/并发 模式&Go&并发.md" 的标题为 "并发 模式"，标签为 Go、并发，分类为 技术、Go
+func (r Rules) Derive(relPath string) Derived {
+	relPath = filepath.ToSlash(relPath)
+	name := strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+
+	var d Derived
+	d.Title = name
+	if r.TagSeparator != "" {
+		parts := strings.Split(name, r.TagSeparator)
+		d.Title = strings.TrimSpace(parts[0])
+		for _, tag := range parts[1:] {
+			if tag = strings.TrimSpace(tag); tag != "" && !contains(d.Tags, tag) {
+				d.Tags = append(d.Tags, tag)
+			}
+		}
+	}
+
+	if r.CategoriesFromDir {
+		if dir := path.Dir(relPath); dir != "." {
+			for _, category := range strings.Split(dir, "/") {
+				if category = strings.TrimSpace(category); category != "" {
+					d.Categories = append(d.Categories, category)
+				}
+			}
+		}
+	}
+
+	// 按路径的哈希选取封面，同一篇文章每次处理得到相同的封面
+	if len(r.Covers) > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(relPath))
+		d.Cover = r.Covers[h.Sum32()%uint32(len(r.Covers))]
+	}
+	return d
+}
+
+// Process 为一篇文章补全 front-matter
+// 作者已填写的字段保持不变（除非列在 Overwrite 中），只补充缺失的字段；
+// date 缺失时优先使用 previousDate（例如输出目录中该文章已有的日期），否则使用 now
+func (r Rules) Process(relPath string, content []byte, previousDate string, now time.Time) ([]byte, error) {
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("文件不是 UTF-8 编码")
+	}
+
+	front, body, _, _ := Split(content)
+	fm, err := ParseFrontMatter(front)
+	if err != nil {
+		return nil, err
+	}
+
+	d := r.Derive(relPath)
+	date := previousDate
+	if date == "" {
+		date = now.Format(r.DateFormat)
+	}
+
+	fields := []struct {
+		key   string
+		value interface{}
+		empty bool
+	}{
+		{"title", d.Title, d.Title == ""},
+		{"date", date, false},
+		{"categories", d.Categories, len(d.Categories) == 0},
+		{"tags", d.Tags, len(d.Tags) == 0},
+		{"cover", d.Cover, d.Cover == ""},
+	}
+	for _, field := range fields {
+		if field.empty {
+			continue
+		}
+		if fm.Has(field.key) && !contains(r.Overwrite, field.key) {
+			continue
+		}
+		if s, ok := field.value.(string); ok {
+			fm.SetPlain(field.key, s)
+		} else if err := fm.Set(field.key, field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	// 其他默认字段按名称排序，保证输出稳定
+	keys := make([]string, 0, len(r.Defaults))
+	for key := range r.Defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if fm.Has(key) && !contains(r.Overwrite, key) {
+			continue
+		}
+		if err := fm.Set(key, r.Defaults[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	return fm.Render(body)
+}
+
+// Result 描述一个文件的处理结果
+type Result struct {
+	Source string // 源文件
+	Dest   string // 输出文件，为空表示没有输出
+	Action string // processed 或 removed
+	Error  error
+}
+
+// Preprocessor 处理文章仓库中的 Markdown 文件并写入 Hexo 的文章目录
+type Preprocessor struct {
+	SourceDir string // 文章仓库目录
+	DestDir   string // 输出目录，为空时只改写源文件
+	InPlace   bool   // 同时改写源文件
+	Rules     Rules
+	Now       func() time.Time
+}
+
+// NewPreprocessor 根据配置创建预处理器
+func NewPreprocessor(cfg config.Posts) *Preprocessor {
+	return &Preprocessor{
+		SourceDir: cfg.SourceDir,
+		DestDir:   cfg.DestDir,
+		InPlace:   cfg.InPlace,
+		Rules:     RulesFromConfig(cfg),
+		Now:       time.Now,
+	}
+}
+
+// IsPost 判断相对路径是否为需要处理的文章
+// 以 . 或 _ 开头的目录与文件（如 .github、_drafts）以及 README.md 不处理
+func IsPost(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if !strings.EqualFold(filepath.Ext(relPath), ".md") {
+		return false
+	}
+	for _, part := range strings.Split(relPath, "/") {
+		if strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_") {
+			return false
+		}
+	}
+	return !strings.EqualFold(path.Base(relPath), "README.md")
+}
+
+// Apply 处理推送中变更的文章
+// 文件仍然存在时补全 front-matter 并写入输出目录，已被删除时删除输出目录中对应的文章；
+// files 为相对于 SourceDir 的路径，不是文章的文件会被忽略
+// 输出目录不保留子目录，不同目录中的同名文章会输出到同一文件，这些文章处理失败，不覆盖彼此
+func (p *Preprocessor) Apply(files []string) []Result {
+	var owners map[string][]string
+	if p.DestDir != "" {
+		all, err := p.All()
+		if err != nil {
+			return []Result{{Source: p.SourceDir, Error: fmt.Errorf("无法列出文章仓库中的文章: %v", err)}}
+		}
+		owners = make(map[string][]string)
+		for _, file := range all {
+			owners[destName(file)] = append(owners[destName(file)], file)
+		}
+	}
+
+	var results []Result
+	done := make(map[string]bool)
+	for _, file := range files {
+		if !IsPost(file) || done[file] {
+			continue
+		}
+		done[file] = true
+		src := filepath.Join(p.SourceDir, filepath.FromSlash(file))
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			if p.DestDir == "" {
+				continue
+			}
+			dest := filepath.Join(p.DestDir, destName(file))
+			switch others := owners[destName(file)]; len(others) {
+			case 0:
+				result := Result{Source: file, Dest: dest, Action: "removed"}
+				if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+					result.Error = err
+				}
+				results = append(results, result)
+			case 1:
+				// 输出文件属于另一篇同名文章，重新生成而不是删除
+				if !done[others[0]] {
+					done[others[0]] = true
+					results = append(results, p.processFile(others[0], nil))
+				}
+			default:
+				results = append(results, Result{Source: file, Dest: dest, Action: "removed",
+					Error: fmt.Errorf("输出文件 %s 仍对应多篇同名文章: %s", destName(file), strings.Join(others, "、"))})
+			}
+			continue
+		}
+		var others []string
+		for _, other := range owners[destName(file)] {
+			if other != file {
+				others = append(others, other)
+			}
+		}
+		results = append(results, p.processFile(file, others))
+	}
+	return results
+}
+
+// destName 返回文章在输出目录中的文件名
+// 输出目录不保留文章仓库中的子目录，否则 Hexo 的 :title 会包含子目录，改变文章的链接
+func destName(rel string) string {
+	return path.Base(filepath.ToSlash(rel))
+}
+
+// Relative 把推送中相对于博客仓库的文件路径转换为相对于文章仓库 sourceDir 的路径
+// 文章仓库是博客仓库的子目录时，只保留其中的文件
+func Relative(sourceDir, repoDir string, changed []string) []string {
+	prefix := ""
+	if repoDir != "" {
+		if rel, err := filepath.Rel(repoDir, sourceDir); err == nil && rel != "." && filepath.IsLocal(rel) {
+			prefix = filepath.ToSlash(rel) + "/"
+		}
+	}
+
+	var files []string
+	for _, file := range changed {
+		if strings.HasPrefix(file, prefix) {
+			files = append(files, strings.TrimPrefix(file, prefix))
+		}
+	}
+	return files
+}
+
+// All 返回文章仓库中的所有文章，路径相对于 SourceDir
+func (p *Preprocessor) All() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(p.SourceDir, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(p.SourceDir, file)
+		if entry.IsDir() {
+			if rel != "." && (strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsPost(rel) {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// processFile 处理单个文件，others 为输出到同一文件的其他文章，不为空时不处理
+func (p *Preprocessor) processFile(rel string, others []string) Result {
+	src := filepath.Join(p.SourceDir, filepath.FromSlash(rel))
+	result := Result{Source: rel, Action: "processed"}
+	if len(others) > 0 {
+		result.Error = fmt.Errorf("与 %s 同名，会输出到同一文件 %s，请重命名其中之一", strings.Join(others, "、"), destName(rel))
+		return result
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	// 输出目录中已有的日期优先，避免每次修改文章都改变发布日期
+	var dest, previousDate string
+	if p.DestDir != "" {
+		dest = filepath.Join(p.DestDir, destName(rel))
+		if existing, err := os.ReadFile(dest); err == nil {
+			if front, _, _, ok := Split(existing); ok {
+				if fm, err := ParseFrontMatter(front); err == nil {
+					previousDate = fm.String("date")
+				}
+			}
+		}
+	}
+
+	output, err := p.Rules.Process(rel, content, previousDate, p.Now())
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if p.InPlace || p.DestDir == "" {
+		if err := writeFile(src, output); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+	if dest != "" {
+		if err := os.MkdirAll(p.DestDir, 0755); err != nil {
+			result.Error = err
+			return result
+		}
+		if err := writeFile(dest, output); err != nil {
+			result.Error = err
+			return result
+		}
+		result.Dest = dest
+	}
+	return result
+}
+
+// writeFile 先写入临时文件再重命名，避免 Hexo 读到写了一半的文件
+func writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func contains(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
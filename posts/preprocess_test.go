@@ -0,0 +1,180 @@
+package posts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name, content, front, body string
+		line                       int
+		ok                         bool
+	}{
+		{"front-matter", "---\ntitle: a\n---\nbody\n", "title: a\n", "body\n", 4, true},
+		{"bom and crlf", "\ufeff---\r\ntitle: a\r\n...\r\nbody", "title: a\r\n", "body", 4, true},
+		{"no front-matter", "# title\n---\n", "", "# title\n---\n", 1, false},
+		{"unterminated", "---\ntitle: a\n", "", "---\ntitle: a\n", 1, false},
+	}
+	for _, tt := range tests {
+		front, body, line, ok := Split([]byte(tt.content))
+		if string(front) != tt.front || string(body) != tt.body || line != tt.line || ok != tt.ok {
+			t.Errorf("%s: Split() = %q, %q, %d, %v", tt.name, front, body, line, ok)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	r := Rules{TagSeparator: "&", CategoriesFromDir: true, Covers: []string{"a.webp", "b.webp"}}
+	d := r.Derive("技术/Go/并发 模式&Go&并发&Go.md")
+	if d.Title != "并发 模式" || !reflect.DeepEqual(d.Tags, []string{"Go", "并发"}) || !reflect.DeepEqual(d.Categories, []string{"技术", "Go"}) {
+		t.Errorf("Derive() = %+v", d)
+	}
+	if again := r.Derive("技术/Go/并发 模式&Go&并发&Go.md"); again.Cover != d.Cover || d.Cover == "" {
+		t.Errorf("Cover = %q then %q, want a stable cover", d.Cover, again.Cover)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	r := Rules{
+		TagSeparator:      "&",
+		CategoriesFromDir: true,
+		DateFormat:        "2006-01-02 15:04:05",
+		Overwrite:         []string{"cover"},
+		Defaults:          map[string]interface{}{"ai": true},
+	}
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name, path, content, previous, want string
+	}{
+		{
+			name:    "new post",
+			path:    "技术/Go&Go.md",
+			content: "正文\n",
+			want:    "---\ntitle: Go\ndate: 2024-05-01 10:00:00\ncategories:\n  - 技术\ntags:\n  - Go\nai: true\n---\n\n正文\n",
+		},
+		{
+			name:     "keep author fields and comments",
+			path:     "笔记.md",
+			content:  "---\n# 作者的注释\ntitle: 自定义标题\nauthor: me\nai: false\n---\n正文\n",
+			previous: "2023-01-01 08:00:00",
+			want:     "---\n# 作者的注释\ntitle: 自定义标题\nauthor: me\nai: false\ndate: 2023-01-01 08:00:00\n---\n\n正文\n",
+		},
+		{
+			name:    "quote values read as other types",
+			path:    "2024.md",
+			content: "---\ndate: 2020-01-01\n---\n",
+			want:    "---\ndate: 2020-01-01\ntitle: \"2024\"\nai: true\n---\n",
+		},
+		{
+			name:    "quote booleans and null",
+			path:    "true&null&yes: no.md",
+			content: "",
+			want:    "---\ntitle: \"true\"\ndate: 2024-05-01 10:00:00\ntags:\n  - \"null\"\n  - 'yes: no'\nai: true\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := r.Process(tt.path, []byte(tt.content), tt.previous, now)
+		if err != nil {
+			t.Errorf("%s: Process() error = %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: Process() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := r.Process("a.md", []byte("---\n- a\n---\n"), "", now); err == nil {
+		t.Error("Process() with a list front-matter error = nil")
+	}
+	if _, err := r.Process("a.md", []byte("---\ntitle: [a\n---\n"), "", now); err == nil {
+		t.Error("Process() with invalid YAML error = nil")
+	}
+}
+
+// TestProcessRoundTrip 写出的 front-matter 再次解析后得到相同的字符串
+func TestProcessRoundTrip(t *testing.T) {
+	r := Rules{DateFormat: "2006-01-02"}
+	for _, title := range []string{"2024", "true", "null", "~", "yes: no", "0x10", "1e3", "a #b", "- x", "普通标题"} {
+		out, err := r.Process(title+".md", nil, "", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		front, _, _, _ := Split(out)
+		fm, err := ParseFrontMatter(front)
+		if err != nil {
+			t.Fatalf("%q: %v\n%s", title, err, out)
+		}
+		if node := fm.lookup("title"); node.Value != title || node.ShortTag() != "!!str" {
+			t.Errorf("title %q read back as %s %q:\n%s", title, node.ShortTag(), node.Value, out)
+		}
+		if node := fm.lookup("date"); node.ShortTag() != "!!timestamp" {
+			t.Errorf("date read back as %s, want an unquoted timestamp:\n%s", node.ShortTag(), out)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	write := func(dir, name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(source, "技术/Go.md", "go")
+	write(source, "a/笔记.md", "a")
+	write(source, "b/笔记.md", "b")
+	write(source, "_drafts/草稿.md", "draft")
+	write(dest, "已删除.md", "old")
+
+	p := &Preprocessor{
+		SourceDir: source,
+		DestDir:   dest,
+		Rules:     Rules{DateFormat: "2006-01-02"},
+		Now:       func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) },
+	}
+	results := p.Apply([]string{"技术/Go.md", "a/笔记.md", "b/笔记.md", "_drafts/草稿.md", "已删除.md", "图片.png"})
+	if len(results) != 4 {
+		t.Fatalf("len(results) = %d, want 4: %+v", len(results), results)
+	}
+	if r := results[0]; r.Error != nil || r.Dest != filepath.Join(dest, "Go.md") {
+		t.Errorf("技术/Go.md = %+v", r)
+	}
+	// 同名文章都不写入，不会互相覆盖
+	for _, r := range results[1:3] {
+		if r.Error == nil || !strings.Contains(r.Error.Error(), "同名") {
+			t.Errorf("%s error = %v, want a name collision", r.Source, r.Error)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "笔记.md")); !os.IsNotExist(err) {
+		t.Errorf("colliding post was written, stat err = %v", err)
+	}
+	if r := results[3]; r.Action != "removed" || r.Error != nil {
+		t.Errorf("已删除.md = %+v", r)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "已删除.md")); !os.IsNotExist(err) {
+		t.Errorf("deleted post still in dest, stat err = %v", err)
+	}
+
+	// 删除其中一篇后，输出文件属于剩下的一篇，重新生成而不是删除
+	if err := os.Remove(filepath.Join(source, "b/笔记.md")); err != nil {
+		t.Fatal(err)
+	}
+	results = p.Apply([]string{"b/笔记.md"})
+	if len(results) != 1 || results[0].Source != "a/笔记.md" || results[0].Error != nil {
+		t.Fatalf("results = %+v, want a/笔记.md processed", results)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "笔记.md"))
+	if err != nil || !strings.HasSuffix(string(data), "\na") {
+		t.Errorf("笔记.md = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,225 @@
+package preview
+
+import (
+	"Hexo-AutoCD/config"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// placeholder 预览地址模板中的预览名占位符
+const placeholder = "{name}"
+
+// marker 解析模板时替换占位符的标记，url.Parse 不接受 { 与控制字符出现在主机名中
+const marker = "hexoautocdpreviewname"
+
+// validName 预览名只包含小写字母、数字与 -，可以安全地用作目录名与子域名
+var validName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// pullRequestName 拉取请求的预览名，同名的分支不能直接使用
+var pullRequestName = regexp.MustCompile(`^pr-[0-9]+$`)
+
+// Name 把分支名转换为预览名
+// 分支名本身就是合法的预览名时原样使用；否则转换后追加分支名哈希的前 8 位，如 feature/New_Post → feature-new-post-1a2b3c4d，
+// 这样 feature/x 与 feature-x、分支 pr-42 与拉取请求 42 不会共用同一个预览。
+// 分支名中没有字母或数字时（如全部为中文）只使用哈希，如 branch-1a2b3c4d
+func Name(branch string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(branch) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimRight(b.String(), "-")
+	if name == branch && validName.MatchString(name) && !pullRequestName.MatchString(name) {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(branch))
+	hash := hex.EncodeToString(sum[:4])
+	if name == "" {
+		return "branch-" + hash
+	}
+	// 子域名的每一段最长 63 个字符，为 -<哈希> 留出 9 个字符
+	if len(name) > 63-9 {
+		name = strings.TrimRight(name[:63-9], "-")
+	}
+	return name + "-" + hash
+}
+
+// PullRequestName 返回拉取请求的预览名，如 pr-42
+func PullRequestName(number int) string {
+	return "pr-" + strconv.Itoa(number)
+}
+
+// URL 返回预览的访问地址
+func URL(template, name string) string {
+	return strings.ReplaceAll(template, placeholder, name)
+}
+
+// Root 返回预览地址的路径部分，用作 Hexo _config.yml 中的 root，如 /preview/feature-x/
+func Root(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return strings.TrimSuffix(u.Path, "/") + "/"
+}
+
+// Match 根据请求的 Host 与路径找出对应的预览
+// 模板的主机名中含有 {name} 时按子域名匹配，否则按路径前缀匹配；
+// 返回预览名与预览内的路径，路径前缀模式下访问 /preview/<name> 时返回的路径为空，应重定向到以 / 结尾的地址
+func Match(template, host, urlPath string) (string, string, bool) {
+	u, err := url.Parse(strings.ReplaceAll(template, placeholder, marker))
+	if err != nil {
+		return "", "", false
+	}
+
+	if strings.Contains(u.Host, marker) {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		pattern := u.Hostname()
+		prefix, suffix, _ := strings.Cut(pattern, marker)
+		host = strings.ToLower(host)
+		if !strings.HasPrefix(host, prefix) || !strings.HasSuffix(host, suffix) || len(host) <= len(prefix)+len(suffix) {
+			return "", "", false
+		}
+		name := host[len(prefix) : len(host)-len(suffix)]
+		if !validName.MatchString(name) {
+			return "", "", false
+		}
+		return name, urlPath, true
+	}
+
+	prefix, _, ok := strings.Cut(u.Path, marker)
+	if !ok || !strings.HasPrefix(urlPath, prefix) {
+		return "", "", false
+	}
+	name, rest, _ := strings.Cut(urlPath[len(prefix):], "/")
+	if !validName.MatchString(name) {
+		return "", "", false
+	}
+	if rest == "" && !strings.HasSuffix(urlPath, "/") {
+		return name, "", true
+	}
+	return name, "/" + rest, true
+}
+
+// locks 同一个预览的构建与删除依次进行
+var locks sync.Map
+
+// Lock 锁定一个预览，返回解锁函数
+func Lock(name string) func() {
+	value, _ := locks.LoadOrStore(name, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Manager 管理预览目录
+// 每个预览 <dir>/<name> 是指向 <dir>/.builds/ 中某次构建的符号链接，重新构建后原子切换
+type Manager struct {
+	Dir     string // 预览根目录
+	WorkDir string // 各预览分支的工作树目录
+}
+
+// New 根据配置创建预览管理器
+func New(cfg config.Preview) *Manager {
+	return &Manager{Dir: cfg.Dir, WorkDir: cfg.WorkDir}
+}
+
+// Path 返回预览的站点目录
+func (m *Manager) Path(name string) string {
+	return filepath.Join(m.Dir, name)
+}
+
+// Worktree 返回预览分支的工作树目录
+func (m *Manager) Worktree(name string) string {
+	return filepath.Join(m.WorkDir, name)
+}
+
+// Prepare 创建本次构建的空目录，步骤通过 PREVIEW_DIR 把生成的站点写入其中
+func (m *Manager) Prepare(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("预览名不合法: %q", name)
+	}
+	builds := filepath.Join(m.Dir, ".builds")
+	if err := os.MkdirAll(builds, 0755); err != nil {
+		return "", fmt.Errorf("创建预览目录失败: %v", err)
+	}
+	return os.MkdirTemp(builds, name+"-"+time.Now().Format("20060102-150405")+"-")
+}
+
+// Activate 把预览切换到新的构建，并删除之前的构建
+func (m *Manager) Activate(name, build string) error {
+	entries, err := os.ReadDir(build)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("预览目录为空，步骤应把生成的站点写入 PREVIEW_DIR")
+	}
+	os.Chmod(build, 0755)
+
+	old, _ := filepath.EvalSymlinks(m.Path(name))
+	target, err := filepath.Rel(m.Dir, build)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(m.Dir, fmt.Sprintf(".%s.%d", name, time.Now().UnixNano()))
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("创建符号链接失败: %v", err)
+	}
+	if err := os.Rename(tmp, m.Path(name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("切换预览失败: %v", err)
+	}
+	if old != "" && old != build {
+		os.RemoveAll(old)
+	}
+	return nil
+}
+
+// Remove 删除预览与其构建，预览不存在时返回 false
+func (m *Manager) Remove(name string) (bool, error) {
+	// 预览名为空或含有 .. 时 Path 会指向预览根目录或其之外
+	if !validName.MatchString(name) {
+		return false, fmt.Errorf("预览名不合法: %q", name)
+	}
+	link := m.Path(name)
+	build, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		if _, statErr := os.Lstat(link); os.IsNotExist(statErr) {
+			return false, nil
+		}
+	}
+	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+	if build != "" {
+		if err := os.RemoveAll(build); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Discard 删除未发布的构建目录
+func (m *Manager) Discard(build string) error {
+	return os.RemoveAll(build)
+}
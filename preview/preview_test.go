@@ -0,0 +1,72 @@
+package preview
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	// 合法的预览名原样使用
+	for _, branch := range []string{"main", "feature-x", "v2", "pr-42-fix"} {
+		if got := Name(branch); got != branch {
+			t.Errorf("Name(%q) = %q, want unchanged", branch, got)
+		}
+	}
+
+	// 需要转换的分支名追加哈希
+	tests := map[string]string{
+		"feature/New_Post":      "feature-new-post-",
+		"draft--2024":           "draft-2024-",
+		"-fix-":                 "fix-",
+		"文章/更新-v2":              "v2-",
+		"pr-42":                 "pr-42-",
+		strings.Repeat("a", 70): strings.Repeat("a", 54) + "-",
+	}
+	for branch, prefix := range tests {
+		got := Name(branch)
+		if !strings.HasPrefix(got, prefix) || len(got) != len(prefix)+8 || !validName.MatchString(got) {
+			t.Errorf("Name(%q) = %q, want %s<hash>", branch, got, prefix)
+		}
+	}
+
+	// 转换后相同的分支名、与拉取请求同名的分支不会共用预览
+	names := map[string]string{PullRequestName(42): "#42"}
+	for _, branch := range []string{"feature/x", "feature-x", "Feature-X", "feature_x", "pr-42"} {
+		name := Name(branch)
+		if other, ok := names[name]; ok {
+			t.Errorf("Name(%q) = %q, same as %s", branch, name, other)
+		}
+		names[name] = branch
+	}
+
+	// 没有字母与数字的分支名使用哈希，不同分支得到不同的预览名
+	a, b := Name("修复错别字"), Name("新文章")
+	if !validName.MatchString(a) || !strings.HasPrefix(a, "branch-") {
+		t.Errorf("Name(修复错别字) = %q, want a valid branch-<hash> name", a)
+	}
+	if a == b {
+		t.Errorf("Name() returned the same name %q for different branches", a)
+	}
+}
+
+func TestRemoveRejectsInvalidName(t *testing.T) {
+	dir := t.TempDir()
+	m := &Manager{Dir: filepath.Join(dir, "previews")}
+	if err := os.MkdirAll(filepath.Join(m.Dir, "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", ".", "..", "../previews", "a/b"} {
+		if _, err := m.Remove(name); err == nil {
+			t.Errorf("Remove(%q) error = nil", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(m.Dir, "keep")); err != nil {
+		t.Errorf("preview dir was modified: %v", err)
+	}
+
+	if existed, err := m.Remove("missing"); existed || err != nil {
+		t.Errorf("Remove(missing) = %v, %v", existed, err)
+	}
+}
@@ -0,0 +1,97 @@
+package publish
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// localTarget 本机目录
+type localTarget struct {
+	dir string
+}
+
+// List 遍历目标目录并计算每个文件的校验和
+func (t *localTarget) List() (map[string]Object, error) {
+	objects := make(map[string]Object)
+	err := filepath.WalkDir(t.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == t.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sum, err := fileMD5(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(t.dir, p)
+		objects[filepath.ToSlash(rel)] = Object{Size: info.Size(), MD5: sum}
+		return nil
+	})
+	return objects, err
+}
+
+// Put 先写入同一目录中的临时文件再重命名，Web 服务器不会读到写了一半的文件
+func (t *localTarget) Put(f File) error {
+	dest := filepath.Join(t.dir, filepath.FromSlash(f.Path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	src, err := os.Open(f.Local)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".hexo-autocd-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// Delete 删除文件，并删除因此变空的上级目录
+func (t *localTarget) Delete(name string) error {
+	dest := filepath.Join(t.dir, filepath.FromSlash(name))
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	root := filepath.Clean(t.dir)
+	for dir := filepath.Dir(dest); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Finish 本机目录不需要额外处理
+func (t *localTarget) Finish(files []File) error {
+	return nil
+}
+
+// Close 本机目录不需要关闭
+func (t *localTarget) Close() error {
+	return nil
+}
@@ -0,0 +1,302 @@
+package publish
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/glob"
+	"Hexo-AutoCD/logger"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// File 生成目录中的一个文件
+type File struct {
+	Path         string // 相对于生成目录的路径，以 / 分隔
+	Local        string // 本机路径
+	Size         int64
+	MD5          string // 内容的 MD5，十六进制
+	ContentType  string
+	CacheControl string
+}
+
+// Object 目标中已有的文件
+type Object struct {
+	Size int64
+	MD5  string // 为空表示无法得知内容，总会重新上传
+}
+
+// Target 同步目标
+type Target interface {
+	// List 返回目标中已有的文件，键为相对路径
+	List() (map[string]Object, error)
+	// Put 上传或覆盖一个文件
+	Put(f File) error
+	// Delete 删除一个文件
+	Delete(name string) error
+	// Finish 在全部上传与删除完成后调用，files 为生成目录中的全部文件
+	Finish(files []File) error
+	// Close 断开连接
+	Close() error
+}
+
+// Result 一个目标的同步结果
+type Result struct {
+	Target    string
+	Type      string
+	Uploaded  int   // 上传的文件数
+	Deleted   int   // 删除的文件数
+	Unchanged int   // 内容未变、跳过的文件数
+	Bytes     int64 // 上传的字节数
+	Error     string
+	Duration  time.Duration
+}
+
+// Syncer 把生成目录同步到 publish.targets 中的各个目标
+type Syncer struct {
+	cfg config.Publish
+}
+
+// New 根据配置创建同步器
+func New(cfg config.Publish) *Syncer {
+	return &Syncer{cfg: cfg}
+}
+
+// Sync 依次同步到每个目标，某个目标失败时继续同步其余目标，最后返回错误
+func (s *Syncer) Sync(source string) ([]Result, error) {
+	if len(s.cfg.Targets) == 0 {
+		return nil, fmt.Errorf("未配置 publish.targets")
+	}
+	files, err := s.Scan(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	var failed []string
+	for _, target := range s.cfg.Targets {
+		result := s.syncTarget(target, files)
+		if result.Error != "" {
+			failed = append(failed, target.Name)
+		}
+		results = append(results, result)
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("同步到 %s 失败", strings.Join(failed, "、"))
+	}
+	return results, nil
+}
+
+// syncTarget 同步到一个目标：先上传有变化的文件，HTML 最后上传，避免页面引用尚未上传的资源；再删除多余的文件
+func (s *Syncer) syncTarget(cfg config.PublishTarget, files []File) (result Result) {
+	result = Result{Target: cfg.Name, Type: cfg.Type}
+	targetLogger := logger.WithFields(logrus.Fields{
+		"目标": cfg.Name,
+		"类型": cfg.Type,
+	})
+	startTime := time.Now()
+	defer func() {
+		result.Duration = time.Since(startTime)
+	}()
+
+	fail := func(err error) Result {
+		result.Error = err.Error()
+		targetLogger.WithError(err).Error("同步失败")
+		return result
+	}
+
+	target, err := Open(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	defer target.Close()
+
+	existing, err := target.List()
+	if err != nil {
+		return fail(fmt.Errorf("列出目标中的文件失败: %v", err))
+	}
+
+	for _, f := range files {
+		if obj, ok := existing[f.Path]; ok && obj.MD5 == f.MD5 && obj.Size == f.Size {
+			result.Unchanged++
+			continue
+		}
+		if err := target.Put(f); err != nil {
+			return fail(fmt.Errorf("上传 %s 失败: %v", f.Path, err))
+		}
+		result.Uploaded++
+		result.Bytes += f.Size
+		targetLogger.WithField("文件", f.Path).Debug("已上传")
+	}
+
+	if !s.cfg.KeepStale {
+		wanted := make(map[string]bool, len(files))
+		for _, f := range files {
+			wanted[f.Path] = true
+		}
+		var stale []string
+		for name := range existing {
+			if !wanted[name] && !glob.MatchAny(s.cfg.Exclude, name) {
+				stale = append(stale, name)
+			}
+		}
+		sort.Strings(stale)
+		for _, name := range stale {
+			if err := target.Delete(name); err != nil {
+				return fail(fmt.Errorf("删除 %s 失败: %v", name, err))
+			}
+			result.Deleted++
+			targetLogger.WithField("文件", name).Debug("已删除")
+		}
+	}
+
+	if err := target.Finish(files); err != nil {
+		return fail(err)
+	}
+	targetLogger.WithFields(logrus.Fields{
+		"上传": result.Uploaded,
+		"删除": result.Deleted,
+		"未变": result.Unchanged,
+		"耗时": time.Since(startTime).String(),
+	}).Info("同步完成")
+	return result
+}
+
+// Scan 列出生成目录中需要同步的文件并计算校验和，HTML 文件排在最后
+func (s *Syncer) Scan(source string) ([]File, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("生成目录不可用: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("生成目录不是目录: %s", source)
+	}
+
+	var files []File
+	err = filepath.WalkDir(source, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(source, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() || rel == "." || glob.MatchAny(s.cfg.Exclude, rel) {
+			return nil
+		}
+		// 跟随符号链接，releases 中未变化的文件可能是指向上一版本的链接
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		sum, err := fileMD5(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{
+			Path:         rel,
+			Local:        p,
+			Size:         info.Size(),
+			MD5:          sum,
+			ContentType:  s.contentType(rel),
+			CacheControl: s.cacheControl(rel),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取生成目录失败: %v", err)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return !isHTML(files[i].Path) && isHTML(files[j].Path)
+	})
+	return files, nil
+}
+
+// contentTypes 标准库没有内置或系统中可能缺少的类型
+var contentTypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".xml":         "application/xml",
+	".txt":         "text/plain; charset=utf-8",
+	".md":          "text/markdown; charset=utf-8",
+	".svg":         "image/svg+xml",
+	".ico":         "image/x-icon",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+}
+
+// contentType 返回文件的 Content-Type，publish.content_types 优先
+func (s *Syncer) contentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := s.cfg.ContentTypes[ext]; ok {
+		return t
+	}
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// cacheControl 返回第一条匹配的 Cache-Control 规则
+func (s *Syncer) cacheControl(name string) string {
+	for _, rule := range s.cfg.CacheControl {
+		if glob.MatchAny(rule.Paths, name) {
+			return rule.Value
+		}
+	}
+	return ""
+}
+
+// Open 连接到同步目标
+func Open(cfg config.PublishTarget) (Target, error) {
+	switch cfg.Type {
+	case "local":
+		return &localTarget{dir: cfg.Path}, nil
+	case "sftp":
+		return openSFTP(cfg)
+	case "s3":
+		return newS3Target(cfg), nil
+	default:
+		return nil, fmt.Errorf("不支持的目标类型: %s", cfg.Type)
+	}
+}
+
+// fileMD5 计算文件内容的 MD5
+func fileMD5(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isHTML 判断是否为 HTML 页面
+func isHTML(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
@@ -0,0 +1,138 @@
+package publish
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// writeTree 在 dir 中按相对路径写入文件
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// syncOnce 同步到唯一的目标并返回结果
+func syncOnce(t *testing.T, cfg config.Publish, source string) Result {
+	t.Helper()
+	results, err := New(cfg).Sync(source)
+	if err != nil {
+		t.Fatalf("Sync() error = %v, results = %+v", err, results)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	return results[0]
+}
+
+func TestScanOrdersHTMLLast(t *testing.T) {
+	source := t.TempDir()
+	writeTree(t, source, map[string]string{
+		"index.html":      "<h1>home</h1>",
+		"css/style.css":   "body{}",
+		"posts/a.html":    "a",
+		"js/app.js":       "1",
+		"js/app.js.map":   "{}",
+		"images/logo.svg": "<svg/>",
+	})
+	files, err := New(config.Publish{Exclude: []string{"**/*.map"}}).Scan(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("len(files) = %d, want 5 (excluded *.map)", len(files))
+	}
+	seenHTML := false
+	for _, f := range files {
+		if isHTML(f.Path) {
+			seenHTML = true
+		} else if seenHTML {
+			t.Errorf("%s listed after an HTML file", f.Path)
+		}
+	}
+	for _, f := range files {
+		if f.Path == "css/style.css" && f.ContentType != "text/css; charset=utf-8" {
+			t.Errorf("ContentType(%s) = %q", f.Path, f.ContentType)
+		}
+	}
+}
+
+func TestLocalSync(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	writeTree(t, source, map[string]string{
+		"index.html":         "home",
+		"posts/a/index.html": "a",
+		"css/style.css":      "body{}",
+	})
+	writeTree(t, dest, map[string]string{
+		"old/stale.html": "stale",
+		"CNAME":          "blog.example.com",
+	})
+	cfg := config.Publish{
+		Exclude: []string{"CNAME"},
+		Targets: []config.PublishTarget{{Name: "www", Type: "local", Path: dest}},
+	}
+
+	got := syncOnce(t, cfg, source)
+	if got.Uploaded != 3 || got.Deleted != 1 || got.Unchanged != 0 {
+		t.Errorf("first sync = %+v, want 3 uploaded, 1 deleted", got)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "old")); !os.IsNotExist(err) {
+		t.Errorf("empty directory old/ should be removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "CNAME")); err != nil {
+		t.Errorf("excluded CNAME should be kept: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "posts/a/index.html"))
+	if err != nil || string(data) != "a" {
+		t.Errorf("posts/a/index.html = %q, %v", data, err)
+	}
+
+	writeTree(t, source, map[string]string{"css/style.css": "body{color:red}"})
+	got = syncOnce(t, cfg, source)
+	if got.Uploaded != 1 || got.Deleted != 0 || got.Unchanged != 2 {
+		t.Errorf("second sync = %+v, want 1 uploaded, 2 unchanged", got)
+	}
+
+	cfg.KeepStale = true
+	writeTree(t, dest, map[string]string{"extra.txt": "x"})
+	got = syncOnce(t, cfg, source)
+	if got.Deleted != 0 {
+		t.Errorf("keep_stale sync deleted %d files", got.Deleted)
+	}
+}
+
+func TestSyncContinuesAfterFailedTarget(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	writeTree(t, source, map[string]string{"index.html": "home"})
+	cfg := config.Publish{Targets: []config.PublishTarget{
+		{Name: "bad", Type: "ftp"},
+		{Name: "good", Type: "local", Path: dest},
+	}}
+	results, err := New(cfg).Sync(source)
+	if err == nil {
+		t.Fatal("Sync() error = nil, want failure for bad target")
+	}
+	if len(results) != 2 || results[0].Error == "" || results[1].Error != "" || results[1].Uploaded != 1 {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,274 @@
+package publish
+
+import (
+	"Hexo-AutoCD/config"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Target 同步到 S3 兼容的对象存储，如 AWS S3、MinIO、Cloudflare R2
+// 单次上传的对象的 ETag 即内容的 MD5，用于判断文件是否变化
+type s3Target struct {
+	cfg    config.PublishTarget
+	prefix string
+	client *http.Client
+	now    func() time.Time
+}
+
+// newS3Target 创建对象存储目标
+func newS3Target(cfg config.PublishTarget) *s3Target {
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Target{cfg: cfg, prefix: prefix, client: &http.Client{Timeout: 5 * time.Minute}, now: time.Now}
+}
+
+// listResult ListObjectsV2 的响应
+type listResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List 分页列出前缀下的所有对象
+func (t *s3Target) List() (map[string]Object, error) {
+	objects := make(map[string]Object)
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if t.prefix != "" {
+			query.Set("prefix", t.prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := t.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析对象列表失败: %v", err)
+		}
+		for _, obj := range result.Contents {
+			name := strings.TrimPrefix(obj.Key, t.prefix)
+			if name == "" || strings.HasSuffix(name, "/") {
+				continue
+			}
+			etag := strings.Trim(obj.ETag, `"`)
+			if strings.Contains(etag, "-") {
+				// 分片上传的 ETag 不是内容的 MD5
+				etag = ""
+			}
+			objects[name] = Object{Size: obj.Size, MD5: etag}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Put 上传对象并设置 Content-Type 与 Cache-Control，服务端会用 Content-MD5 校验内容
+func (t *s3Target) Put(f File) error {
+	data, err := os.ReadFile(f.Local)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	header := http.Header{}
+	header.Set("Content-Type", f.ContentType)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	if f.CacheControl != "" {
+		header.Set("Cache-Control", f.CacheControl)
+	}
+	resp, err := t.do(http.MethodPut, t.prefix+f.Path, nil, header, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete 删除对象
+func (t *s3Target) Delete(name string) error {
+	resp, err := t.do(http.MethodDelete, t.prefix+name, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Finish 对象存储不需要额外处理
+func (t *s3Target) Finish(files []File) error {
+	return nil
+}
+
+// Close 对象存储不需要关闭
+func (t *s3Target) Close() error {
+	return nil
+}
+
+// do 发送签名后的请求，状态码不是 2xx 时返回服务端给出的错误
+func (t *s3Target) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	endpoint, err := url.Parse(t.cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u := *endpoint
+	if t.cfg.PathStyle {
+		u.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + t.cfg.Bucket + "/" + key
+	} else {
+		u.Host = t.cfg.Bucket + "." + endpoint.Host
+		u.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	t.sign(req, body)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if xml.Unmarshal(data, &s3Err) == nil && s3Err.Code != "" {
+			return nil, fmt.Errorf("%s %s 返回 %d %s: %s", method, u.Path, resp.StatusCode, s3Err.Code, s3Err.Message)
+		}
+		return nil, fmt.Errorf("%s %s 返回 %d", method, u.Path, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// sign 按 AWS Signature Version 4 为请求签名
+func (t *s3Target) sign(req *http.Request, body []byte) {
+	now := t.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// 签名 Host 与所有 x-amz-*、Content-* 头
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lower := strings.ToLower(k)
+		if strings.HasPrefix(lower, "x-amz-") || strings.HasPrefix(lower, "content-") {
+			headers[lower] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + t.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+t.cfg.SecretKey), date)
+	key = hmacSHA256(key, t.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath 按 SigV4 的规则编码路径，/ 保留，其余非保留字符都编码
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery 按键排序并编码查询参数
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode 编码除 A-Z a-z 0-9 - _ . ~ 以外的所有字节
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,270 @@
+package publish
+
+import (
+	"Hexo-AutoCD/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// manifestName SFTP 目标中记录各文件校验和的清单，避免每次同步都读取远程文件
+const manifestName = ".hexo-autocd-manifest.json"
+
+// tmpSuffix 上传过程中的临时文件后缀
+const tmpSuffix = ".hexo-autocd-tmp"
+
+// sftpTarget 通过 SFTP 同步到远程目录
+// 远程文件的校验和记录在清单中，清单中没有或大小不一致的文件会重新上传
+type sftpTarget struct {
+	root   string
+	conn   *ssh.Client
+	client *sftp.Client
+	dirs   map[string]bool // 已确认存在的目录
+}
+
+// openSFTP 连接到远程主机并启动 sftp 子系统
+func openSFTP(cfg config.PublishTarget) (*sftpTarget, error) {
+	conn, err := dialSSH(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("启动 sftp 子系统失败: %v", err)
+	}
+	return &sftpTarget{root: path.Clean(cfg.Path), conn: conn, client: client, dirs: map[string]bool{}}, nil
+}
+
+// dialSSH 按配置认证并校验主机密钥
+func dialSSH(cfg config.PublishTarget) (*ssh.Client, error) {
+	var auth []ssh.AuthMethod
+	if cfg.IdentityFile != "" {
+		key, err := os.ReadFile(cfg.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Agent {
+		sock, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+		if err != nil {
+			return nil, fmt.Errorf("连接 ssh-agent 失败: %v", err)
+		}
+		defer sock.Close()
+		// 签名只在握手时使用，握手完成后即可断开 ssh-agent
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(sock).Signers))
+	}
+	hostKey, err := knownhosts.New(cfg.KnownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("读取 known_hosts 失败: %v", err)
+	}
+
+	address := cfg.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+	conn, err := ssh.Dial("tcp", address, &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         30 * time.Second,
+	})
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) {
+		if len(keyErr.Want) == 0 {
+			return nil, fmt.Errorf("%s 不在 known_hosts 中（可用 ssh-keyscan 添加）", address)
+		}
+		return nil, fmt.Errorf("%s 的主机密钥与 known_hosts 不一致，可能遭到中间人攻击", address)
+	} else if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %v", address, err)
+	}
+	return conn, nil
+}
+
+// manifestEntry 清单中的一个文件
+type manifestEntry struct {
+	Size int64  `json:"size"`
+	MD5  string `json:"md5"`
+}
+
+// List 遍历远程目录，并用清单中的校验和补全文件信息
+func (t *sftpTarget) List() (map[string]Object, error) {
+	manifest := make(map[string]manifestEntry)
+	if data, err := t.readFile(path.Join(t.root, manifestName)); err == nil {
+		json.Unmarshal(data, &manifest)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	objects := make(map[string]Object)
+	var walk func(dir, rel string) error
+	walk = func(dir, rel string) error {
+		entries, err := t.client.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) && dir == t.root {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		t.dirs[dir] = true
+		for _, entry := range entries {
+			name := path.Join(rel, entry.Name())
+			switch {
+			case entry.IsDir():
+				if err := walk(path.Join(dir, entry.Name()), name); err != nil {
+					return err
+				}
+			case !entry.Mode().IsRegular() || name == manifestName:
+			case strings.HasSuffix(name, tmpSuffix):
+				// 上次同步中断时遗留的临时文件
+				t.client.Remove(path.Join(dir, entry.Name()))
+			default:
+				obj := Object{Size: entry.Size()}
+				if m, ok := manifest[name]; ok && m.Size == entry.Size() {
+					obj.MD5 = m.MD5
+				}
+				objects[name] = obj
+			}
+		}
+		return nil
+	}
+	return objects, walk(t.root, "")
+}
+
+// Put 上传到临时文件后重命名
+func (t *sftpTarget) Put(f File) error {
+	src, err := os.Open(f.Local)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return t.write(path.Join(t.root, f.Path), src)
+}
+
+// write 写入远程文件，先写临时文件再替换，Web 服务器不会读到写了一半的文件
+func (t *sftpTarget) write(dest string, r io.Reader) error {
+	if err := t.mkdirAll(path.Dir(dest)); err != nil {
+		return err
+	}
+	tmp := dest + tmpSuffix
+	if err := t.writeFile(tmp, r); err != nil {
+		t.client.Remove(tmp)
+		return err
+	}
+	if err := t.rename(tmp, dest); err != nil {
+		t.client.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeFile 创建或截断远程文件并写入 r 中的全部内容
+func (t *sftpTarget) writeFile(name string, r io.Reader) error {
+	f, err := t.client.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := f.ReadFrom(r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readFile 读取整个远程文件
+func (t *sftpTarget) readFile(name string) ([]byte, error) {
+	f, err := t.client.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// rename 重命名，目标已存在时覆盖
+func (t *sftpTarget) rename(from, to string) error {
+	if _, ok := t.client.HasExtension("posix-rename@openssh.com"); ok {
+		return t.client.PosixRename(from, to)
+	}
+	// 第 3 版的 RENAME 不能覆盖已有文件
+	if err := t.client.Remove(to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return t.client.Rename(from, to)
+}
+
+// mkdirAll 逐级创建远程目录
+func (t *sftpTarget) mkdirAll(dir string) error {
+	if t.dirs[dir] || dir == "/" || dir == "." {
+		return nil
+	}
+	if err := t.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := t.client.Mkdir(dir); err != nil {
+		// 目录可能已存在，以 stat 的结果为准
+		if info, statErr := t.client.Stat(dir); statErr != nil || !info.IsDir() {
+			return err
+		}
+	}
+	t.dirs[dir] = true
+	return nil
+}
+
+// Delete 删除远程文件，并删除因此变空的上级目录
+func (t *sftpTarget) Delete(name string) error {
+	dest := path.Join(t.root, name)
+	if err := t.client.Remove(dest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := path.Dir(dest); dir != t.root && strings.HasPrefix(dir, t.root+"/"); dir = path.Dir(dir) {
+		if t.client.RemoveDirectory(dir) != nil {
+			break
+		}
+		delete(t.dirs, dir)
+	}
+	return nil
+}
+
+// Finish 写入新的清单
+func (t *sftpTarget) Finish(files []File) error {
+	manifest := make(map[string]manifestEntry, len(files))
+	for _, f := range files {
+		manifest[f.Path] = manifestEntry{Size: f.Size, MD5: f.MD5}
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := t.write(path.Join(t.root, manifestName), strings.NewReader(string(data))); err != nil {
+		return fmt.Errorf("写入清单失败: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭 sftp 会话与连接
+func (t *sftpTarget) Close() error {
+	t.client.Close()
+	return t.conn.Close()
+}
@@ -0,0 +1,177 @@
+package publish
+
+import (
+	"Hexo-AutoCD/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSFTPServer 在本机启动只接受 client 公钥的 SSH 服务器，提供 sftp 子系统
+// 返回服务器地址与写好的 known_hosts 文件
+func startSFTPServer(t *testing.T, client ssh.PublicKey) (string, string) {
+	t.Helper()
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(client.Marshal()) {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	serverConfig.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, serverConfig)
+		}
+	}()
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(listener.Addr().String())}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHosts, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return listener.Addr().String(), knownHosts
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server, err := sftp.NewServer(channel)
+					if err == nil {
+						server.Serve()
+					}
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestSFTPSync(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(identity, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, knownHosts := startSFTPServer(t, signer.PublicKey())
+
+	source, dest := t.TempDir(), t.TempDir()
+	writeTree(t, source, map[string]string{
+		"index.html":         "home",
+		"posts/a/index.html": "a",
+		"css/style.css":      "body{}",
+	})
+	writeTree(t, dest, map[string]string{
+		"old/stale.html":            "stale",
+		"index.html" + tmpSuffix:    "interrupted",
+		"posts/a/index.html":        "a",
+		"posts/a/index.html.backup": "b",
+	})
+	cfg := config.Publish{Targets: []config.PublishTarget{{
+		Name:         "mirror",
+		Type:         "sftp",
+		Address:      address,
+		User:         "deploy",
+		IdentityFile: identity,
+		KnownHosts:   knownHosts,
+		Path:         dest,
+	}}}
+
+	// 没有清单时无法得知远程文件的内容，全部上传
+	got := syncOnce(t, cfg, source)
+	if got.Uploaded != 3 || got.Deleted != 2 {
+		t.Errorf("first sync = %+v, want 3 uploaded, 2 deleted", got)
+	}
+	for name, want := range map[string]string{"index.html": "home", "posts/a/index.html": "a", "css/style.css": "body{}"} {
+		data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	for _, name := range []string{"old", "index.html" + tmpSuffix} {
+		if _, err := os.Stat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, stat err = %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, manifestName)); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+
+	// 有清单后只上传变化的文件，清单本身不会被当作多余的文件删除
+	writeTree(t, source, map[string]string{"css/style.css": "body{color:red}"})
+	got = syncOnce(t, cfg, source)
+	if got.Uploaded != 1 || got.Unchanged != 2 || got.Deleted != 0 {
+		t.Errorf("second sync = %+v, want 1 uploaded, 2 unchanged", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "css/style.css")); string(data) != "body{color:red}" {
+		t.Errorf("css/style.css = %q", data)
+	}
+}
+
+func TestSFTPUnknownHost(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(key)
+	address, _ := startSFTPServer(t, signer.PublicKey())
+	empty := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(empty, nil, 0600)
+
+	_, err := Open(config.PublishTarget{Type: "sftp", Address: address, User: "deploy", KnownHosts: empty})
+	if err == nil {
+		t.Fatal("Open() error = nil, want unknown host error")
+	}
+}
@@ -0,0 +1,64 @@
+package purge
+
+import (
+	"Hexo-AutoCD/config"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// cloudflare 调用 Cloudflare 的 purge_cache 接口按 URL 刷新
+type cloudflare struct {
+	cfg    config.PurgeBackend
+	client *http.Client
+}
+
+// cloudflareResponse Cloudflare API 的响应
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result struct {
+		ID string `json:"id"`
+	} `json:"result"`
+}
+
+func (b *cloudflare) purge(urls []string) (string, error) {
+	body, err := json.Marshal(map[string][]string{"files": urls})
+	if err != nil {
+		return "", err
+	}
+	endpoint := strings.TrimSuffix(b.cfg.BaseURL, "/") + "/zones/" + url.PathEscape(b.cfg.ZoneID) + "/purge_cache"
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	var result cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("%s，无法解析响应: %v", resp.Status, err)
+	}
+	if !result.Success {
+		messages := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			messages = append(messages, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		if len(messages) == 0 {
+			messages = append(messages, resp.Status)
+		}
+		return "", fmt.Errorf("%s", strings.Join(messages, "；"))
+	}
+	return "任务 " + result.Result.ID, nil
+}
@@ -0,0 +1,66 @@
+package purge
+
+import (
+	"Hexo-AutoCD/config"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// httpPurge 向缓存服务器发送 PURGE 等请求，适用于 Varnish、Nginx proxy_cache_purge 等
+// 设置了 base_url 时请求发往缓存服务器，Host 为站点的域名；否则直接请求页面地址
+type httpPurge struct {
+	cfg    config.PurgeBackend
+	client *http.Client
+}
+
+func (b *httpPurge) purge(urls []string) (string, error) {
+	var detail string
+	for _, u := range urls {
+		target, err := url.Parse(u)
+		if err != nil {
+			return "", err
+		}
+		host := target.Host
+		if b.cfg.BaseURL != "" {
+			base, err := url.Parse(b.cfg.BaseURL)
+			if err != nil {
+				return "", err
+			}
+			// 保留页面路径原有的编码
+			prefix := strings.TrimSuffix(base.EscapedPath(), "/")
+			base.Path = strings.TrimSuffix(base.Path, "/") + target.Path
+			base.RawPath = prefix + target.EscapedPath()
+			base.RawQuery = target.RawQuery
+			target = base
+		}
+
+		req, err := http.NewRequest(b.cfg.Method, target.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Host = host
+		for k, v := range b.cfg.Headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("请求失败: %v", err)
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			// proxy_cache_purge 在页面未被缓存时返回 404
+			detail = "404，未缓存"
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			detail = fmt.Sprintf("%d", resp.StatusCode)
+		default:
+			err = fmt.Errorf("%s %s 返回 %s", b.cfg.Method, target.Path, readError(resp))
+		}
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return detail, nil
+}
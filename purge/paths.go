@@ -0,0 +1,158 @@
+package purge
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/posts"
+	"Hexo-AutoCD/smoke"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Post 一篇变更的文章在推送前后的内容
+// 新增的文章 Before 为 nil，删除的文章 After 为 nil
+type Post struct {
+	Name   string // 相对于文章目录的路径
+	Before []byte
+	After  []byte
+}
+
+// Paths 计算需要刷新的页面路径
+// 包括 purge.paths 中的固定页面，以及每篇文章推送前后的文章页、所在年月的归档页、标签页与分类页；
+// 文章的日期、标签或 permalink 改变时新旧页面都会刷新。无法计算的文章记录在返回的 warnings 中
+// publicDir 为本次生成的站点目录，其中存在的分页（如 /tags/Go/page/2/）随所属页面一起刷新，为空时不刷新分页
+func Paths(cfg config.Purge, dateFormats []string, publicDir string, changed []Post) (paths []string, warnings []string) {
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		paths = append(paths, p)
+		// 文章增删后列表中每一页的内容都会后移，所有分页都需要刷新
+		for _, page := range pagination(publicDir, cfg.PaginationDir, p) {
+			seen[page] = true
+			paths = append(paths, page)
+		}
+	}
+	for _, p := range cfg.Paths {
+		add(p)
+	}
+
+	for _, post := range changed {
+		for _, content := range [][]byte{post.Before, post.After} {
+			if content == nil {
+				continue
+			}
+			postPaths, err := pagesOf(cfg, dateFormats, post.Name, content)
+			for _, p := range postPaths {
+				add(p)
+			}
+			if err != nil {
+				warnings = append(warnings, err.Error())
+			}
+		}
+	}
+	return paths, warnings
+}
+
+// pagesOf 返回一个版本的文章所在的页面，无法计算文章地址时仍返回标签与分类页
+func pagesOf(cfg config.Purge, dateFormats []string, name string, content []byte) ([]string, error) {
+	fm, _ := posts.ParseFrontMatter(nil)
+	if front, _, _, ok := posts.Split(content); ok {
+		var err error
+		if fm, err = posts.ParseFrontMatter(front); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	var pages []string
+	if tags := fm.Strings("tags"); len(tags) > 0 {
+		dir := "/" + strings.Trim(cfg.TagDir, "/") + "/"
+		pages = append(pages, dir)
+		for _, tag := range tags {
+			if slug := slugize(tag); slug != "" {
+				pages = append(pages, dir+slug+"/")
+			}
+		}
+	}
+	// Hexo 的分类是层级的，[前端, Vue] 对应 /categories/前端/ 与 /categories/前端/Vue/
+	if categories := fm.Strings("categories"); len(categories) > 0 {
+		dir := "/" + strings.Trim(cfg.CategoryDir, "/") + "/"
+		pages = append(pages, dir)
+		for _, category := range categories {
+			if slug := slugize(category); slug != "" {
+				dir += slug + "/"
+				pages = append(pages, dir)
+			}
+		}
+	}
+	if date, ok := parseDate(fm.String("date"), dateFormats); ok {
+		dir := "/" + strings.Trim(cfg.ArchiveDir, "/") + "/"
+		pages = append(pages, dir+date.Format("2006")+"/", dir+date.Format("2006/01")+"/")
+	}
+
+	link, err := smoke.Permalink(cfg.Permalink, name, fm, dateFormats)
+	if err != nil {
+		return pages, err
+	}
+	return append([]string{link}, pages...), nil
+}
+
+// pagination 返回生成目录中 dir 的第 2 页及之后的分页，按页码排序
+// 页数只能从本次生成的结果中得到：文章删除后页数减少时，已经不存在的最后一页不会被刷新
+func pagination(publicDir, pageDir, dir string) []string {
+	if publicDir == "" || !strings.HasSuffix(dir, "/") {
+		return nil
+	}
+	base := dir + strings.Trim(pageDir, "/") + "/"
+	entries, err := os.ReadDir(filepath.Join(publicDir, filepath.FromSlash(base)))
+	if err != nil {
+		return nil
+	}
+	var numbers []int
+	for _, entry := range entries {
+		if n, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() && n > 1 {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	pages := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		pages = append(pages, base+strconv.Itoa(n)+"/")
+	}
+	return pages
+}
+
+// parseDate 按允许的格式解析文章日期
+func parseDate(value string, formats []string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, format := range formats {
+		if date, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rSpecial Hexo 生成标签与分类地址时替换为 - 的字符
+var rSpecial = regexp.MustCompile("[\\s~`!@#$%^&*()\\-_+=\\[\\]{}|\\\\;:\"'<>,.?/]+")
+
+// slugize 与 hexo-util 的 slugize 相同：特殊字符与空白替换为 -，去掉首尾的 -，不改变大小写
+// 不处理变音符号，如 é 不会转换为 e
+func slugize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Trim(rSpecial.ReplaceAllString(s, "-"), "-")
+}
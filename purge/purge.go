@@ -0,0 +1,138 @@
+package purge
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Result 在一个刷新接口中刷新一个 URL 的结果
+type Result struct {
+	Backend string
+	URL     string
+	OK      bool
+	Detail  string // 成功时为任务ID或状态码，失败时为失败原因
+}
+
+func (r Result) String() string {
+	mark := "✓"
+	if !r.OK {
+		mark = "✗"
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", mark, r.Backend, r.URL, r.Detail)
+}
+
+// backend 刷新接口，一次刷新不超过 batch_size 个 URL，成功时返回任务ID等信息
+type backend interface {
+	purge(urls []string) (string, error)
+}
+
+// Purger 调用 purge.backends 中的各个刷新接口
+type Purger struct {
+	cfg    config.Purge
+	client *http.Client
+}
+
+// New 根据配置创建刷新器
+func New(cfg config.Purge) *Purger {
+	timeout, _ := time.ParseDuration(cfg.Timeout)
+	return &Purger{cfg: cfg, client: &http.Client{Timeout: timeout}}
+}
+
+// Purge 在每个刷新接口中刷新 paths 对应的 URL，某个接口失败时继续调用其余接口
+func (p *Purger) Purge(paths []string) []Result {
+	urls, err := p.URLs(paths)
+	if err != nil {
+		return []Result{{Backend: "-", URL: p.cfg.SiteURL, Detail: err.Error()}}
+	}
+
+	var results []Result
+	for _, cfg := range p.cfg.Backends {
+		b := p.backend(cfg)
+		size := cfg.BatchSize
+		if cfg.Type == "http" || size <= 0 {
+			size = 1
+		}
+		backendLogger := logger.WithFields(logrus.Fields{
+			"后端": cfg.Name,
+			"类型": cfg.Type,
+		})
+		for start := 0; start < len(urls); start += size {
+			batch := urls[start:min(start+size, len(urls))]
+			detail, err := b.purge(batch)
+			for _, u := range batch {
+				result := Result{Backend: cfg.Name, URL: u, OK: err == nil, Detail: detail}
+				if err != nil {
+					result.Detail = err.Error()
+					backendLogger.WithField("URL", u).WithError(err).Error("刷新缓存失败")
+				} else {
+					backendLogger.WithFields(logrus.Fields{
+						"URL": u,
+						"结果":  detail,
+					}).Info("已刷新缓存")
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+// URLs 把以 / 开头的路径转换为以 site_url 开头的完整地址，路径中的中文与空格按 URL 编码
+func (p *Purger) URLs(paths []string) ([]string, error) {
+	base, err := url.Parse(strings.TrimSuffix(p.cfg.SiteURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("purge.site_url 不合法: %v", err)
+	}
+	urls := make([]string, 0, len(paths))
+	for _, p := range paths {
+		target := base.JoinPath(strings.Split(strings.TrimPrefix(p, "/"), "/")...)
+		if strings.HasSuffix(p, "/") && !strings.HasSuffix(target.Path, "/") {
+			target.Path += "/"
+		}
+		urls = append(urls, target.String())
+	}
+	return urls, nil
+}
+
+// backend 按类型创建刷新接口
+func (p *Purger) backend(cfg config.PurgeBackend) backend {
+	switch cfg.Type {
+	case "cloudflare":
+		return &cloudflare{cfg: cfg, client: p.client}
+	case "tencent":
+		return &tencent{cfg: cfg, client: p.client, now: time.Now}
+	case "aliyun":
+		return &aliyun{cfg: cfg, client: p.client, now: time.Now}
+	default:
+		return &httpPurge{cfg: cfg, client: p.client}
+	}
+}
+
+// Failed 返回失败的结果
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if !r.OK {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// readError 读取错误响应的开头部分，用于错误信息
+func readError(resp *http.Response) string {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return resp.Status
+	}
+	return fmt.Sprintf("%s: %s", resp.Status, text)
+}
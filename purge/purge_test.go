@@ -0,0 +1,243 @@
+package purge
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func testConfig() config.Purge {
+	return config.Purge{
+		SiteURL:       "https://blog.example.com",
+		Permalink:     ":year/:month/:day/:title/",
+		Paths:         []string{"/", "/archives/"},
+		ArchiveDir:    "archives",
+		TagDir:        "tags",
+		CategoryDir:   "categories",
+		PaginationDir: "page",
+	}
+}
+
+func TestPaths(t *testing.T) {
+	before := "---\ntitle: 你好\ndate: 2024-01-02 10:00:00\ntags: [Go]\n---\n"
+	after := "---\ntitle: 你好\ndate: 2024-02-03 10:00:00\ntags: [Go, 并发 模式]\ncategories: [技术, Go]\n---\n"
+	changed := []Post{
+		{Name: "hello.md", Before: []byte(before), After: []byte(after)},
+		{Name: "broken.md", After: []byte("---\ntitle: x\n---\n")},
+	}
+	paths, warnings := Paths(testConfig(), []string{"2006-01-02 15:04:05"}, "", changed)
+	want := []string{
+		"/", "/archives/",
+		"/2024/01/02/hello/", "/tags/", "/tags/Go/", "/archives/2024/", "/archives/2024/01/",
+		"/2024/02/03/hello/", "/tags/并发-模式/", "/categories/", "/categories/技术/", "/categories/技术/Go/", "/archives/2024/02/",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Paths() =\n%q\nwant\n%q", paths, want)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "broken.md") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestPathsPagination(t *testing.T) {
+	public := t.TempDir()
+	for _, dir := range []string{"page/2", "page/3", "page/10", "page/latest", "tags/Go/page/2", "archives/2024/01"} {
+		if err := os.MkdirAll(filepath.Join(public, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 不是目录的同名文件不是分页
+	os.WriteFile(filepath.Join(public, "page", "4"), nil, 0644)
+
+	post := "---\ndate: 2024-01-02 10:00:00\ntags: [Go]\n---\n"
+	paths, _ := Paths(testConfig(), []string{"2006-01-02 15:04:05"}, public, []Post{{Name: "hello.md", After: []byte(post)}})
+	want := []string{
+		"/", "/page/2/", "/page/3/", "/page/10/", "/archives/",
+		"/2024/01/02/hello/", "/tags/", "/tags/Go/", "/tags/Go/page/2/", "/archives/2024/", "/archives/2024/01/",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Paths() =\n%q\nwant\n%q", paths, want)
+	}
+}
+
+func TestURLs(t *testing.T) {
+	urls, err := New(config.Purge{SiteURL: "https://blog.example.com/"}).URLs([]string{"/", "/tags/并发 模式/", "/atom.xml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"https://blog.example.com/",
+		"https://blog.example.com/tags/%E5%B9%B6%E5%8F%91%20%E6%A8%A1%E5%BC%8F/",
+		"https://blog.example.com/atom.xml",
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("URLs() = %q, want %q", urls, want)
+	}
+}
+
+// 示例凭据与时间取自腾讯云 API 3.0 签名方法 v3 的文档，服务改为 cdn；期望值按文档中的步骤独立计算
+func TestTencentSignKnownAnswer(t *testing.T) {
+	b := &tencent{
+		cfg: config.PurgeBackend{SecretID: "AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE", SecretKey: "Gu5t9xGARNpq86cd98joQYCN3EXAMPLE"},
+		now: func() time.Time { return time.Unix(1551113065, 0) },
+	}
+	body := []byte(`{"Urls":["https://blog.example.com/","https://blog.example.com/tags/Go/"]}`)
+	req, err := http.NewRequest(http.MethodPost, "https://cdn.tencentcloudapi.com", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	b.sign(req, body)
+
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE/2019-02-25/cdn/tc3_request, " +
+		"SignedHeaders=content-type;host, " +
+		"Signature=fa048919c5263187927304abeedace293bab795c4a48bf549761adfa71ab47cb"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if got := req.Header.Get("X-TC-Timestamp"); got != "1551113065" {
+		t.Errorf("X-TC-Timestamp = %s", got)
+	}
+}
+
+// 示例参数与签名取自阿里云 RPC 风格签名机制的文档
+func TestAliyunSignKnownAnswer(t *testing.T) {
+	b := &aliyun{cfg: config.PurgeBackend{SecretKey: "testsecret"}}
+	params := url.Values{
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Format":           {"XML"},
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"Version":          {"2014-05-26"},
+		"SignatureVersion": {"1.0"},
+	}
+	if got := b.sign(http.MethodGet, params); got != "OLeaidS1JvxuMvnyHOwuJ+uX5qY=" {
+		t.Errorf("sign() = %s, want OLeaidS1JvxuMvnyHOwuJ+uX5qY=", got)
+	}
+	if got := percentEncode("a b*c~d/文"); got != "a%20b%2Ac~d%2F%E6%96%87" {
+		t.Errorf("percentEncode() = %s", got)
+	}
+}
+
+func TestBackends(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(data))
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/zones/"):
+			fmt.Fprint(w, `{"success":true,"result":{"id":"cf-1"}}`)
+		case r.Header.Get("X-TC-Action") != "":
+			fmt.Fprint(w, `{"Response":{"TaskId":"tc-1","RequestId":"r1"}}`)
+		case r.URL.Path == "/aliyun":
+			fmt.Fprint(w, `{"RefreshTaskId":"ali-1","RequestId":"r2"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.Backends = []config.PurgeBackend{
+		{Name: "cf", Type: "cloudflare", BaseURL: server.URL, ZoneID: "zone", Token: "cf-token", BatchSize: 30},
+		{Name: "qcloud", Type: "tencent", BaseURL: server.URL, SecretID: "id", SecretKey: "key", BatchSize: 100},
+		{Name: "alicdn", Type: "aliyun", BaseURL: server.URL + "/aliyun", SecretID: "id", SecretKey: "key", BatchSize: 1},
+	}
+	results := New(cfg).Purge([]string{"/", "/tags/Go/"})
+	if failed := Failed(results); len(failed) != 0 || len(results) != 6 {
+		t.Fatalf("results = %v", results)
+	}
+	if results[0].Detail != "任务 cf-1" || results[2].Detail != "任务 tc-1" || results[4].Detail != "任务 ali-1" {
+		t.Errorf("results = %v", results)
+	}
+	// cloudflare 与 tencent 一次提交两个 URL，aliyun 每批一个
+	if len(requests) != 4 {
+		t.Fatalf("len(requests) = %d, want 4", len(requests))
+	}
+
+	cf := requests[0]
+	if cf.URL.Path != "/zones/zone/purge_cache" || cf.Header.Get("Authorization") != "Bearer cf-token" {
+		t.Errorf("cloudflare request = %s %s", cf.URL.Path, cf.Header.Get("Authorization"))
+	}
+	var files struct{ Files []string }
+	json.Unmarshal([]byte(bodies[0]), &files)
+	if !reflect.DeepEqual(files.Files, []string{"https://blog.example.com/", "https://blog.example.com/tags/Go/"}) {
+		t.Errorf("cloudflare body = %s", bodies[0])
+	}
+
+	tc := requests[1]
+	if tc.Header.Get("X-TC-Action") != "PurgeUrlsCache" || !strings.HasPrefix(tc.Header.Get("Authorization"), "TC3-HMAC-SHA256 Credential=id/") {
+		t.Errorf("tencent headers = %v", tc.Header)
+	}
+
+	form, _ := url.ParseQuery(bodies[2])
+	if form.Get("Action") != "RefreshObjectCaches" || form.Get("ObjectPath") != "https://blog.example.com/" || form.Get("Signature") == "" {
+		t.Errorf("aliyun form = %v", form)
+	}
+	// 签名覆盖除 Signature 外的所有参数
+	signature := form.Get("Signature")
+	form.Del("Signature")
+	if got := (&aliyun{cfg: config.PurgeBackend{SecretKey: "key"}}).sign(http.MethodPost, form); got != signature {
+		t.Errorf("aliyun Signature = %s, want %s", signature, got)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/zones/"):
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`)
+		case r.Header.Get("X-TC-Action") != "":
+			fmt.Fprint(w, `{"Response":{"RequestId":"r1","Error":{"Code":"AuthFailure.SignatureFailure","Message":"签名错误"}}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"Code":"InvalidAccessKeyId.NotFound","Message":"Specified access key is not found.","RequestId":"r2"}`)
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.Backends = []config.PurgeBackend{
+		{Name: "cf", Type: "cloudflare", BaseURL: server.URL, ZoneID: "zone"},
+		{Name: "qcloud", Type: "tencent", BaseURL: server.URL},
+		{Name: "alicdn", Type: "aliyun", BaseURL: server.URL + "/aliyun"},
+	}
+	results := New(cfg).Purge([]string{"/"})
+	want := []string{
+		"10000 Authentication error",
+		"AuthFailure.SignatureFailure: 签名错误（RequestId r1）",
+		"InvalidAccessKeyId.NotFound: Specified access key is not found.（RequestId r2）",
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %v", results)
+	}
+	for i, r := range results {
+		if r.OK || r.Detail != want[i] {
+			t.Errorf("%s: OK = %v, Detail = %q, want %q", r.Backend, r.OK, r.Detail, want[i])
+		}
+	}
+}
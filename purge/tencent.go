@@ -0,0 +1,98 @@
+package purge
+
+import (
+	"Hexo-AutoCD/config"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// tencent 调用腾讯云 CDN 的 PurgeUrlsCache 接口，请求按 TC3-HMAC-SHA256 签名
+type tencent struct {
+	cfg    config.PurgeBackend
+	client *http.Client
+	now    func() time.Time
+}
+
+// tencentResponse 腾讯云 API 3.0 的响应
+type tencentResponse struct {
+	Response struct {
+		TaskID    string `json:"TaskId"`
+		RequestID string `json:"RequestId"`
+		Error     *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+	} `json:"Response"`
+}
+
+func (b *tencent) purge(urls []string) (string, error) {
+	body, err := json.Marshal(map[string][]string{"Urls": urls})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, b.cfg.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-TC-Action", "PurgeUrlsCache")
+	req.Header.Set("X-TC-Version", "2018-06-06")
+	b.sign(req, body)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	var result tencentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("%s，无法解析响应: %v", resp.Status, err)
+	}
+	if e := result.Response.Error; e != nil {
+		return "", fmt.Errorf("%s: %s（RequestId %s）", e.Code, e.Message, result.Response.RequestID)
+	}
+	return "任务 " + result.Response.TaskID, nil
+}
+
+// sign 按腾讯云 API 3.0 的 TC3-HMAC-SHA256 规则签名，签名 content-type 与 host 两个头
+func (b *tencent) sign(req *http.Request, body []byte) {
+	now := b.now().UTC()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	date := now.Format("2006-01-02")
+	req.Header.Set("X-TC-Timestamp", timestamp)
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonicalRequest := "POST\n" + uri + "\n" + req.URL.RawQuery + "\n" +
+		"content-type:" + req.Header.Get("Content-Type") + "\nhost:" + req.URL.Host + "\n\n" +
+		"content-type;host\n" + sha256Hex(body)
+	scope := date + "/cdn/tc3_request"
+	stringToSign := "TC3-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("TC3"+b.cfg.SecretKey), date)
+	key = hmacSHA256(key, "cdn")
+	key = hmacSHA256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s",
+		b.cfg.SecretID, scope, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
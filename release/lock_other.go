@@ -0,0 +1,10 @@
+//go:build !unix
+
+package release
+
+import "os"
+
+// flock 在不支持 flock 的平台上不加锁，只能依靠进程内的互斥锁
+func flock(f *os.File) error {
+	return nil
+}
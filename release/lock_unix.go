@@ -0,0 +1,13 @@
+//go:build unix
+
+package release
+
+import (
+	"os"
+	"syscall"
+)
+
+// flock 对锁文件加排他锁，其他进程持有锁时阻塞等待
+func flock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
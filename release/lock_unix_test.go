@@ -0,0 +1,62 @@
+//go:build unix
+
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestRollbackWaitsForLock 其他进程持有锁文件时，回滚等待锁释放后才切换 current
+func TestRollbackWaitsForLock(t *testing.T) {
+	m := &Manager{Dir: t.TempDir(), Keep: 5}
+	for _, id := range []string{"20240101-000000-aaaaaaa", "20240102-000000-bbbbbbb"} {
+		if err := os.MkdirAll(filepath.Join(m.releasesDir(), id), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Activate("20240102-000000-bbbbbbb"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 另行打开的文件描述符与其他进程一样受 flock 约束
+	f, err := os.OpenFile(filepath.Join(m.releasesDir(), lockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := m.Rollback("")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Rollback() returned %v while the lock was held", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if current, _ := m.Current(); current != "20240102-000000-bbbbbbb" {
+		t.Fatalf("current = %s before the lock was released", current)
+	}
+
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := m.Current(); current != "20240101-000000-aaaaaaa" {
+		t.Errorf("current = %s, want 20240101-000000-aaaaaaa", current)
+	}
+
+	// 锁文件不算版本
+	releases, err := m.List()
+	if err != nil || len(releases) != 2 {
+		t.Errorf("List() = %+v, %v", releases, err)
+	}
+}
@@ -0,0 +1,323 @@
+package release
+
+import (
+	"Hexo-AutoCD/config"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idFormat 版本名中的时间部分，版本名为 时间-提交ID前7位，按名称排序即按时间排序
+const idFormat = "20060102-150405"
+
+// mu 保证同一进程中切换、回滚与清理版本不会同时进行，跨进程（如服务与 rollback 命令）由 lock 的文件锁保证
+var mu sync.Mutex
+
+// lockFile 发布目录中的锁文件，以 . 开头，List 不会把它当作版本
+const lockFile = ".lock"
+
+// Release 描述一个已构建的版本
+type Release struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	Commit    string    `json:"commit"` // 提交ID前7位，本地部署时为 local
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"` // 是否为 current 指向的版本
+}
+
+// Manager 管理发布根目录
+// 目录结构为 releases/<版本>/ 与指向当前版本的 current 符号链接，
+// Web 服务器的站点目录应指向 current
+type Manager struct {
+	Dir    string // 发布根目录
+	Source string // 构建输出目录，为空时由步骤直接写入版本目录
+	Keep   int    // 保留的版本数量
+}
+
+// New 根据配置创建发布管理器
+func New(cfg config.Releases) *Manager {
+	return &Manager{Dir: cfg.Dir, Source: cfg.Source, Keep: cfg.Keep}
+}
+
+// releasesDir 返回存放各版本的目录
+func (m *Manager) releasesDir() string {
+	return filepath.Join(m.Dir, "releases")
+}
+
+// currentLink 返回 current 符号链接的路径
+func (m *Manager) currentLink() string {
+	return filepath.Join(m.Dir, "current")
+}
+
+// lock 获取发布目录的锁，返回释放锁的函数
+// 锁文件放在 releases 目录中，进程退出时文件锁自动释放
+func (m *Manager) lock() (func(), error) {
+	mu.Lock()
+	if err := os.MkdirAll(m.releasesDir(), 0755); err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("创建发布目录失败: %v", err)
+	}
+	f, err := os.OpenFile(filepath.Join(m.releasesDir(), lockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("打开锁文件失败: %v", err)
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		mu.Unlock()
+		return nil, fmt.Errorf("锁定发布目录失败: %v", err)
+	}
+	return func() {
+		f.Close()
+		mu.Unlock()
+	}, nil
+}
+
+// Prepare 为一次部署创建空的版本目录，步骤可以通过 RELEASE_DIR 直接构建到其中
+func (m *Manager) Prepare(commit string, now time.Time) (*Release, error) {
+	if err := os.MkdirAll(m.releasesDir(), 0755); err != nil {
+		return nil, fmt.Errorf("创建发布目录失败: %v", err)
+	}
+	short := "local"
+	if commit != "" {
+		short = commit
+		if len(short) > 7 {
+			short = short[:7]
+		}
+	}
+
+	// 同一秒内重复部署同一个提交时追加序号
+	base := now.Format(idFormat) + "-" + short
+	id := base
+	for i := 2; ; i++ {
+		err := os.Mkdir(filepath.Join(m.releasesDir(), id), 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建版本目录失败: %v", err)
+		}
+		id = fmt.Sprintf("%s.%d", base, i)
+	}
+	return &Release{ID: id, Path: filepath.Join(m.releasesDir(), id), Commit: short, CreatedAt: now}, nil
+}
+
+// Publish 把构建输出复制到版本目录
+// 未设置 Source 时要求步骤已经写入版本目录；版本目录为空时返回错误，避免切换到空站点
+func (m *Manager) Publish(r *Release) error {
+	if m.Source != "" {
+		if info, err := os.Stat(m.Source); err != nil || !info.IsDir() {
+			return fmt.Errorf("构建输出目录不存在: %s", m.Source)
+		}
+		if err := copyDir(m.Source, r.Path); err != nil {
+			return fmt.Errorf("复制构建输出失败: %v", err)
+		}
+	}
+	entries, err := os.ReadDir(r.Path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("版本目录为空: %s", r.Path)
+	}
+	return nil
+}
+
+// Discard 删除未发布的版本目录，例如构建失败时
+func (m *Manager) Discard(r *Release) error {
+	return os.RemoveAll(r.Path)
+}
+
+// Activate 把 current 原子地切换到指定版本，返回切换前的版本
+// 先创建临时符号链接再重命名覆盖，Web 服务器在任何时刻看到的都是完整的站点
+func (m *Manager) Activate(id string) (string, error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	return m.activate(id)
+}
+
+func (m *Manager) activate(id string) (string, error) {
+	if err := m.check(id); err != nil {
+		return "", err
+	}
+	previous, _ := m.Current()
+
+	tmp := filepath.Join(m.Dir, fmt.Sprintf(".current.%d", time.Now().UnixNano()))
+	if err := os.Symlink(filepath.Join("releases", id), tmp); err != nil {
+		return previous, fmt.Errorf("创建符号链接失败: %v", err)
+	}
+	if err := os.Rename(tmp, m.currentLink()); err != nil {
+		os.Remove(tmp)
+		return previous, fmt.Errorf("切换 current 失败: %v", err)
+	}
+	return previous, nil
+}
+
+// check 确认 id 是 List 列出的某个版本
+// id 来自管理接口与命令行，不能含有路径分隔符或以 . 开头，否则 current 可能指向 releases 目录之外
+func (m *Manager) check(id string) error {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("版本名不合法: %q", id)
+	}
+	releases, err := m.List()
+	if err != nil {
+		return fmt.Errorf("读取版本失败: %v", err)
+	}
+	for _, r := range releases {
+		if r.ID == id {
+			return nil
+		}
+	}
+	return fmt.Errorf("版本不存在: %s", id)
+}
+
+// Current 返回 current 指向的版本，尚未发布过时返回空字符串
+func (m *Manager) Current() (string, error) {
+	target, err := os.Readlink(m.currentLink())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取 current 失败: %v", err)
+	}
+	return filepath.Base(target), nil
+}
+
+// List 返回所有版本，最新的在前
+func (m *Manager) List() ([]Release, error) {
+	entries, err := os.ReadDir(m.releasesDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current, _ := m.Current()
+
+	var releases []Release
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		r := Release{ID: entry.Name(), Path: filepath.Join(m.releasesDir(), entry.Name()), Current: entry.Name() == current}
+		// 版本名形如 20240102-150405-abc1234，可能带有 .2 等序号
+		parts := strings.SplitN(entry.Name(), "-", 3)
+		if len(parts) == 3 {
+			r.CreatedAt, _ = time.ParseInLocation(idFormat, parts[0]+"-"+parts[1], time.Local)
+			r.Commit, _, _ = strings.Cut(parts[2], ".")
+		}
+		releases = append(releases, r)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].ID > releases[j].ID })
+	return releases, nil
+}
+
+// Rollback 把 current 切换到指定版本，id 为空时切换到当前版本之前的一个版本
+// 返回切换后与切换前的版本
+func (m *Manager) Rollback(id string) (string, string, error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+
+	if id == "" {
+		releases, err := m.List()
+		if err != nil {
+			return "", "", err
+		}
+		current, _ := m.Current()
+		for i, r := range releases {
+			if r.ID == current && i+1 < len(releases) {
+				id = releases[i+1].ID
+				break
+			}
+		}
+		if id == "" {
+			return "", current, fmt.Errorf("没有可以回滚的旧版本")
+		}
+	}
+	previous, err := m.activate(id)
+	return id, previous, err
+}
+
+// Prune 删除多余的旧版本，保留最新的 Keep 个版本；current 指向的版本始终保留
+// 返回被删除的版本
+func (m *Manager) Prune() ([]string, error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	releases, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for i, r := range releases {
+		if i < m.Keep || r.Current {
+			continue
+		}
+		if err := os.RemoveAll(r.Path); err != nil {
+			return removed, fmt.Errorf("删除旧版本 %s 失败: %v", r.ID, err)
+		}
+		removed = append(removed, r.ID)
+	}
+	return removed, nil
+}
+
+// copyDir 递归复制目录，保留文件权限与符号链接
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,39 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollbackRejectsInvalidID(t *testing.T) {
+	root := t.TempDir()
+	m := &Manager{Dir: filepath.Join(root, "site"), Keep: 5}
+	for _, dir := range []string{"site/releases/20240101-000000-aaaaaaa", "site/releases/.hidden", "secret"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(m.releasesDir(), "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Activate("20240101-000000-aaaaaaa"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"../../secret", "../releases", ".", "..", ".hidden", ".lock", "file", "a/b", "20240101-000000-missing"} {
+		if _, _, err := m.Rollback(id); err == nil {
+			t.Errorf("Rollback(%q) error = nil", id)
+		}
+		if _, err := m.Activate(id); err == nil {
+			t.Errorf("Activate(%q) error = nil", id)
+		}
+	}
+	if current, _ := m.Current(); current != "20240101-000000-aaaaaaa" {
+		t.Errorf("current = %s after invalid rollbacks", current)
+	}
+	target, _ := os.Readlink(m.currentLink())
+	if target != filepath.Join("releases", "20240101-000000-aaaaaaa") {
+		t.Errorf("current -> %s", target)
+	}
+}
@@ -0,0 +1,37 @@
+package release
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/events"
+	"Hexo-AutoCD/logger"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RollbackSite 按当前配置把站点回滚到指定版本，id 为空时回滚到上一个版本
+// 供命令行与管理接口使用，by 记录回滚的发起方；回滚后通知下游系统
+func RollbackSite(id, by string) (string, string, error) {
+	cfg := config.Get()
+	if !cfg.Releases.Enabled {
+		return "", "", fmt.Errorf("未启用 releases")
+	}
+
+	to, from, err := New(cfg.Releases).Rollback(id)
+	rollbackLogger := logger.WithFields(logrus.Fields{
+		"目标版本": to,
+		"原版本":  from,
+		"发起方":  by,
+	})
+	if err != nil {
+		rollbackLogger.WithError(err).Error("回滚失败")
+		return to, from, err
+	}
+	rollbackLogger.Warn("站点已回滚")
+
+	evt := events.NewEvent(events.TypeRolledBack)
+	evt.Outcome = events.OutcomeSuccess
+	evt.Data = map[string]interface{}{"release": to, "previous_release": from, "by": by}
+	events.Publish(evt)
+	return to, from, nil
+}
@@ -1,19 +1,41 @@
 package router
 
 import (
+	"Hexo-AutoCD/api"
 	"Hexo-AutoCD/config"
 	"Hexo-AutoCD/webhooks"
+	"net/http"
+	"sync/atomic"
 
-	"github.com/gin-gonic/gin"
 	"Hexo-AutoCD/middlewares"
+	"github.com/gin-gonic/gin"
 )
 
+// engine 当前生效的路由，配置热加载后整体替换
+var engine atomic.Pointer[gin.Engine]
+
 // InitRouter 初始化路由
 func InitRouter() *gin.Engine {
 	r := gin.Default()
 	// 设置拒绝扫描中间件
 	r.Use(middlewares.DenyScan())
 	// 注册 webhook 路由
-	r.POST(config.Config.Webhook.Path, webhooks.HandleWebhook)
+	r.POST(config.Get().Webhook.Path, webhooks.HandleWebhook)
+	// 注册管理接口
+	api.Register(r)
+	engine.Store(r)
 	return r
 }
+
+// Handler 返回始终转发到当前路由的 http.Handler
+// 配置热加载重建路由后，正在监听的服务器无需重启即可使用新路由
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		engine.Load().ServeHTTP(w, req)
+	})
+}
+
+// Reload 根据当前配置重建路由并原子替换
+func Reload() {
+	InitRouter()
+}
@@ -0,0 +1,300 @@
+package scripts
+
+import (
+	"Hexo-AutoCD/logger"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ContainerConfig 定义容器执行器
+type ContainerConfig struct {
+	Host     string   // 接口地址，如 unix:///var/run/docker.sock
+	Image    string   // 执行命令的镜像
+	Pull     string   // 拉取策略：missing、always、never
+	Mounts   []string // 挂载的目录，格式为 主机路径[:容器路径][:ro]
+	User     string   // 容器内的用户，为空时使用服务进程的 uid:gid
+	Memory   int64    // 内存上限（字节），为 0 时不限制
+	NanoCPUs int64    // CPU 上限（十亿分之一核），为 0 时不限制
+	Network  string   // 网络模式
+	Env      []string // 追加的环境变量
+}
+
+// ContainerExecutor 在容器中执行命令的执行器
+// 每条命令使用一个新的容器，执行结束后删除；容器中只有执行器导出的环境变量，不继承服务进程的环境
+type ContainerExecutor struct {
+	config    ExecutorConfig
+	container ContainerConfig
+	client    *dockerClient
+	semaphore chan struct{} // 信号量，用于控制同时运行的容器数
+	pullOnce  sync.Once     // 同一个执行器只检查一次镜像
+	pullErr   error
+	payload   payloadFiles // 载荷文件，所在目录以只读方式挂载到容器中
+}
+
+// NewContainerExecutor 创建容器执行器
+func NewContainerExecutor(config ExecutorConfig, container ContainerConfig) (*ContainerExecutor, error) {
+	client, err := newDockerClient(container.Host)
+	if err != nil {
+		return nil, err
+	}
+	if container.Image == "" {
+		return nil, fmt.Errorf("未配置容器镜像")
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 5
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	if container.User == "" {
+		container.User = fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	}
+	return &ContainerExecutor{
+		config:    config,
+		container: container,
+		client:    client,
+		semaphore: make(chan struct{}, config.MaxConcurrent),
+	}, nil
+}
+
+// Execute 在容器中执行指定事件对应的脚本
+func (e *ContainerExecutor) Execute(event string, payload interface{}) (*ExecutionResult, error) {
+	command, err := scriptCommand(e.config.ScriptsPath, event)
+	if err != nil {
+		return nil, err
+	}
+	return e.Run(command)
+}
+
+// Close 删除执行器写入的载荷文件，所有命令执行结束后调用
+func (e *ContainerExecutor) Close() error {
+	return e.payload.close()
+}
+
+// Run 在新容器中执行一条命令，实时记录输出并返回执行结果
+// 超时或执行结束后容器都会被删除
+func (e *ContainerExecutor) Run(command Command) (*ExecutionResult, error) {
+	scriptLogger := logger.WithFields(logrus.Fields{
+		"脚本": command.Name,
+		"命令": strings.TrimSpace(command.Path + " " + strings.Join(command.Args, " ")),
+		"镜像": e.container.Image,
+	})
+
+	scriptLogger.Info("准备在容器中执行脚本")
+
+	e.semaphore <- struct{}{}
+	defer func() { <-e.semaphore }()
+
+	timeout := command.Timeout
+	if timeout <= 0 {
+		timeout = e.config.Timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := e.pullImage(ctx); err != nil {
+		scriptLogger.WithError(err).Error("准备镜像失败")
+		return nil, err
+	}
+
+	// 环境变量：默认变量、事件信息、命令自身的变量，最后是 executor.docker.env
+	env := append([]string{}, e.config.DefaultEnv...)
+	env = append(env, e.payload.prepare(e.config.Payload)...)
+	env = append(env, command.Env...)
+	env = append(env, e.container.Env...)
+
+	id, err := e.client.create(ctx, containerSpec{
+		Image:        e.container.Image,
+		Cmd:          append([]string{command.Path}, command.Args...),
+		Env:          env,
+		WorkingDir:   command.Dir,
+		User:         e.container.User,
+		AttachStdout: true,
+		AttachStderr: true,
+		Labels:       map[string]string{"hexo-autocd.command": command.Name},
+		HostConfig: hostConfig{
+			Binds:       e.binds(command),
+			Memory:      e.container.Memory,
+			NanoCpus:    e.container.NanoCPUs,
+			NetworkMode: e.container.Network,
+		},
+	})
+	if err != nil {
+		scriptLogger.WithError(err).Error("创建容器失败")
+		return nil, fmt.Errorf("创建容器失败: %v", err)
+	}
+	scriptLogger = scriptLogger.WithField("容器", shortID(id))
+
+	// 无论结果如何都删除容器，超时后上下文已取消，使用新的上下文
+	defer func() {
+		cleanup, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := e.client.remove(cleanup, id); err != nil {
+			scriptLogger.WithError(err).Warn("删除容器失败")
+		}
+	}()
+
+	startTime := time.Now()
+	scriptLogger.WithField("开始时间", startTime.Format("2006-01-02 15:04:05")).Info("开始执行脚本")
+	if err := e.client.start(ctx, id); err != nil {
+		scriptLogger.WithError(err).Error("启动容器失败")
+		return nil, fmt.Errorf("启动容器失败: %v", err)
+	}
+
+	// 跟随容器输出，容器退出后日志流结束
+	out := &output{}
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		out.read(stdout, false, scriptLogger)
+	}()
+	go func() {
+		defer wg.Done()
+		out.read(stderr, true, scriptLogger)
+	}()
+	go func() {
+		defer wg.Done()
+		err := e.client.logs(ctx, id, stdoutWriter, stderrWriter)
+		if err != nil && ctx.Err() == nil {
+			scriptLogger.WithError(err).Warn("读取容器输出失败")
+		}
+		stdoutWriter.Close()
+		stderrWriter.Close()
+	}()
+
+	exitCode, err := e.client.wait(ctx, id)
+	if ctx.Err() != nil {
+		// 超时后停止容器，日志流随之结束
+		kill, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		e.client.kill(kill, id)
+		cancel()
+	}
+	wg.Wait()
+	endTime := time.Now()
+
+	result := &ExecutionResult{
+		Output:   out.buffer.String(),
+		ExitCode: exitCode,
+		Logs:     out.logs,
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Error = "script execution timed out"
+		result.ExitCode = -1
+		scriptLogger.Error("脚本执行超时")
+	case err != nil:
+		result.Error = err.Error()
+		scriptLogger.WithError(err).Error("脚本执行遇到错误")
+	case exitCode != 0:
+		result.Error = fmt.Sprintf("exit status %d", exitCode)
+		if e.client.oomKilled(ctx, id) {
+			result.Error = fmt.Sprintf("超出内存上限，容器被终止（exit status %d）", exitCode)
+		}
+		scriptLogger.WithFields(logrus.Fields{
+			"退出码":  exitCode,
+			"结束时间": endTime.Format("2006-01-02 15:04:05"),
+			"执行时长": endTime.Sub(startTime).String(),
+		}).Warn("脚本执行返回非零退出码")
+	default:
+		scriptLogger.WithFields(logrus.Fields{
+			"结束时间": endTime.Format("2006-01-02 15:04:05"),
+			"执行时长": endTime.Sub(startTime).String(),
+		}).Info("脚本执行成功")
+	}
+
+	return result, nil
+}
+
+// pullImage 按拉取策略准备镜像
+func (e *ContainerExecutor) pullImage(ctx context.Context) error {
+	e.pullOnce.Do(func() {
+		image := e.container.Image
+		switch e.container.Pull {
+		case "never":
+			return
+		case "always":
+		default:
+			exists, err := e.client.imageExists(ctx, image)
+			if err != nil {
+				e.pullErr = fmt.Errorf("检查镜像 %s 失败: %v", image, err)
+				return
+			}
+			if exists {
+				return
+			}
+		}
+		logger.WithField("镜像", image).Info("正在拉取镜像")
+		e.pullErr = e.client.pull(ctx, image)
+	})
+	return e.pullErr
+}
+
+// binds 返回命令需要的挂载
+// 脚本目录、工作目录与配置的目录以相同路径挂载，载荷目录只读；同一容器路径只挂载一次，不存在的主机目录不挂载
+func (e *ContainerExecutor) binds(command Command) []string {
+	var binds []string
+	seen := make(map[string]bool)
+	add := func(host, target string, readOnly bool) {
+		host, target = filepath.Clean(host), filepath.Clean(target)
+		if host == "." || seen[target] {
+			return
+		}
+		if _, err := os.Stat(host); err != nil {
+			return
+		}
+		seen[target] = true
+		bind := host + ":" + target
+		if readOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+
+	for _, mount := range e.container.Mounts {
+		host, target, readOnly := parseMount(mount)
+		add(host, target, readOnly)
+	}
+	add(command.Dir, command.Dir, false)
+	if e.config.ScriptsPath != "" {
+		add(e.config.ScriptsPath, e.config.ScriptsPath, false)
+	}
+	if e.payload.dir != "" {
+		add(e.payload.dir, e.payload.dir, true)
+	}
+	return binds
+}
+
+// parseMount 解析 主机路径[:容器路径][:ro] 形式的挂载
+func parseMount(mount string) (host, target string, readOnly bool) {
+	parts := strings.Split(mount, ":")
+	if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+		readOnly = parts[n-1] == "ro"
+		parts = parts[:n-1]
+	}
+	host, target = parts[0], parts[0]
+	if len(parts) > 1 && parts[1] != "" {
+		target = parts[1]
+	}
+	return host, target, readOnly
+}
+
+// shortID 返回容器ID的前 12 位
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,355 @@
+package scripts
+
+import (
+	"Hexo-AutoCD/logger"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// frame 构造多路输出中的一帧
+func frame(stream byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestDemux(t *testing.T) {
+	var input bytes.Buffer
+	input.Write(frame(1, "out 1\n"))
+	input.Write(frame(2, "err 1\n"))
+	input.Write(frame(1, "out 2\n"))
+	var stdout, stderr bytes.Buffer
+	if err := demux(&input, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "out 1\nout 2\n" || stderr.String() != "err 1\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	// 帧不完整时返回错误
+	truncated := append(frame(1, "out"), frame(2, "lost")[:10]...)
+	if err := demux(bytes.NewReader(truncated), io.Discard, io.Discard); err == nil {
+		t.Error("demux() of a truncated frame error = nil")
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := map[string][2]string{
+		"node:20":                           {"node", "20"},
+		"node":                              {"node", ""},
+		"registry:5000/hexo/builder":        {"registry:5000/hexo/builder", ""},
+		"registry:5000/hexo/builder:v1":     {"registry:5000/hexo/builder", "v1"},
+		"node@sha256:0123456789abcdef01234": {"node@sha256:0123456789abcdef01234", ""},
+	}
+	for image, want := range tests {
+		if name, tag := splitImage(image); name != want[0] || tag != want[1] {
+			t.Errorf("splitImage(%q) = %q, %q, want %q, %q", image, name, tag, want[0], want[1])
+		}
+	}
+}
+
+// fakeContainer 假的 Docker 接口中的一个容器，行为由 hexo-autocd.command 标签决定：
+// ok 正常退出，fail 以 3 退出，oom 以 137 退出且被标记为 OOMKilled，hang 一直运行直到被停止
+type fakeContainer struct {
+	spec    containerSpec
+	started bool
+	killed  chan struct{}
+}
+
+// fakeDocker 在 unix 套接字上模拟 Docker Engine API
+type fakeDocker struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	images     map[string]bool
+	pulls      []string
+	created    []containerSpec
+	containers map[string]*fakeContainer
+	removed    []string
+	next       int
+}
+
+// startFakeDocker 启动假的 Docker 接口并返回 unix:// 地址
+func startFakeDocker(t *testing.T) (*fakeDocker, string) {
+	// unix 套接字路径有长度限制，不使用 t.TempDir()
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &fakeDocker{t: t, images: map[string]bool{}, containers: map[string]*fakeContainer{}}
+	server := httptest.NewUnstartedServer(d)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return d, "unix://" + socket
+}
+
+func (d *fakeDocker) container(id string) *fakeContainer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.containers[id]
+}
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.Path, dockerAPIVersion)
+	if !ok {
+		http.Error(w, `{"message":"unsupported api version"}`, http.StatusBadRequest)
+		return
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && parts[0] == "images":
+		image := strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json")
+		d.mu.Lock()
+		exists := d.images[image]
+		d.mu.Unlock()
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"message":"No such image: %s"}`, image)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	case r.Method == http.MethodPost && path == "/images/create":
+		image := r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+		d.mu.Lock()
+		d.pulls = append(d.pulls, image)
+		d.images[image] = true
+		d.mu.Unlock()
+		fmt.Fprint(w, `{"status":"Pulling"}`+"\n"+`{"status":"Downloaded"}`+"\n")
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var spec containerSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			d.t.Errorf("decode create body: %v", err)
+		}
+		d.mu.Lock()
+		d.next++
+		id := fmt.Sprintf("%064d", d.next)
+		d.containers[id] = &fakeContainer{spec: spec, killed: make(chan struct{})}
+		d.created = append(d.created, spec)
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, id)
+	case parts[0] == "containers" && len(parts) >= 2:
+		c := d.container(parts[1])
+		if c == nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"No such container"}`)
+			return
+		}
+		d.containerRequest(w, r, parts[1], c, strings.Join(parts[2:], "/"))
+	default:
+		http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+	}
+}
+
+func (d *fakeDocker) containerRequest(w http.ResponseWriter, r *http.Request, id string, c *fakeContainer, action string) {
+	behavior := c.spec.Labels["hexo-autocd.command"]
+	switch {
+	case r.Method == http.MethodPost && action == "start":
+		d.mu.Lock()
+		c.started = true
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && action == "logs":
+		d.mu.Lock()
+		started := c.started
+		d.mu.Unlock()
+		if !started || r.URL.Query().Get("follow") != "1" {
+			d.t.Errorf("logs requested before start or without follow: %s", r.URL.RawQuery)
+		}
+		w.Write(frame(1, "building "+strings.Join(c.spec.Cmd, " ")+"\n"))
+		w.Write(frame(2, "warning: deprecated\n"))
+		w.Write(frame(1, "token="+envValue(c.spec.Env, "GITHUB_TOKEN")+"\n"))
+		w.(http.Flusher).Flush()
+		if behavior == "hang" {
+			select {
+			case <-c.killed:
+			case <-r.Context().Done():
+			}
+		}
+	case r.Method == http.MethodPost && action == "wait":
+		code := map[string]int{"ok": 0, "fail": 3, "oom": 137}[behavior]
+		if behavior == "hang" {
+			select {
+			case <-c.killed:
+				code = 137
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"StatusCode":%d}`, code)
+	case r.Method == http.MethodGet && action == "json":
+		fmt.Fprintf(w, `{"State":{"OOMKilled":%v}}`, behavior == "oom")
+	case r.Method == http.MethodPost && action == "kill":
+		d.mu.Lock()
+		select {
+		case <-c.killed:
+		default:
+			close(c.killed)
+		}
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && action == "":
+		if r.URL.Query().Get("force") != "1" || r.URL.Query().Get("v") != "1" {
+			d.t.Errorf("remove without force and v: %s", r.URL.RawQuery)
+		}
+		d.mu.Lock()
+		delete(d.containers, id)
+		d.removed = append(d.removed, id)
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, `{"message":"not implemented"}`, http.StatusNotImplemented)
+	}
+}
+
+// envValue 返回 KEY=VALUE 列表中 key 的值
+func envValue(env []string, key string) string {
+	value := ""
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+func newTestContainerExecutor(t *testing.T, host string, timeout time.Duration) *ContainerExecutor {
+	t.Helper()
+	e, err := NewContainerExecutor(ExecutorConfig{Timeout: timeout, DefaultEnv: []string{"GITHUB_TOKEN=default"}}, ContainerConfig{
+		Host:  host,
+		Image: "node:20",
+		Pull:  "missing",
+		User:  "1000:1000",
+		Env:   []string{"GITHUB_TOKEN=from-config"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestContainerRun(t *testing.T) {
+	docker, host := startFakeDocker(t)
+	e := newTestContainerExecutor(t, host, time.Minute)
+	dir := t.TempDir()
+
+	result, err := e.Run(Command{Name: "ok", Path: "/bin/bash", Args: []string{"-c", "hexo generate"}, Dir: dir, Env: []string{"GITHUB_TOKEN=from-step"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode != 0 || result.Error != "" {
+		t.Errorf("result = %+v", result)
+	}
+	// 标准输出与错误输出都被记录，executor.docker.env 最后设置，优先于命令自身的变量
+	want := "building /bin/bash -c hexo generate\nwarning: deprecated\ntoken=from-config\n"
+	if result.Output != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+	if len(docker.pulls) != 1 || docker.pulls[0] != "node:20" {
+		t.Errorf("pulls = %v, want node:20 pulled once", docker.pulls)
+	}
+	if len(docker.removed) != 1 || len(docker.containers) != 0 {
+		t.Errorf("removed = %v, remaining = %d, want the container removed", docker.removed, len(docker.containers))
+	}
+
+	// 镜像已拉取，不再拉取；非零退出码原样返回，超出内存时说明原因
+	tests := []struct {
+		name     string
+		exitCode int
+		error    string
+	}{
+		{"fail", 3, "exit status 3"},
+		{"oom", 137, "超出内存上限，容器被终止（exit status 137）"},
+	}
+	for _, tt := range tests {
+		result, err := e.Run(Command{Name: tt.name, Path: "/bin/sh", Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ExitCode != tt.exitCode || result.Error != tt.error {
+			t.Errorf("%s: ExitCode = %d, Error = %q, want %d, %q", tt.name, result.ExitCode, result.Error, tt.exitCode, tt.error)
+		}
+	}
+	if len(docker.pulls) != 1 || len(docker.removed) != 3 {
+		t.Errorf("pulls = %v, removed = %v", docker.pulls, docker.removed)
+	}
+
+	spec := docker.created[0]
+	if spec.Image != "node:20" || spec.User != "1000:1000" || spec.WorkingDir != dir || !spec.AttachStdout || !spec.AttachStderr || spec.Tty {
+		t.Errorf("spec = %+v", spec)
+	}
+	if len(spec.HostConfig.Binds) != 1 || spec.HostConfig.Binds[0] != dir+":"+dir {
+		t.Errorf("Binds = %v, want the working directory", spec.HostConfig.Binds)
+	}
+}
+
+func TestContainerTimeout(t *testing.T) {
+	docker, host := startFakeDocker(t)
+	docker.images["node:20"] = true
+	e := newTestContainerExecutor(t, host, time.Minute)
+
+	start := time.Now()
+	result, err := e.Run(Command{Name: "hang", Path: "/bin/sleep", Args: []string{"600"}, Timeout: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Run() took %s after the timeout", elapsed)
+	}
+	if result.ExitCode != -1 || result.Error != "script execution timed out" {
+		t.Errorf("result = %+v, want a timeout", result)
+	}
+	if !strings.Contains(result.Output, "building /bin/sleep 600") {
+		t.Errorf("Output = %q, want the output before the timeout", result.Output)
+	}
+	// 超时后容器被停止并删除
+	docker.mu.Lock()
+	defer docker.mu.Unlock()
+	if len(docker.pulls) != 0 || len(docker.removed) != 1 || len(docker.containers) != 0 {
+		t.Errorf("pulls = %v, removed = %v, remaining = %d", docker.pulls, docker.removed, len(docker.containers))
+	}
+}
+
+func TestContainerCreateError(t *testing.T) {
+	docker, host := startFakeDocker(t)
+	docker.images["node:20"] = true
+	e := newTestContainerExecutor(t, host, time.Minute)
+	e.container.Pull = "never"
+
+	// 接口返回错误时，错误信息取自响应中的 message
+	e.client.base = "http://docker/v1.0"
+	_, err := e.Run(Command{Name: "ok", Path: "/bin/true"})
+	if err == nil || !strings.Contains(err.Error(), "容器接口返回 400: unsupported api version") {
+		t.Errorf("Run() error = %v", err)
+	}
+}
@@ -160,17 +160,22 @@ log "拉取最新文章..."
 # 配置Git环境
 setup_git
 
-# 重置所有本地更改
-git reset --hard HEAD
-git clean -fd  # 删除未跟踪的文件和目录
-
-# 拉取最新更改
-git pull origin main
-
-# 检查是否有错误
-if [ $? -ne 0 ]; then
-    log "错误：拉取文章失败"
-    exit 1
+# 启用 git.enabled 时服务已检出推送中的提交，无需再拉取
+if [ "$COMMIT_CHECKED_OUT" = "1" ]; then
+    log "服务已检出提交 $(git rev-parse --short HEAD)"
+else
+    # 重置所有本地更改
+    git reset --hard HEAD
+    git clean -fd  # 删除未跟踪的文件和目录
+
+    # 拉取最新更改
+    git pull origin main
+
+    # 检查是否有错误
+    if [ $? -ne 0 ]; then
+        log "错误：拉取文章失败"
+        exit 1
+    fi
 fi
 
 # 处理新增和修改的文件
@@ -198,8 +203,8 @@ log "开始部署博客..."
 
 sleep 1
 
-# 停止hexo服务
-if ! stop_hexo; then
+# 停止hexo服务，启用内置静态文件服务时不需要
+if [ "$STATIC_SERVE" != "1" ] && ! stop_hexo; then
     log "警告：继续部署，但hexo服务可能未完全停止"
 fi
 
@@ -218,7 +223,7 @@ if [ $? -ne 0 ]; then
 fi
 
 # 启动hexo服务
-if ! start_hexo; then
+if [ "$STATIC_SERVE" != "1" ] && ! start_hexo; then
     log "错误：部署完成但服务启动失败"
     exit 1
 fi
@@ -0,0 +1,279 @@
+package scripts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// dockerAPIVersion Docker Engine API 的版本，Podman 的兼容接口同样支持
+const dockerAPIVersion = "/v1.41"
+
+// dockerClient Docker Engine API 客户端，只实现容器执行器用到的接口
+type dockerClient struct {
+	http *http.Client
+	base string // 请求地址前缀，使用 unix 套接字时主机名无意义
+}
+
+// newDockerClient 根据 unix:///path 或 tcp://host:port 形式的地址创建客户端
+func newDockerClient(host string) (*dockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("容器接口地址不合法: %v", err)
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &dockerClient{http: &http.Client{Transport: transport}, base: "http://docker" + dockerAPIVersion}, nil
+	case "tcp":
+		return &dockerClient{http: &http.Client{}, base: "http://" + u.Host + dockerAPIVersion}, nil
+	default:
+		return nil, fmt.Errorf("不支持的容器接口地址: %s（可选：unix://、tcp://）", host)
+	}
+}
+
+// do 发送请求，状态码不是 2xx 时返回接口给出的错误信息
+func (c *dockerClient) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return resp, &dockerError{Status: resp.StatusCode, Message: apiErr.Message}
+	}
+	return resp, nil
+}
+
+// call 发送请求并把响应解析到 out，out 为 nil 时丢弃响应
+func (c *dockerClient) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// dockerError 接口返回的错误
+type dockerError struct {
+	Status  int
+	Message string
+}
+
+func (e *dockerError) Error() string {
+	return fmt.Sprintf("容器接口返回 %d: %s", e.Status, e.Message)
+}
+
+// isNotFound 判断错误是否为资源不存在
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*dockerError)
+	return ok && apiErr.Status == http.StatusNotFound
+}
+
+// imageExists 判断本地是否已有镜像
+func (c *dockerClient) imageExists(ctx context.Context, image string) (bool, error) {
+	err := c.call(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pull 拉取镜像，拉取进度以 JSON 流返回，其中的错误也需要检查
+func (c *dockerClient) pull(ctx context.Context, image string) error {
+	name, tag := splitImage(image)
+	query := url.Values{"fromImage": {name}}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/images/create", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&progress); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("拉取镜像 %s 失败: %s", image, progress.Error)
+		}
+	}
+}
+
+// splitImage 把镜像拆分为名称与标签，使用摘要或没有标签时标签为空
+func splitImage(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+// containerSpec 创建容器时的参数，字段名与 Engine API 一致
+type containerSpec struct {
+	Image        string
+	Cmd          []string
+	Env          []string
+	WorkingDir   string
+	User         string `json:",omitempty"`
+	AttachStdout bool
+	AttachStderr bool
+	Tty          bool
+	Labels       map[string]string
+	HostConfig   hostConfig
+}
+
+// hostConfig 容器的挂载与资源限制
+type hostConfig struct {
+	Binds       []string
+	Memory      int64  `json:",omitempty"`
+	NanoCpus    int64  `json:",omitempty"`
+	NetworkMode string `json:",omitempty"`
+}
+
+// create 创建容器并返回容器ID
+func (c *dockerClient) create(ctx context.Context, spec containerSpec) (string, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers/create", nil, spec, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// start 启动容器
+func (c *dockerClient) start(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+}
+
+// logs 跟随容器的输出直到容器退出，按流类型分别写入 stdout 与 stderr
+func (c *dockerClient) logs(ctx context.Context, id string, stdout, stderr io.Writer) error {
+	query := url.Values{"follow": {"1"}, "stdout": {"1"}, "stderr": {"1"}}
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+id+"/logs", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return demux(resp.Body, stdout, stderr)
+}
+
+// demux 拆分未分配终端时的多路输出，每帧以 8 字节的头开始：流类型、3 字节填充、4 字节大端长度
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, reader, size); err != nil {
+			return err
+		}
+	}
+}
+
+// wait 等待容器退出并返回退出码
+func (c *dockerClient) wait(ctx context.Context, id string) (int, error) {
+	var status struct {
+		StatusCode int `json:"StatusCode"`
+		Error      *struct {
+			Message string `json:"Message"`
+		} `json:"Error"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers/"+id+"/wait", nil, nil, &status); err != nil {
+		return -1, err
+	}
+	if status.Error != nil && status.Error.Message != "" {
+		return status.StatusCode, fmt.Errorf("%s", status.Error.Message)
+	}
+	return status.StatusCode, nil
+}
+
+// oomKilled 判断容器是否因超出内存上限被终止
+func (c *dockerClient) oomKilled(ctx context.Context, id string) bool {
+	var info struct {
+		State struct {
+			OOMKilled bool `json:"OOMKilled"`
+		} `json:"State"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &info); err != nil {
+		return false
+	}
+	return info.State.OOMKilled
+}
+
+// kill 强制停止容器
+func (c *dockerClient) kill(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, "/containers/"+id+"/kill", nil, nil, nil)
+}
+
+// remove 删除容器及其匿名卷
+func (c *dockerClient) remove(ctx context.Context, id string) error {
+	err := c.call(ctx, http.MethodDelete, "/containers/"+id, url.Values{"force": {"1"}, "v": {"1"}}, nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -2,9 +2,11 @@ package scripts
 
 import (
 	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/redact"
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -20,10 +22,84 @@ import (
 // ExecutionResult 定义脚本执行结果
 // 这个结构体用于存储脚本执行后的各种状态
 type ExecutionResult struct {
-	Output   string   `json:"output"`          // 脚本的输出内容
-	ExitCode int      `json:"exit_code"`       // 脚本的退出码，0表示成功，非0表示失败
-	Error    string   `json:"error,omitempty"` // 如果执行出错，这里存储错误信息
-	Logs     []string `json:"logs"`            // 执行日志
+	Output   string       `json:"output"`          // 脚本的输出内容
+	ExitCode int          `json:"exit_code"`       // 脚本的退出码，0表示成功，非0表示失败
+	Error    string       `json:"error,omitempty"` // 如果执行出错，这里存储错误信息
+	Logs     []string     `json:"logs"`            // 执行日志
+	Steps    []StepResult `json:"steps,omitempty"` // 流水线各步骤的执行结果
+	Hosts    []HostResult `json:"hosts,omitempty"` // 在远程主机上执行时各主机的执行结果
+
+	Attempts int       `json:"attempts,omitempty"` // 部署脚本的执行次数（含重试）
+	History  []Attempt `json:"history,omitempty"`  // 部署脚本重试时每次执行的结果
+}
+
+// HostResult 定义一台远程主机上的执行结果
+type HostResult struct {
+	Host       string `json:"host"`            // 主机名称
+	Status     string `json:"status"`          // success、failed 或 skipped
+	ExitCode   int    `json:"exit_code"`       // 退出码
+	Error      string `json:"error,omitempty"` // 失败或跳过的原因
+	Output     string `json:"output,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// 步骤状态
+const (
+	StepSuccess = "success"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// StepResult 定义流水线中单个步骤的执行结果
+type StepResult struct {
+	Name       string `json:"name"`                 // 步骤名称
+	Status     string `json:"status"`               // 步骤状态：success、failed 或 skipped
+	ExitCode   int    `json:"exit_code"`            // 最后一次执行的退出码
+	Error      string `json:"error,omitempty"`      // 失败原因
+	ErrorKind  string `json:"error_kind,omitempty"` // 失败类型，如 git 检出时的 auth_failed、dirty_tree
+	Reason     string `json:"reason,omitempty"`     // 跳过原因
+	Output     string `json:"output,omitempty"`     // 步骤输出
+	StartedAt  string `json:"started_at,omitempty"` // 开始时间
+	DurationMs int64  `json:"duration_ms"`          // 耗时（毫秒）
+	Attempts   int    `json:"attempts,omitempty"`   // 执行次数（含重试）
+
+	History []Attempt `json:"history,omitempty"` // 重试时每次执行的结果
+
+	Findings []Finding    `json:"findings,omitempty"` // 检查类步骤发现的问题
+	Hosts    []HostResult `json:"hosts,omitempty"`    // 在远程主机上执行时各主机最后一次执行的结果
+	Sync     []SyncResult `json:"sync,omitempty"`     // 同步步骤中各目标的同步结果
+}
+
+// SyncResult 定义同步到一个目标的结果
+type SyncResult struct {
+	Target     string `json:"target"`          // 目标名称
+	Type       string `json:"type"`            // local、sftp 或 s3
+	Uploaded   int    `json:"uploaded"`        // 上传的文件数
+	Deleted    int    `json:"deleted"`         // 删除的文件数
+	Unchanged  int    `json:"unchanged"`       // 未变化的文件数
+	Bytes      int64  `json:"bytes"`           // 上传的字节数
+	Error      string `json:"error,omitempty"` // 失败原因
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Finding 描述检查类步骤发现的一个问题，如文章 front-matter 不合法
+type Finding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"` // error 或 warning
+	Message  string `json:"message"`
+}
+
+// Command 定义一次命令执行
+type Command struct {
+	Name    string         // 命令名称，用于日志和并发控制
+	Path    string         // 可执行文件路径
+	Args    []string       // 命令参数
+	Dir     string         // 工作目录
+	Env     []string       // 追加的环境变量
+	Timeout time.Duration  // 超时时间，为 0 时使用执行器的默认超时
+	Sandbox *SandboxConfig // 替换执行器的限制，为 nil 时使用执行器的限制
 }
 
 // ScriptExecutor 定义脚本执行器接口
@@ -32,12 +108,27 @@ type ScriptExecutor interface {
 	Execute(event string, payload interface{}) (*ExecutionResult, error)
 }
 
+// CommandRunner 执行单条命令，流水线通过它执行各个步骤
+type CommandRunner interface {
+	Run(command Command) (*ExecutionResult, error)
+}
+
+// Backend 执行器后端，本机执行与容器执行都实现它
+// 部署与预览按 executor.type 选择后端，所有命令执行结束后调用 Close
+type Backend interface {
+	ScriptExecutor
+	CommandRunner
+	Close() error
+}
+
 // ExecutorConfig 定义执行器配置
 type ExecutorConfig struct {
-	ScriptsPath   string        // 脚本所在目录
-	Timeout       time.Duration // 脚本执行超时时间
-	MaxConcurrent int           // 最大并发执行数
-	DefaultEnv    []string      // 默认环境变量
+	ScriptsPath   string         // 脚本所在目录
+	Timeout       time.Duration  // 脚本执行超时时间
+	MaxConcurrent int            // 最大并发执行数
+	DefaultEnv    []string       // 默认环境变量
+	Payload       *Payload       // 触发执行的事件，为 nil 时不导出事件相关的环境变量
+	Sandbox       *SandboxConfig // 在本机执行时的用户、环境变量与资源限制，为 nil 时不限制
 }
 
 // DefaultExecutor 默认的脚本执行器实现
@@ -46,10 +137,17 @@ type DefaultExecutor struct {
 	semaphore  chan struct{}        // 信号量，用于控制并发执行数
 	mu         sync.RWMutex         // 互斥锁，用于保护并发访问
 	executions map[string]*exec.Cmd // 正在执行的脚本映射
+	payload    payloadFiles         // 载荷文件
 }
 
 // NewExecutor 创建新的执行器实例
 func NewExecutor(config ExecutorConfig) ScriptExecutor {
+	return NewDefaultExecutor(config)
+}
+
+// NewDefaultExecutor 创建默认执行器
+// 流水线等需要直接执行命令的场景使用它的 Run 方法
+func NewDefaultExecutor(config ExecutorConfig) *DefaultExecutor {
 	// 确保配置合理
 	if config.MaxConcurrent <= 0 {
 		config.MaxConcurrent = 5 // 默认最大并发数
@@ -69,17 +167,41 @@ func NewExecutor(config ExecutorConfig) ScriptExecutor {
 // event: 触发事件的类型（如 push, release 等）
 // payload: 事件的详细信息，会转换为环境变量传递给脚本
 func (e *DefaultExecutor) Execute(event string, payload interface{}) (*ExecutionResult, error) {
+	command, err := scriptCommand(e.config.ScriptsPath, event)
+	if err != nil {
+		return nil, err
+	}
+	return e.Run(command)
+}
+
+// scriptCommand 返回执行 scripts.path 中某个脚本的命令
+func scriptCommand(scriptsPath, event string) (Command, error) {
 	// 构建脚本路径
-	scriptPath := filepath.Join(e.config.ScriptsPath, event)
+	scriptPath := filepath.Join(scriptsPath, event)
 
 	// 检查脚本是否存在
 	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("脚本不存在: %s", event)
+		return Command{}, fmt.Errorf("脚本不存在: %s", event)
 	}
 
+	return Command{
+		Name: event,
+		Path: "/bin/bash",
+		Args: []string{scriptPath},
+		Dir:  scriptsPath,
+	}, nil
+}
+
+// Close 删除执行器写入的载荷文件，所有命令执行结束后调用
+func (e *DefaultExecutor) Close() error {
+	return e.payload.close()
+}
+
+// Run 执行一条命令，实时记录输出并返回执行结果
+func (e *DefaultExecutor) Run(command Command) (*ExecutionResult, error) {
 	scriptLogger := logger.WithFields(logrus.Fields{
-		"脚本": event,
-		"路径": scriptPath,
+		"脚本": command.Name,
+		"命令": strings.TrimSpace(command.Path + " " + strings.Join(command.Args, " ")),
 	})
 
 	scriptLogger.Info("准备执行脚本")
@@ -88,46 +210,79 @@ func (e *DefaultExecutor) Execute(event string, payload interface{}) (*Execution
 	e.semaphore <- struct{}{}        // 占用一个并发槽
 	defer func() { <-e.semaphore }() // 释放并发槽
 
+	timeout := command.Timeout
+	if timeout <= 0 {
+		timeout = e.config.Timeout
+	}
+
 	// 创建带超时的上下文
-	ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	// 准备命令
-	cmd := exec.CommandContext(ctx, "/bin/bash", scriptPath)
-
-	// 设置工作目录
-	cmd.Dir = e.config.ScriptsPath
+	// 准备命令，需要切换用户或施加限制时通过辅助进程执行
+	sandbox := command.Sandbox
+	if sandbox == nil {
+		sandbox = e.config.Sandbox
+	}
+	isolated := sandbox != nil && sandbox.isolated()
+	var account *sandboxUser
+	if isolated {
+		var err error
+		if account, err = sandbox.resolve(); err != nil {
+			scriptLogger.WithError(err).Error("准备沙箱失败")
+			return nil, fmt.Errorf("准备沙箱失败: %v", err)
+		}
+	}
 
 	// 设置环境变量
-	env := os.Environ()
+	env := sandbox.env(account)
 	if len(e.config.DefaultEnv) > 0 {
 		env = append(env, e.config.DefaultEnv...)
 	}
+	env = append(env, e.payload.prepare(e.config.Payload)...)
+	env = append(env, command.Env...)
+
+	var cmd *exec.Cmd
+	if isolated {
+		var err error
+		if cmd, err = sandbox.command(ctx, command, account, env); err != nil {
+			scriptLogger.WithError(err).Error("准备沙箱失败")
+			return nil, fmt.Errorf("准备沙箱失败: %v", err)
+		}
+	} else {
+		cmd = exec.CommandContext(ctx, command.Path, command.Args...)
+		// 设置工作目录
+		cmd.Dir = command.Dir
+	}
 	cmd.Env = env
+	if account != nil && account.uid != nil {
+		// 载荷文件只有服务用户可以读取，交给执行脚本的用户
+		if err := e.payload.chown(*account.uid, *account.gid); err != nil {
+			scriptLogger.WithError(err).Warn("修改载荷文件属主失败")
+		}
+	}
 
 	// 创建管道用于实时获取输出
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("无法创建输出管道: %v", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("无法创建错误输出管道: %v", err)
-	}
+	// 使用 io.Pipe 而不是 StdoutPipe，这样即使脚本遗留的后台进程仍持有输出，
+	// 也能在脚本退出后由我们主动关闭管道，避免读取协程永久阻塞
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	cmd.WaitDelay = 5 * time.Second
 
 	// 创建多路复用的输出
-	var outputBuffer bytes.Buffer
-	var logs []string
+	out := &output{}
 
 	// 记录正在执行的命令
 	e.mu.Lock()
-	e.executions[event] = cmd
+	e.executions[command.Name] = cmd
 	e.mu.Unlock()
 
 	// 清理函数
 	defer func() {
 		e.mu.Lock()
-		delete(e.executions, event)
+		delete(e.executions, command.Name)
 		e.mu.Unlock()
 	}()
 
@@ -137,6 +292,8 @@ func (e *DefaultExecutor) Execute(event string, payload interface{}) (*Execution
 
 	// 启动命令
 	if err := cmd.Start(); err != nil {
+		stdoutWriter.Close()
+		stderrWriter.Close()
 		scriptLogger.WithError(err).Error("启动脚本失败")
 		return nil, fmt.Errorf("启动脚本失败: %v", err)
 	}
@@ -144,80 +301,35 @@ func (e *DefaultExecutor) Execute(event string, payload interface{}) (*Execution
 	// 创建等待组
 	var wg sync.WaitGroup
 	wg.Add(2)
-
-	// 处理标准输出
 	go func() {
 		defer wg.Done()
-		reader := bufio.NewReader(stdout)
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					// 只在非EOF错误时记录日志
-					scriptLogger.WithError(err).Error("读取输出失败")
-				}
-				break
-			}
-			line = strings.TrimSpace(line)
-			if line != "" {
-				// 直接输出脚本内容，不添加额外标记
-				logger.Debug(line)
-				logs = append(logs, line)
-				outputBuffer.WriteString(line + "\n")
-			}
-		}
+		out.read(stdout, false, scriptLogger)
 	}()
-
-	// 处理错误输出
 	go func() {
 		defer wg.Done()
-		reader := bufio.NewReader(stderr)
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					// 只在非EOF错误时记录日志
-					scriptLogger.WithError(err).Error("读取错误输出失败")
-				}
-				break
-			}
-			line = strings.TrimSpace(line)
-			if line != "" {
-				// 判断是否为明确的错误信息
-				isError := strings.Contains(line, "error:") ||
-					strings.Contains(line, "fatal:") ||
-					strings.Contains(line, "错误：") ||
-					strings.Contains(line, "failed") ||
-					strings.Contains(line, "失败")
-
-				if isError {
-					// 明确的错误信息使用Warn级别
-					logger.Warn(line)
-				} else {
-					// 其他所有输出使用Debug级别，包括git的正常输出
-					logger.Debug(line)
-				}
-
-				logs = append(logs, line)
-				outputBuffer.WriteString(line + "\n")
-			}
-		}
+		out.read(stderr, true, scriptLogger)
 	}()
 
-	// 等待命令完成
-	err = cmd.Wait()
-
-	// 等待所有输出处理完成
+	// 等待命令完成，随后关闭管道并等待所有输出处理完成
+	err := cmd.Wait()
+	stdoutWriter.Close()
+	stderrWriter.Close()
 	wg.Wait()
 
+	// 脚本本身已正常退出，只是遗留的后台进程仍持有输出，不视为失败
+	if errors.Is(err, exec.ErrWaitDelay) {
+		scriptLogger.Warn("脚本退出后仍有后台进程持有输出，已停止读取")
+		err = nil
+	}
+
 	// 执行结束时间
 	endTime := time.Now()
 
 	// 准备执行结果
 	result := &ExecutionResult{
-		Output:   outputBuffer.String(),
+		Output:   out.buffer.String(),
 		ExitCode: 0,
-		Logs:     logs,
+		Logs:     out.logs,
 	}
 
 	// 处理执行错误
@@ -288,3 +400,53 @@ func (e *DefaultExecutor) StopAll() {
 		}
 	}
 }
+
+// output 收集命令的标准输出与错误输出
+type output struct {
+	mu     sync.Mutex // 标准输出与错误输出在两个协程中并发写入
+	prefix string     // 每行输出的前缀，在多台主机上执行时为主机名称
+	buffer bytes.Buffer
+	logs   []string
+}
+
+// read 逐行读取输出并记录日志，直到 r 关闭
+func (o *output) read(r io.Reader, stderr bool, scriptLogger *logrus.Entry) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err != io.EOF {
+				// 只在非EOF错误时记录日志
+				scriptLogger.WithError(err).Error("读取输出失败")
+			}
+			return
+		}
+		// 脚本可能输出令牌等密钥，在记录日志与保存输出之前替换
+		line = redact.String(strings.TrimSpace(line))
+		if line != "" {
+			line = o.prefix + line
+			// 判断是否为明确的错误信息
+			isError := stderr && (strings.Contains(line, "error:") ||
+				strings.Contains(line, "fatal:") ||
+				strings.Contains(line, "错误：") ||
+				strings.Contains(line, "failed") ||
+				strings.Contains(line, "失败"))
+
+			if isError {
+				// 明确的错误信息使用Warn级别
+				logger.Warn(line)
+			} else {
+				// 其他所有输出使用Debug级别，包括git的正常输出
+				logger.Debug(line)
+			}
+
+			o.mu.Lock()
+			o.logs = append(o.logs, line)
+			o.buffer.WriteString(line + "\n")
+			o.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
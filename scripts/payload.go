@@ -0,0 +1,166 @@
+package scripts
+
+import (
+	"Hexo-AutoCD/logger"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Payload 描述触发本次执行的事件
+// 执行器把原始载荷与解析后的事件写入临时文件，并把常用字段导出为环境变量
+type Payload struct {
+	Event      string      // 事件类型，如 push、release
+	DeliveryID string      // 投递ID，本地执行时为空
+	RunID      string      // 部署运行ID
+	Site       string      // 站点名
+	Repo       string      // 仓库全名，如 owner/blog
+	Ref        string      // 完整的 ref，如 refs/heads/main、refs/tags/v1.0.0
+	Branch     string      // 分支名，标签触发时为空
+	Before     string      // 推送前的提交
+	After      string      // 推送后的提交
+	Pusher     string      // 推送者或触发事件的用户
+	Raw        []byte      // 原始载荷
+	Normalized interface{} // 解析并补全后的事件
+
+	Added    []string // 新增的文件
+	Modified []string // 修改的文件
+	Removed  []string // 删除的文件
+}
+
+// env 返回标准环境变量
+func (p *Payload) env() []string {
+	return []string{
+		"EVENT=" + p.Event,
+		"DELIVERY_ID=" + p.DeliveryID,
+		"RUN_ID=" + p.RunID,
+		"SITE=" + p.Site,
+		"REPO=" + p.Repo,
+		"REF=" + p.Ref,
+		"BRANCH=" + p.Branch,
+		"BEFORE=" + p.Before,
+		"AFTER=" + p.After,
+		"PUSHER=" + p.Pusher,
+	}
+}
+
+// write 把载荷与变更文件列表写入 dir，返回指向这些文件的环境变量
+// 文件列表分别以换行与 NUL 分隔，文件名中含有逗号或换行时使用后者，如 xargs -0 -a "$CHANGED_FILES_NUL_FILE"
+func (p *Payload) write(dir string) ([]string, error) {
+	raw := json.RawMessage("null")
+	if json.Valid(p.Raw) {
+		raw = p.Raw
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"event":       p.Event,
+		"delivery_id": p.DeliveryID,
+		"run_id":      p.RunID,
+		"site":        p.Site,
+		"raw":         raw,
+		"normalized":  p.Normalized,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, "payload.json")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return nil, err
+	}
+	env := []string{"WEBHOOK_PAYLOAD_FILE=" + file}
+
+	var changed []string
+	seen := make(map[string]bool)
+	for _, files := range [][]string{p.Added, p.Modified, p.Removed} {
+		for _, f := range files {
+			if !seen[f] {
+				seen[f] = true
+				changed = append(changed, f)
+			}
+		}
+	}
+	lists := []struct {
+		name  string
+		files []string
+	}{
+		{"CHANGED", changed},
+		{"ADDED", p.Added},
+		{"MODIFIED", p.Modified},
+		{"REMOVED", p.Removed},
+	}
+	for _, list := range lists {
+		base := filepath.Join(dir, strings.ToLower(list.name))
+		if err := os.WriteFile(base+".txt", []byte(join(list.files, "\n")), 0600); err != nil {
+			return env, err
+		}
+		if err := os.WriteFile(base+".nul", []byte(join(list.files, "\x00")), 0600); err != nil {
+			return env, err
+		}
+		env = append(env,
+			list.name+"_FILES_FILE="+base+".txt",
+			list.name+"_FILES_NUL_FILE="+base+".nul",
+		)
+	}
+	return env, nil
+}
+
+// join 连接文件名，每个文件名后都带分隔符，与 find -print0 的输出格式一致
+func join(files []string, sep string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return strings.Join(files, sep) + sep
+}
+
+// payloadFiles 管理执行器写入的载荷文件
+type payloadFiles struct {
+	once sync.Once // 载荷文件只在第一次执行命令时写入
+	dir  string    // 载荷文件所在的临时目录
+	env  []string  // 载荷相关的环境变量
+}
+
+// prepare 写入载荷文件并返回事件相关的环境变量，同一个执行器中的所有命令共用这些文件
+// 写入失败时只导出标准环境变量，不影响脚本执行
+func (f *payloadFiles) prepare(p *Payload) []string {
+	if p == nil {
+		return nil
+	}
+	f.once.Do(func() {
+		f.env = p.env()
+		dir, err := os.MkdirTemp("", "hexo-autocd-payload-")
+		if err != nil {
+			logger.WithError(err).Warn("创建载荷目录失败")
+			return
+		}
+		f.dir = dir
+		env, err := p.write(dir)
+		if err != nil {
+			logger.WithError(err).Warn("写入载荷文件失败")
+		}
+		f.env = append(f.env, env...)
+	})
+	return f.env
+}
+
+// chown 修改载荷目录与文件的属主，切换用户执行时脚本才能读取
+func (f *payloadFiles) chown(uid, gid uint32) error {
+	if f.dir == "" {
+		return nil
+	}
+	return filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, int(uid), int(gid))
+	})
+}
+
+// close 删除载荷文件
+func (f *payloadFiles) close() error {
+	if f.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(f.dir)
+}
@@ -0,0 +1,149 @@
+package scripts
+
+import (
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy 定义失败后的重试策略
+type RetryPolicy struct {
+	Retries    int              // 失败后最多重试的次数
+	Delay      time.Duration    // 第一次重试前的等待时间
+	MaxDelay   time.Duration    // 等待时间上限，为 0 时不限制
+	Multiplier float64          // 每次重试等待时间的倍数，1 为固定间隔
+	Jitter     float64          // 随机抖动比例，0.2 表示在 ±20% 内浮动
+	ExitCodes  []int            // 只在这些退出码时重试
+	Patterns   []*regexp.Regexp // 只在输出或错误信息匹配任一正则表达式时重试
+
+	Classify func(Outcome) bool // 不为 nil 时替代 ExitCodes 与 Patterns 判断失败是否可以重试
+}
+
+// Attempt 定义一次执行的结果，重试时每次执行都单独记录
+type Attempt struct {
+	Attempt    int    `json:"attempt"`            // 第几次执行
+	ExitCode   int    `json:"exit_code"`          // 退出码
+	Error      string `json:"error,omitempty"`    // 失败原因
+	Output     string `json:"output,omitempty"`   // 输出，最后一次执行的输出见步骤的 output
+	StartedAt  string `json:"started_at"`         // 开始时间
+	DurationMs int64  `json:"duration_ms"`        // 耗时（毫秒）
+	DelayMs    int64  `json:"delay_ms,omitempty"` // 执行前等待的时间（毫秒）
+}
+
+// Backoff 返回第 n 次重试前的等待时间，n 从 1 开始，加上随机抖动后仍不超过 MaxDelay
+func (p *RetryPolicy) Backoff(n int) time.Duration {
+	multiplier := math.Max(p.Multiplier, 1)
+	d := float64(p.Delay) * math.Pow(multiplier, float64(n-1))
+	if p.Jitter > 0 {
+		// 多个部署同时失败时错开重试的时间
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	return time.Duration(d)
+}
+
+// Matches 返回输出或错误信息是否匹配任一正则表达式
+func (p *RetryPolicy) Matches(output string) bool {
+	for _, re := range p.Patterns {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retryable 返回一次失败是否可以重试
+// 设置了 Classify 时由它判断；未设置退出码与正则表达式时任何失败都可以重试，
+// 否则退出码在 ExitCodes 中或输出与错误信息匹配任一正则表达式时才重试
+func (p *RetryPolicy) Retryable(outcome Outcome) bool {
+	if p.Classify != nil {
+		return p.Classify(outcome)
+	}
+	if len(p.ExitCodes) == 0 && len(p.Patterns) == 0 {
+		return true
+	}
+	for _, code := range p.ExitCodes {
+		if code == outcome.ExitCode {
+			return true
+		}
+	}
+	return p.Matches(outcome.Output + "\n" + outcome.message())
+}
+
+// Outcome 定义一次执行的结果，用于判断是否成功、是否可以重试并记录在 Attempt 中
+type Outcome struct {
+	ExitCode int    // 退出码，无法执行时为 -1
+	Output   string // 输出
+	Error    string // 命令报告的失败原因
+	Err      error  // 无法执行或执行出错时的错误
+}
+
+// failed 返回这次执行是否失败
+func (o Outcome) failed() bool {
+	return o.Err != nil || o.ExitCode != 0 || o.Error != ""
+}
+
+// message 返回失败原因
+func (o Outcome) message() string {
+	if o.Err != nil {
+		return o.Err.Error()
+	}
+	return o.Error
+}
+
+// Retry 执行 run，失败且可以重试时按策略等待后再次执行，policy 为 nil 时只执行一次
+// 返回最后一次执行的结果与每次执行的记录，最后一次执行的输出不重复记录
+func Retry[T any](policy *RetryPolicy, log *logrus.Entry, run func() (T, Outcome)) (T, []Attempt) {
+	var attempts []Attempt
+	for n := 1; ; n++ {
+		var delay time.Duration
+		if n > 1 {
+			delay = policy.Backoff(n - 1)
+			log.WithFields(logrus.Fields{
+				"第几次": n,
+				"等待":  delay.Round(time.Millisecond).String(),
+			}).Warn("执行失败，准备重试")
+			time.Sleep(delay)
+		}
+
+		startTime := time.Now()
+		result, outcome := run()
+		attempts = append(attempts, Attempt{
+			Attempt:    n,
+			ExitCode:   outcome.ExitCode,
+			Error:      outcome.message(),
+			Output:     outcome.Output,
+			StartedAt:  startTime.Format(time.RFC3339),
+			DurationMs: time.Since(startTime).Milliseconds(),
+			DelayMs:    delay.Milliseconds(),
+		})
+
+		if outcome.failed() && policy != nil && n <= policy.Retries {
+			if policy.Retryable(outcome) {
+				continue
+			}
+			log.WithField("退出码", outcome.ExitCode).Info("失败不满足重试条件，不再重试")
+		}
+		attempts[len(attempts)-1].Output = ""
+		return result, attempts
+	}
+}
+
+// RetryExecution 按策略重试 run，run 返回错误时视为退出码为 -1 的失败
+func RetryExecution(policy *RetryPolicy, log *logrus.Entry, run func() (*ExecutionResult, error)) (*ExecutionResult, []Attempt, error) {
+	var err error
+	result, attempts := Retry(policy, log, func() (*ExecutionResult, Outcome) {
+		var result *ExecutionResult
+		result, err = run()
+		if err != nil {
+			return nil, Outcome{ExitCode: -1, Err: err}
+		}
+		return result, Outcome{ExitCode: result.ExitCode, Output: result.Output, Error: result.Error}
+	})
+	return result, attempts, err
+}
@@ -0,0 +1,84 @@
+package scripts
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// discard 丢弃输出的日志
+func discard() *logrus.Entry {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return logrus.NewEntry(log)
+}
+
+func TestBackoff(t *testing.T) {
+	p := &RetryPolicy{Delay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := p.Backoff(n); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", n, got, want)
+		}
+	}
+
+	// 加上抖动后不超过上限
+	p.Jitter = 0.5
+	for i := 0; i < 200; i++ {
+		if got := p.Backoff(10); got > p.MaxDelay || got < p.MaxDelay/2 {
+			t.Fatalf("Backoff(10) with jitter = %s, want within [%s, %s]", got, p.MaxDelay/2, p.MaxDelay)
+		}
+		if got := p.Backoff(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Backoff(1) with jitter = %s", got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *RetryPolicy
+		outcomes []Outcome
+		want     int
+	}{
+		{"no policy", nil, []Outcome{{ExitCode: 1}}, 1},
+		{"success", &RetryPolicy{Retries: 3}, []Outcome{{}}, 1},
+		{"any failure", &RetryPolicy{Retries: 3}, []Outcome{{ExitCode: 1}, {Err: errors.New("x"), ExitCode: -1}, {}}, 3},
+		{"retries exhausted", &RetryPolicy{Retries: 2}, []Outcome{{ExitCode: 1}, {ExitCode: 1}, {ExitCode: 1}, {}}, 3},
+		{"exit code", &RetryPolicy{Retries: 3, ExitCodes: []int{75}}, []Outcome{{ExitCode: 75}, {ExitCode: 1}, {}}, 2},
+		{"pattern in error", &RetryPolicy{Retries: 3, Patterns: []*regexp.Regexp{regexp.MustCompile("ETIMEDOUT")}},
+			[]Outcome{{ExitCode: 1, Error: "connect ETIMEDOUT"}, {ExitCode: 1, Output: "other"}, {}}, 2},
+		{"classify", &RetryPolicy{Retries: 3, ExitCodes: []int{1}, Classify: func(o Outcome) bool { return o.Err != nil }},
+			[]Outcome{{ExitCode: -1, Err: errors.New("network")}, {ExitCode: 1}, {}}, 2},
+	}
+	for _, tt := range tests {
+		n := 0
+		got, attempts := Retry(tt.policy, discard(), func() (int, Outcome) {
+			n++
+			return n, tt.outcomes[n-1]
+		})
+		if got != tt.want || len(attempts) != tt.want {
+			t.Errorf("%s: ran %d times with %d attempts, want %d", tt.name, got, len(attempts), tt.want)
+		}
+	}
+}
+
+func TestRetryExecution(t *testing.T) {
+	runs := 0
+	result, attempts, err := RetryExecution(&RetryPolicy{Retries: 1}, discard(), func() (*ExecutionResult, error) {
+		runs++
+		if runs == 1 {
+			return nil, errors.New("executor unavailable")
+		}
+		return &ExecutionResult{Output: "ok"}, nil
+	})
+	if err != nil || result == nil || result.Output != "ok" {
+		t.Fatalf("RetryExecution() = %+v, %v", result, err)
+	}
+	if len(attempts) != 2 || attempts[0].ExitCode != -1 || attempts[0].Error != "executor unavailable" || attempts[1].Output != "" {
+		t.Errorf("attempts = %+v", attempts)
+	}
+}
@@ -0,0 +1,215 @@
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SandboxCommand 辅助进程的子命令
+// 需要切换用户或施加限制时，执行器以该子命令启动服务自身，辅助进程施加限制后再执行脚本
+const SandboxCommand = "__sandbox"
+
+// defaultPath env 为 clean 且服务没有 PATH 时使用的 PATH
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// SandboxConfig 定义在本机执行命令时的限制
+type SandboxConfig struct {
+	User      string        // 执行命令的用户，用户名或 UID
+	Group     string        // 执行命令的用户组，默认为用户的主组
+	CleanEnv  bool          // 不继承服务的环境变量，只保留 PATH、LANG 等基本变量与 EnvAllow 中的变量
+	EnvAllow  []string      // CleanEnv 时额外保留的环境变量，支持通配符
+	CPU       time.Duration // CPU 时间上限
+	Memory    int64         // 数据段内存上限（字节）
+	Files     int           // 同时打开的文件数上限
+	Processes int           // 用户的进程数上限
+	Chroot    string        // 切换的根目录
+	Landlock  bool          // 使用 Landlock 限制可写入的目录
+	Writable  []string      // 启用 Landlock 时额外允许写入的目录
+}
+
+// isolated 返回是否需要通过辅助进程执行
+func (s *SandboxConfig) isolated() bool {
+	return s.User != "" || s.Group != "" || s.CPU > 0 || s.Memory > 0 ||
+		s.Files > 0 || s.Processes > 0 || s.Chroot != "" || s.Landlock
+}
+
+// sandboxSpec 传给辅助进程的限制，用户与用户组已在服务进程中解析为数字 ID
+type sandboxSpec struct {
+	UID       *uint32  `json:"uid,omitempty"`
+	GID       *uint32  `json:"gid,omitempty"`
+	Groups    []uint32 `json:"groups,omitempty"`
+	CPU       uint64   `json:"cpu,omitempty"` // 秒
+	Memory    uint64   `json:"memory,omitempty"`
+	Files     uint64   `json:"files,omitempty"`
+	Processes uint64   `json:"processes,omitempty"`
+	Chroot    string   `json:"chroot,omitempty"`
+	Dir       string   `json:"dir,omitempty"` // 工作目录，chroot 后切换
+	Landlock  bool     `json:"landlock,omitempty"`
+	Writable  []string `json:"writable,omitempty"`
+}
+
+// sandboxUser 解析后的用户
+type sandboxUser struct {
+	uid, gid *uint32
+	groups   []uint32
+	account  *user.User // 设置了 User 时的账户信息，用于 HOME 等环境变量
+}
+
+// resolve 把用户与用户组解析为数字 ID
+func (s *SandboxConfig) resolve() (*sandboxUser, error) {
+	result := &sandboxUser{}
+	if s.User != "" {
+		u, err := user.Lookup(s.User)
+		if err != nil {
+			if u, err = user.LookupId(s.User); err != nil {
+				return nil, fmt.Errorf("用户不存在: %s", s.User)
+			}
+		}
+		uid, err := parseID(u.Uid)
+		if err != nil {
+			return nil, err
+		}
+		gid, err := parseID(u.Gid)
+		if err != nil {
+			return nil, err
+		}
+		result.uid, result.gid, result.account = &uid, &gid, u
+		if ids, err := u.GroupIds(); err == nil {
+			for _, id := range ids {
+				if gid, err := parseID(id); err == nil {
+					result.groups = append(result.groups, gid)
+				}
+			}
+		}
+	}
+	if s.Group != "" {
+		g, err := user.LookupGroup(s.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(s.Group); err != nil {
+				return nil, fmt.Errorf("用户组不存在: %s", s.Group)
+			}
+		}
+		gid, err := parseID(g.Gid)
+		if err != nil {
+			return nil, err
+		}
+		result.gid = &gid
+		if s.User == "" {
+			result.groups = []uint32{gid}
+		}
+	}
+	if result.gid != nil && len(result.groups) == 0 {
+		result.groups = []uint32{*result.gid}
+	}
+	return result, nil
+}
+
+func parseID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("不是数字 ID: %s", id)
+	}
+	return uint32(n), nil
+}
+
+// env 按策略返回命令的基础环境变量，切换用户时 HOME、USER 与 LOGNAME 指向该用户
+func (s *SandboxConfig) env(u *sandboxUser) []string {
+	var env []string
+	if s == nil || !s.CleanEnv {
+		env = os.Environ()
+	} else {
+		for _, kv := range os.Environ() {
+			if key, _, _ := strings.Cut(kv, "="); s.keep(key) {
+				env = append(env, kv)
+			}
+		}
+		if os.Getenv("PATH") == "" {
+			env = append(env, "PATH="+defaultPath)
+		}
+	}
+	if u == nil || u.account == nil {
+		return env
+	}
+	filtered := env[:0:0]
+	for _, kv := range env {
+		switch key, _, _ := strings.Cut(kv, "="); key {
+		case "HOME", "USER", "LOGNAME":
+		default:
+			filtered = append(filtered, kv)
+		}
+	}
+	return append(filtered,
+		"HOME="+u.account.HomeDir,
+		"USER="+u.account.Username,
+		"LOGNAME="+u.account.Username,
+	)
+}
+
+// keep 返回 CleanEnv 时是否保留某个环境变量
+func (s *SandboxConfig) keep(key string) bool {
+	switch key {
+	case "PATH", "LANG", "LANGUAGE", "TZ", "HOME", "USER", "LOGNAME":
+		return true
+	}
+	if strings.HasPrefix(key, "LC_") {
+		return true
+	}
+	for _, pattern := range s.EnvAllow {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// command 返回在辅助进程中执行 command 的命令，辅助进程施加限制后以相同的参数执行原命令
+// env 为命令的环境变量，其中的 RELEASE_DIR 与 PREVIEW_DIR 在启用 Landlock 时可以写入
+func (s *SandboxConfig) command(ctx context.Context, command Command, u *sandboxUser, env []string) (*exec.Cmd, error) {
+	if !sandboxSupported {
+		return nil, fmt.Errorf("当前系统不支持 scripts.sandbox 中的用户、资源限制、chroot 与 Landlock")
+	}
+	spec := sandboxSpec{
+		UID:       u.uid,
+		GID:       u.gid,
+		Groups:    u.groups,
+		Memory:    uint64(s.Memory),
+		Files:     uint64(s.Files),
+		Processes: uint64(s.Processes),
+		Chroot:    s.Chroot,
+		Dir:       command.Dir,
+		Landlock:  s.Landlock,
+	}
+	if s.CPU > 0 {
+		// CPU 时间以秒为单位，不足一秒按一秒计算
+		spec.CPU = uint64((s.CPU + time.Second - 1) / time.Second)
+	}
+	if s.Landlock {
+		// 工作目录与临时目录总是可以写入，/dev/null 常被用来丢弃输出
+		spec.Writable = append([]string{command.Dir, os.TempDir(), "/dev/null"}, s.Writable...)
+		// 步骤需要把生成的站点写入本次发布或预览的目录，这些目录每次部署都不同，无法写在 writable 中
+		for _, kv := range env {
+			if key, value, ok := strings.Cut(kv, "="); ok && value != "" && (key == "RELEASE_DIR" || key == "PREVIEW_DIR") {
+				spec.Writable = append(spec.Writable, value)
+			}
+		}
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{SandboxCommand, string(data), command.Path}, command.Args...)
+	cmd := exec.CommandContext(ctx, sandboxExecutable, args...)
+	if s.Chroot == "" {
+		cmd.Dir = command.Dir
+	}
+	return cmd, nil
+}
@@ -0,0 +1,197 @@
+//go:build linux
+
+package scripts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxSupported 当前系统是否支持通过辅助进程施加限制
+const sandboxSupported = true
+
+// sandboxExecutable 辅助进程的可执行文件
+// 使用 /proc/self/exe 而不是可执行文件路径，服务升级替换了文件后仍然执行当前版本
+const sandboxExecutable = "/proc/self/exe"
+
+// SandboxMain 辅助进程的入口，参数为限制与要执行的命令
+// 依次设置资源限制、chroot、Landlock 并切换用户，然后以当前进程执行命令；失败时返回退出码 126
+func SandboxMain(args []string) int {
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "沙箱: 缺少参数")
+		return 126
+	}
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(args[0]), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "沙箱: 解析参数失败: %v\n", err)
+		return 126
+	}
+
+	// 切换用户之前的步骤都作用于当前线程
+	runtime.LockOSThread()
+	if err := spec.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "沙箱: %v\n", err)
+		return 126
+	}
+	err := syscall.Exec(args[1], args[1:], os.Environ())
+	fmt.Fprintf(os.Stderr, "沙箱: 执行 %s 失败: %v\n", args[1], err)
+	return 126
+}
+
+// apply 在当前进程上施加限制
+func (s *sandboxSpec) apply() error {
+	// 配置校验已拒绝这种组合，这里再检查一次，避免可写路径按 chroot 后的根目录解析
+	if s.Chroot != "" && s.Landlock {
+		return fmt.Errorf("Landlock 不能与 chroot 同时使用")
+	}
+
+	limits := []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"cpu", unix.RLIMIT_CPU, s.CPU},
+		{"memory", unix.RLIMIT_DATA, s.Memory},
+		{"files", unix.RLIMIT_NOFILE, s.Files},
+		{"processes", unix.RLIMIT_NPROC, s.Processes},
+	}
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+		rlimit := syscall.Rlimit{Cur: limit.value, Max: limit.value}
+		if limit.resource == unix.RLIMIT_CPU {
+			// 软上限先发送 SIGXCPU，错误信息为 CPU time limit exceeded，一秒后仍未退出再由硬上限终止
+			rlimit.Max++
+		}
+		// 使用 syscall.Setrlimit，Go 运行时不会在执行命令时恢复原来的打开文件数上限
+		if err := syscall.Setrlimit(limit.resource, &rlimit); err != nil {
+			return fmt.Errorf("设置 %s 上限失败: %v", limit.name, err)
+		}
+	}
+
+	if s.Chroot != "" {
+		if err := syscall.Chroot(s.Chroot); err != nil {
+			return fmt.Errorf("chroot 到 %s 失败: %v", s.Chroot, err)
+		}
+		dir := s.Dir
+		if dir == "" {
+			dir = "/"
+		}
+		if err := syscall.Chdir(dir); err != nil {
+			return fmt.Errorf("切换到工作目录 %s 失败: %v", dir, err)
+		}
+	}
+
+	if s.Landlock {
+		if err := restrictFilesystem(s.Writable); err != nil {
+			return err
+		}
+	}
+
+	if s.GID != nil {
+		groups := make([]int, 0, len(s.Groups))
+		for _, g := range s.Groups {
+			groups = append(groups, int(g))
+		}
+		if err := syscall.Setgroups(groups); err != nil {
+			return fmt.Errorf("设置附加组失败: %v", err)
+		}
+		if err := syscall.Setgid(int(*s.GID)); err != nil {
+			return fmt.Errorf("切换用户组失败: %v", err)
+		}
+	}
+	if s.UID != nil {
+		if err := syscall.Setuid(int(*s.UID)); err != nil {
+			return fmt.Errorf("切换用户失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// landlockFileAccess 可以用于单个文件的权限
+const landlockFileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+	unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+
+// restrictFilesystem 使用 Landlock 限制文件系统访问：整个文件系统只读，writable 中的路径可以写入
+// 内核不支持 Landlock 时返回错误，不在没有限制的情况下执行脚本
+func restrictFilesystem(writable []string) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("内核不支持 Landlock: %v", errno)
+	}
+	// 按内核支持的版本处理所有文件系统权限，未处理的权限不受限制
+	handled := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		handled |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("创建 Landlock 规则集失败: %v", errno)
+	}
+	defer unix.Close(int(fd))
+
+	readOnly := uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR)
+	if err := addLandlockRule(int(fd), "/", readOnly); err != nil {
+		return err
+	}
+	for _, dir := range writable {
+		if dir == "" {
+			continue
+		}
+		if err := addLandlockRule(int(fd), dir, handled); err != nil {
+			// 不存在的路径不需要放开写入，但输出到步骤的输出中，便于发现写错的路径
+			if errors.Is(err, unix.ENOENT) {
+				fmt.Fprintf(os.Stderr, "沙箱: 可写路径不存在，已跳过: %s\n", dir)
+				continue
+			}
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("设置 no_new_privs 失败: %v", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("启用 Landlock 失败: %v", errno)
+	}
+	return nil
+}
+
+// addLandlockRule 允许对 path 及其下的所有文件进行 access 中的操作，path 为文件时只保留文件适用的权限
+func addLandlockRule(rulesetFd int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= landlockFileAccess
+	}
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("添加 Landlock 规则 %s 失败: %v", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build linux
+
+package scripts
+
+import "testing"
+
+func TestSandboxRejectsChrootWithLandlock(t *testing.T) {
+	// 在施加任何限制之前返回错误，测试进程本身不受影响
+	spec := sandboxSpec{Chroot: t.TempDir(), Landlock: true, Writable: []string{"/home/hexo/blog"}}
+	if err := spec.apply(); err == nil {
+		t.Fatal("apply() with chroot and Landlock error = nil")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package scripts
+
+import (
+	"fmt"
+	"os"
+)
+
+// sandboxSupported 当前系统是否支持通过辅助进程施加限制
+const sandboxSupported = false
+
+// sandboxExecutable 辅助进程的可执行文件，当前系统不使用
+const sandboxExecutable = ""
+
+// SandboxMain 辅助进程的入口，当前系统不支持
+func SandboxMain(args []string) int {
+	fmt.Fprintln(os.Stderr, "沙箱: 当前系统不支持")
+	return 126
+}
@@ -0,0 +1,38 @@
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSandboxWritable(t *testing.T) {
+	if !sandboxSupported {
+		t.Skip("当前系统不支持沙箱")
+	}
+	s := &SandboxConfig{Landlock: true, Writable: []string{"/home/hexo/.npm"}}
+	env := []string{"RELEASE_DIR=/var/lib/hexo-autocd/releases/20240101", "PREVIEW_DIR=", "PUBLIC_DIR=/srv/www"}
+	cmd, err := s.command(context.Background(), Command{Path: "/bin/true", Dir: "/home/hexo/blog"}, &sandboxUser{}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(cmd.Args[2]), &spec); err != nil {
+		t.Fatalf("spec %q: %v", cmd.Args[2], err)
+	}
+	// 设置了的 RELEASE_DIR 可以写入，空的 PREVIEW_DIR 与其他变量不影响
+	want := []string{"/home/hexo/blog", os.TempDir(), "/dev/null", "/home/hexo/.npm", "/var/lib/hexo-autocd/releases/20240101"}
+	if !reflect.DeepEqual(spec.Writable, want) {
+		t.Errorf("Writable = %q, want %q", spec.Writable, want)
+	}
+
+	s.Landlock = false
+	cmd, _ = s.command(context.Background(), Command{Path: "/bin/true"}, &sandboxUser{}, env)
+	spec = sandboxSpec{}
+	json.Unmarshal([]byte(cmd.Args[2]), &spec)
+	if len(spec.Writable) != 0 {
+		t.Errorf("Writable without Landlock = %q", spec.Writable)
+	}
+}
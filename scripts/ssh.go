@@ -0,0 +1,423 @@
+package scripts
+
+import (
+	"Hexo-AutoCD/logger"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig 定义远程执行器
+type SSHConfig struct {
+	Hosts          []SSHHost
+	IdentityFile   string        // 私钥文件
+	Agent          bool          // 是否使用 ssh-agent 认证
+	KnownHosts     string        // known_hosts 文件
+	Parallel       bool          // 为 true 时同时在所有主机上执行，否则逐台执行
+	ConnectTimeout time.Duration // 连接超时时间
+	Env            []string      // 追加的环境变量
+}
+
+// SSHHost 定义一台远程主机
+type SSHHost struct {
+	Name    string // 主机名称
+	Address string // 地址，没有端口时使用 22
+	User    string // 登录用户
+	Dir     string // 远程工作目录，为空时使用命令的工作目录
+}
+
+// SSHExecutor 通过 SSH 在远程主机上执行命令的执行器
+// 同一个执行器中的命令复用到每台主机的连接，Close 时断开
+type SSHExecutor struct {
+	config    ExecutorConfig
+	ssh       SSHConfig
+	client    *ssh.ClientConfig
+	semaphore chan struct{}
+
+	mu    sync.Mutex
+	conns map[string]*ssh.Client // 已建立的连接，按主机名称索引
+	agent net.Conn               // 与 ssh-agent 的连接
+}
+
+// NewSSHExecutor 创建远程执行器，读取私钥与 known_hosts 失败时返回错误
+func NewSSHExecutor(config ExecutorConfig, sshConfig SSHConfig) (*SSHExecutor, error) {
+	if len(sshConfig.Hosts) == 0 {
+		return nil, fmt.Errorf("未配置远程主机")
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 5
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	if sshConfig.ConnectTimeout <= 0 {
+		sshConfig.ConnectTimeout = 10 * time.Second
+	}
+
+	e := &SSHExecutor{
+		config:    config,
+		ssh:       sshConfig,
+		semaphore: make(chan struct{}, config.MaxConcurrent),
+		conns:     make(map[string]*ssh.Client),
+	}
+
+	var auth []ssh.AuthMethod
+	if sshConfig.IdentityFile != "" {
+		key, err := os.ReadFile(sshConfig.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("私钥 %s 设置了密码，请改用 ssh-agent", sshConfig.IdentityFile)
+		} else if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if sshConfig.Agent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, fmt.Errorf("未设置 SSH_AUTH_SOCK，无法使用 ssh-agent")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("连接 ssh-agent 失败: %v", err)
+		}
+		e.agent = conn
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("未配置私钥或 ssh-agent")
+	}
+
+	hostKey, err := knownhosts.New(sshConfig.KnownHosts)
+	if err != nil {
+		e.Close()
+		return nil, fmt.Errorf("读取 known_hosts 失败: %v", err)
+	}
+	e.client = &ssh.ClientConfig{
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         sshConfig.ConnectTimeout,
+	}
+	return e, nil
+}
+
+// Execute 在远程主机上执行指定事件对应的脚本
+// 脚本在本机读取后通过标准输入交给远程的 bash 执行，远程主机上不需要有脚本文件
+func (e *SSHExecutor) Execute(event string, payload interface{}) (*ExecutionResult, error) {
+	command, err := scriptCommand(e.config.ScriptsPath, event)
+	if err != nil {
+		return nil, err
+	}
+	script, err := os.ReadFile(command.Args[0])
+	if err != nil {
+		return nil, fmt.Errorf("读取脚本失败: %v", err)
+	}
+	command.Args = []string{"-s"}
+	command.Dir = "" // 本机的脚本目录在远程主机上通常不存在，使用主机的 dir 或登录目录
+	return e.run(command, script)
+}
+
+// Run 在远程主机上执行一条命令
+func (e *SSHExecutor) Run(command Command) (*ExecutionResult, error) {
+	return e.run(command, nil)
+}
+
+// Close 断开与所有主机及 ssh-agent 的连接
+func (e *SSHExecutor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, conn := range e.conns {
+		conn.Close()
+		delete(e.conns, name)
+	}
+	if e.agent != nil {
+		e.agent.Close()
+		e.agent = nil
+	}
+	return nil
+}
+
+// run 按策略在所有主机上执行命令并汇总结果
+// 逐台执行时某台主机失败后，其余主机标记为跳过；整体的退出码与错误信息取自第一台失败的主机
+func (e *SSHExecutor) run(command Command, stdin []byte) (*ExecutionResult, error) {
+	scriptLogger := logger.WithFields(logrus.Fields{
+		"脚本":  command.Name,
+		"命令":  strings.TrimSpace(command.Path + " " + strings.Join(command.Args, " ")),
+		"主机数": len(e.ssh.Hosts),
+	})
+	scriptLogger.Info("准备在远程主机上执行脚本")
+
+	e.semaphore <- struct{}{}
+	defer func() { <-e.semaphore }()
+
+	hosts := make([]HostResult, len(e.ssh.Hosts))
+	if e.ssh.Parallel {
+		var wg sync.WaitGroup
+		for i, host := range e.ssh.Hosts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				hosts[i] = e.runOnHost(host, command, stdin)
+			}()
+		}
+		wg.Wait()
+	} else {
+		var failed string
+		for i, host := range e.ssh.Hosts {
+			if failed != "" {
+				hosts[i] = HostResult{Host: host.Name, Status: StepSkipped, Error: fmt.Sprintf("主机 %s 失败", failed)}
+				continue
+			}
+			hosts[i] = e.runOnHost(host, command, stdin)
+			if hosts[i].Status == StepFailed {
+				failed = host.Name
+			}
+		}
+	}
+
+	result := &ExecutionResult{Hosts: hosts}
+	for _, host := range hosts {
+		if host.Output != "" {
+			result.Output += host.Output
+		}
+		if host.Status == StepFailed && result.Error == "" {
+			result.ExitCode = host.ExitCode
+			result.Error = fmt.Sprintf("主机 %s 失败: %s", host.Host, host.Error)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(result.Output, "\n"), "\n") {
+		if line != "" {
+			result.Logs = append(result.Logs, line)
+		}
+	}
+	return result, nil
+}
+
+// runOnHost 在一台主机上执行命令，输出的每一行以主机名称为前缀
+func (e *SSHExecutor) runOnHost(host SSHHost, command Command, stdin []byte) (result HostResult) {
+	hostLogger := logger.WithFields(logrus.Fields{
+		"脚本": command.Name,
+		"主机": host.Name,
+	})
+	result = HostResult{Host: host.Name, Status: StepFailed}
+	startTime := time.Now()
+	defer func() {
+		result.DurationMs = time.Since(startTime).Milliseconds()
+	}()
+
+	timeout := command.Timeout
+	if timeout <= 0 {
+		timeout = e.config.Timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := e.connect(ctx, host)
+	if err != nil {
+		hostLogger.WithError(err).Error("连接远程主机失败")
+		result.ExitCode = -1
+		result.Error = err.Error()
+		return result
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		// 连接可能已被远程主机断开，丢弃后下次重新连接
+		e.drop(host.Name, conn)
+		hostLogger.WithError(err).Error("创建 SSH 会话失败")
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("创建 SSH 会话失败: %v", err)
+		return result
+	}
+	defer session.Close()
+
+	out := &output{prefix: "[" + host.Name + "] "}
+	stdout, stdoutWriter := io.Pipe()
+	stderr, stderrWriter := io.Pipe()
+	session.Stdout = stdoutWriter
+	session.Stderr = stderrWriter
+	session.Stdin = bytes.NewReader(e.remoteInput(host, command, stdin))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		out.read(stdout, false, hostLogger)
+	}()
+	go func() {
+		defer wg.Done()
+		out.read(stderr, true, hostLogger)
+	}()
+
+	hostLogger.Info("开始在远程主机上执行脚本")
+	done := make(chan error, 1)
+	if err := session.Start(remoteShell); err != nil {
+		done <- err
+	} else {
+		go func() { done <- session.Wait() }()
+	}
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// 超时后发送 KILL 信号并关闭会话，不是所有 sshd 都支持信号，关闭会话可以保证返回
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		err = ctx.Err()
+	}
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
+	result.Output = out.buffer.String()
+
+	var exitErr *ssh.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Error = "script execution timed out"
+		hostLogger.Error("脚本执行超时")
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+		result.Error = fmt.Sprintf("exit status %d", result.ExitCode)
+		hostLogger.WithField("退出码", result.ExitCode).Warn("脚本执行返回非零退出码")
+	case err != nil:
+		result.ExitCode = -1
+		result.Error = err.Error()
+		hostLogger.WithError(err).Error("脚本执行遇到错误")
+	default:
+		result.Status = StepSuccess
+		hostLogger.WithField("执行时长", time.Since(startTime).String()).Info("脚本执行成功")
+	}
+	return result
+}
+
+// connect 返回到主机的连接，已有连接时复用
+func (e *SSHExecutor) connect(ctx context.Context, host SSHHost) (*ssh.Client, error) {
+	e.mu.Lock()
+	conn := e.conns[host.Name]
+	e.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+
+	address := host.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+	dialer := net.Dialer{Timeout: e.ssh.ConnectTimeout}
+	tcp, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %v", address, err)
+	}
+	clientConfig := *e.client
+	clientConfig.User = host.User
+	// 握手同样受连接超时限制，完成后取消
+	tcp.SetDeadline(time.Now().Add(e.ssh.ConnectTimeout))
+	c, chans, reqs, err := ssh.NewClientConn(tcp, address, &clientConfig)
+	tcp.SetDeadline(time.Time{})
+	if err != nil {
+		tcp.Close()
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return nil, fmt.Errorf("%s 不在 known_hosts 中（可用 ssh-keyscan 添加）", address)
+			}
+			return nil, fmt.Errorf("%s 的主机密钥与 known_hosts 不一致，可能遭到中间人攻击", address)
+		}
+		return nil, fmt.Errorf("SSH 握手失败: %v", err)
+	}
+	conn = ssh.NewClient(c, chans, reqs)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if existing := e.conns[host.Name]; existing != nil {
+		// 并行执行时其他协程已建立连接
+		conn.Close()
+		return existing, nil
+	}
+	e.conns[host.Name] = conn
+	return conn, nil
+}
+
+// drop 丢弃失效的连接
+func (e *SSHExecutor) drop(name string, conn *ssh.Client) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conns[name] == conn {
+		delete(e.conns, name)
+	}
+	conn.Close()
+}
+
+// remoteShell 在远程主机上执行的命令，要执行的内容全部通过标准输入传递
+const remoteShell = "bash -s"
+
+// remoteInput 构造交给远程 bash 从标准输入执行的内容：导出环境变量、进入工作目录，再执行 script 或命令
+// 环境变量中常有令牌，放在命令行中会出现在远程主机的进程列表里，因此同样通过标准输入传递；
+// sshd 默认也不接受客户端设置的环境变量。
+// 全部内容包在 main 函数中，bash 读完整个函数后才开始执行，执行时标准输入为 /dev/null，
+// 脚本中读取标准输入的命令（ssh、read、npm 的提示等）不会读走后面的脚本；
+// 连接中断导致内容不完整时函数无法解析，什么都不会执行
+func (e *SSHExecutor) remoteInput(host SSHHost, command Command, script []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("main() {\n")
+	env := append(append([]string{}, e.config.DefaultEnv...), e.payloadEnv()...)
+	env = append(env, command.Env...)
+	env = append(env, e.ssh.Env...)
+	env = append(env, "HOST_NAME="+host.Name)
+	for _, kv := range env {
+		b.WriteString("export " + ShellQuote(kv) + "\n")
+	}
+
+	dir := host.Dir
+	if dir == "" {
+		dir = command.Dir
+	}
+	if dir != "" {
+		b.WriteString("cd " + ShellQuote(dir) + " || exit 1\n")
+	}
+
+	if script != nil {
+		b.Write(script)
+		if len(script) > 0 && script[len(script)-1] != '\n' {
+			b.WriteString("\n")
+		}
+	} else {
+		b.WriteString("exec " + ShellQuote(command.Path))
+		for _, arg := range command.Args {
+			b.WriteString(" " + ShellQuote(arg))
+		}
+		b.WriteString("\n")
+	}
+	// 函数体中至少有 HOST_NAME 的导出，不会为空；main 的退出码即脚本的退出码
+	b.WriteString("}\nmain </dev/null\n")
+	return b.Bytes()
+}
+
+// payloadEnv 返回事件信息的环境变量
+// 载荷文件只存在于本机，远程主机上只导出标准环境变量
+func (e *SSHExecutor) payloadEnv() []string {
+	if e.config.Payload == nil {
+		return nil
+	}
+	return e.config.Payload.env()
+}
+
+// ShellQuote 用单引号包裹参数，供远程 shell 解析，也用于生成可复制执行的命令
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
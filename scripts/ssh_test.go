@@ -0,0 +1,90 @@
+package scripts
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// runRemoteInput 在本机用 bash -s 执行 remoteInput 的内容，模拟远程主机
+func runRemoteInput(t *testing.T, input []byte) string {
+	t.Helper()
+	cmd := exec.Command("bash", "-s")
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash -s: %v\n%s", err, out)
+	}
+	return string(out)
+}
+
+type remoteExit struct {
+	code   int
+	output string
+}
+
+// runRemoteExit 与 runRemoteInput 相同，但允许非零退出码
+func runRemoteExit(t *testing.T, input []byte) remoteExit {
+	t.Helper()
+	cmd := exec.Command("bash", "-s")
+	cmd.Stdin = bytes.NewReader(input)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return remoteExit{exitErr.ExitCode(), out.String()}
+	}
+	if err != nil {
+		t.Fatalf("bash -s: %v", err)
+	}
+	return remoteExit{0, out.String()}
+}
+
+func TestRemoteInput(t *testing.T) {
+	dir := t.TempDir()
+	e := &SSHExecutor{
+		config: ExecutorConfig{DefaultEnv: []string{"EVENT=push"}},
+		ssh:    SSHConfig{Env: []string{"DEPLOY_TOKEN=it's a \"secret\" $HOME `x`\nline2"}},
+	}
+	host := SSHHost{Name: "web1", Dir: dir}
+
+	// 环境变量与命令都不出现在远程的命令行中
+	command := Command{Path: "/bin/bash", Args: []string{"-c", `printf '%s|%s|%s|%s' "$EVENT" "$HOST_NAME" "$DEPLOY_TOKEN" "$PWD"`}}
+	input := e.remoteInput(host, command, nil)
+	if strings.Contains(remoteShell, "DEPLOY_TOKEN") {
+		t.Fatalf("remote command line contains the environment: %s", remoteShell)
+	}
+	want := "push|web1|it's a \"secret\" $HOME `x`\nline2|" + dir
+	if got := runRemoteInput(t, input); got != want {
+		t.Errorf("command output = %q, want %q\ninput:\n%s", got, want, input)
+	}
+
+	// 脚本内容接在环境变量之后执行
+	script := []byte("#!/bin/bash\necho \"$HOST_NAME $EVENT\"\n")
+	if got := runRemoteInput(t, e.remoteInput(host, Command{Path: "/bin/bash", Args: []string{"-s"}}, script)); got != "web1 push\n" {
+		t.Errorf("script output = %q", got)
+	}
+
+	// 脚本中读取标准输入的命令不会读走后面的内容
+	if out := runRemoteExit(t, e.remoteInput(host, Command{}, []byte("cat\nread line\necho after\nexit 3"))); out.code != 3 || out.output != "after\n" {
+		t.Errorf("script reading stdin: exit %d, output %q, want exit 3 and %q", out.code, out.output, "after\n")
+	}
+	// 最后一条命令失败时退出码不被吞掉
+	if out := runRemoteExit(t, e.remoteInput(host, Command{}, []byte("false\n"))); out.code != 1 {
+		t.Errorf("script ending in false: exit %d, want 1", out.code)
+	}
+	// 内容不完整（连接中断）时什么都不执行
+	input = e.remoteInput(host, Command{}, []byte("echo partial\n"))
+	if out := runRemoteExit(t, input[:len(input)-len("}\nmain </dev/null\n")]); out.code == 0 || strings.Contains(out.output, "partial") {
+		t.Errorf("truncated input: exit %d, output %q", out.code, out.output)
+	}
+
+	// 工作目录不存在时不执行命令
+	host.Dir = dir + "/missing"
+	cmd := exec.Command("bash", "-s")
+	cmd.Stdin = bytes.NewReader(e.remoteInput(host, Command{Path: "/bin/echo", Args: []string{"ran"}}, nil))
+	if out, err := cmd.CombinedOutput(); err == nil || strings.Contains(string(out), "ran\n") {
+		t.Errorf("command ran in a missing directory: %v, %q", err, out)
+	}
+}
@@ -0,0 +1,30 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Prefix GitHub 风格签名的前缀
+const Prefix = "sha256="
+
+// Sign 计算 GitHub 风格的签名
+// 返回值格式为 "sha256=" + hex(HMAC-SHA256(secret, body))，
+// 与 X-Hub-Signature-256 头的格式一致
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return Prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验 GitHub 风格的签名
+// 使用常量时间比较，避免时序攻击
+func Verify(signature string, body []byte, secret string) bool {
+	// 检查前缀
+	if len(signature) <= len(Prefix) || signature[:len(Prefix)] != Prefix {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(Sign(body, secret)))
+}
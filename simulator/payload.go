@@ -0,0 +1,380 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 支持模拟的 Webhook 格式
+const (
+	FormatGitHub = "github"
+	FormatGitea  = "gitea"
+	FormatGitLab = "gitlab"
+)
+
+// zeroSHA 新建分支时 before 字段使用的全零提交ID
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// 提交范围起点中表示祖先与父提交的后缀，如 HEAD~2、HEAD^
+var (
+	ancestorSuffix = regexp.MustCompile(`^(.+)~([0-9]*)$`)
+	parentSuffix   = regexp.MustCompile(`^(.+)\^1?$`)
+)
+
+// Options 定义从本地仓库构造推送载荷的参数
+type Options struct {
+	RepoDir string // 本地 Git 仓库目录
+	Range   string // 提交范围，如 HEAD~3..HEAD；只写一个提交时等价于 <提交>~1..<提交>
+	Branch  string // 推送的分支，默认为当前分支
+	Format  string // 载荷格式：github、gitea 或 gitlab
+}
+
+// Person 提交的作者或提交者
+type Person struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username,omitempty"`
+}
+
+// Commit 推送中的一个提交，字段与 GitHub push 事件一致
+type Commit struct {
+	ID        string   `json:"id"`
+	TreeID    string   `json:"tree_id"`
+	Distinct  bool     `json:"distinct"`
+	Message   string   `json:"message"`
+	Timestamp string   `json:"timestamp"`
+	URL       string   `json:"url"`
+	Author    Person   `json:"author"`
+	Committer Person   `json:"committer"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Modified  []string `json:"modified"`
+}
+
+// Repository 推送所在的仓库
+type Repository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// gitHubPush GitHub（以及兼容 GitHub 格式的 Gitea）的 push 事件载荷
+type gitHubPush struct {
+	Ref        string     `json:"ref"`
+	Before     string     `json:"before"`
+	After      string     `json:"after"`
+	Created    bool       `json:"created"`
+	Deleted    bool       `json:"deleted"`
+	Forced     bool       `json:"forced"`
+	Compare    string     `json:"compare"`
+	Commits    []Commit   `json:"commits"`
+	HeadCommit *Commit    `json:"head_commit"`
+	Repository Repository `json:"repository"`
+	Pusher     Person     `json:"pusher"`
+	Sender     struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// gitLabCommit GitLab push 事件中的提交
+type gitLabCommit struct {
+	ID        string   `json:"id"`
+	Message   string   `json:"message"`
+	Title     string   `json:"title"`
+	Timestamp string   `json:"timestamp"`
+	URL       string   `json:"url"`
+	Author    Person   `json:"author"`
+	Added     []string `json:"added"`
+	Modified  []string `json:"modified"`
+	Removed   []string `json:"removed"`
+}
+
+// gitLabPush GitLab 的 Push Hook 载荷
+type gitLabPush struct {
+	ObjectKind        string         `json:"object_kind"`
+	EventName         string         `json:"event_name"`
+	Before            string         `json:"before"`
+	After             string         `json:"after"`
+	Ref               string         `json:"ref"`
+	CheckoutSHA       string         `json:"checkout_sha"`
+	UserName          string         `json:"user_name"`
+	UserEmail         string         `json:"user_email"`
+	Project           gitLabProject  `json:"project"`
+	Commits           []gitLabCommit `json:"commits"`
+	TotalCommitsCount int            `json:"total_commits_count"`
+}
+
+type gitLabProject struct {
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	GitHTTPURL        string `json:"git_http_url"`
+	DefaultBranch     string `json:"default_branch"`
+}
+
+// BuildPush 根据本地仓库中的提交范围构造推送事件载荷
+func BuildPush(opts Options) ([]byte, error) {
+	if opts.RepoDir == "" {
+		opts.RepoDir = "."
+	}
+	if opts.Range == "" {
+		opts.Range = "HEAD"
+	}
+	if opts.Format == "" {
+		opts.Format = FormatGitHub
+	}
+
+	g := &gitCmd{dir: opts.RepoDir}
+
+	base, head := splitRange(opts.Range)
+	after, err := g.run("rev-parse", "--verify", head+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("无法解析提交 %s: %v", head, err)
+	}
+
+	// 起点是根提交的父提交时视为新建分支，其他无法解析的起点（拼写错误、未拉取的引用）报错，
+	// 而不是把整个历史当作新建分支发送
+	before := zeroSHA
+	revRange := after
+	if sha, err := g.run("rev-parse", "--verify", "--quiet", base+"^{commit}"); err == nil {
+		before = sha
+		revRange = before + ".." + after
+	} else if !g.rootParent(base) {
+		return nil, fmt.Errorf("无法解析提交 %s", base)
+	}
+
+	list, err := g.run("rev-list", "--reverse", revRange)
+	if err != nil {
+		return nil, fmt.Errorf("列出提交失败: %v", err)
+	}
+	if list == "" {
+		return nil, fmt.Errorf("提交范围 %s 中没有提交", opts.Range)
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch, _ = g.run("rev-parse", "--abbrev-ref", "HEAD")
+		if branch == "" || branch == "HEAD" {
+			branch = "main"
+		}
+	}
+
+	repo := g.repository(branch)
+
+	var commits []Commit
+	for _, sha := range strings.Fields(list) {
+		commit, err := g.commit(sha, repo.HTMLURL)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+	headCommit := commits[len(commits)-1]
+
+	switch opts.Format {
+	case FormatGitHub, FormatGitea:
+		payload := gitHubPush{
+			Ref:        "refs/heads/" + branch,
+			Before:     before,
+			After:      after,
+			Created:    before == zeroSHA,
+			Compare:    fmt.Sprintf("%s/compare/%s...%s", repo.HTMLURL, short(before), short(after)),
+			Commits:    commits,
+			HeadCommit: &headCommit,
+			Repository: repo,
+			Pusher:     headCommit.Committer,
+		}
+		payload.Sender.Login = headCommit.Committer.Name
+		return marshal(payload)
+	case FormatGitLab:
+		payload := gitLabPush{
+			ObjectKind:  "push",
+			EventName:   "push",
+			Before:      before,
+			After:       after,
+			Ref:         "refs/heads/" + branch,
+			CheckoutSHA: after,
+			UserName:    headCommit.Committer.Name,
+			UserEmail:   headCommit.Committer.Email,
+			Project: gitLabProject{
+				Name:              repo.Name,
+				PathWithNamespace: repo.FullName,
+				WebURL:            repo.HTMLURL,
+				GitHTTPURL:        repo.CloneURL,
+				DefaultBranch:     repo.DefaultBranch,
+			},
+			TotalCommitsCount: len(commits),
+		}
+		for _, c := range commits {
+			payload.Commits = append(payload.Commits, gitLabCommit{
+				ID:        c.ID,
+				Message:   c.Message,
+				Title:     strings.SplitN(c.Message, "\n", 2)[0],
+				Timestamp: c.Timestamp,
+				URL:       c.URL,
+				Author:    Person{Name: c.Author.Name, Email: c.Author.Email},
+				Added:     c.Added,
+				Modified:  c.Modified,
+				Removed:   c.Removed,
+			})
+		}
+		return marshal(payload)
+	default:
+		return nil, fmt.Errorf("不支持的载荷格式: %s（可选：github、gitea、gitlab）", opts.Format)
+	}
+}
+
+// splitRange 将 A..B 拆分为起点与终点；只有一个提交时起点为它的父提交
+func splitRange(r string) (string, string) {
+	if i := strings.Index(r, ".."); i >= 0 {
+		base, head := r[:i], strings.TrimPrefix(r[i+2:], ".")
+		if head == "" {
+			head = "HEAD"
+		}
+		return base, head
+	}
+	return r + "~1", r
+}
+
+// marshal 序列化载荷，不转义 HTML 字符以保持与真实载荷相同的可读性
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func short(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// gitCmd 在指定目录中执行 git 命令
+type gitCmd struct {
+	dir string
+}
+
+func (g *gitCmd) run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// rootParent 判断 rev 是否为根提交的父提交，如根提交为 A 时的 A~1、A^，或 A 为 HEAD~1 时的 HEAD~2
+func (g *gitCmd) rootParent(rev string) bool {
+	var child string
+	if m := ancestorSuffix.FindStringSubmatch(rev); m != nil {
+		n := 1
+		if m[2] != "" {
+			n, _ = strconv.Atoi(m[2])
+		}
+		if n < 1 {
+			return false
+		}
+		child = m[1] + "~" + strconv.Itoa(n-1)
+	} else if m := parentSuffix.FindStringSubmatch(rev); m != nil {
+		child = m[1]
+	} else {
+		return false
+	}
+	out, err := g.run("rev-list", "--parents", "-n", "1", child+"^{commit}", "--")
+	return err == nil && len(strings.Fields(out)) == 1
+}
+
+// commit 读取一个提交的元数据与文件变更
+func (g *gitCmd) commit(sha, htmlURL string) (Commit, error) {
+	out, err := g.run("show", "-s", "--format=%H%x00%T%x00%an%x00%ae%x00%cn%x00%ce%x00%aI%x00%B", sha)
+	if err != nil {
+		return Commit{}, fmt.Errorf("读取提交 %s 失败: %v", sha, err)
+	}
+	fields := strings.SplitN(out, "\x00", 8)
+	if len(fields) != 8 {
+		return Commit{}, fmt.Errorf("无法解析提交 %s", sha)
+	}
+
+	commit := Commit{
+		ID:        fields[0],
+		TreeID:    fields[1],
+		Distinct:  true,
+		Author:    Person{Name: fields[2], Email: fields[3], Username: fields[2]},
+		Committer: Person{Name: fields[4], Email: fields[5], Username: fields[4]},
+		Timestamp: fields[6],
+		Message:   strings.TrimSpace(fields[7]),
+		URL:       htmlURL + "/commit/" + fields[0],
+		Added:     []string{},
+		Removed:   []string{},
+		Modified:  []string{},
+	}
+
+	// 与 GitHub 一样不识别重命名，重命名表现为删除加新增
+	changes, err := g.run("diff-tree", "--root", "--no-commit-id", "--no-renames", "--name-status", "-r", "-z", sha)
+	if err != nil {
+		return Commit{}, fmt.Errorf("读取提交 %s 的文件变更失败: %v", sha, err)
+	}
+	parts := strings.Split(strings.Trim(changes, "\x00"), "\x00")
+	for i := 0; i+1 < len(parts); i += 2 {
+		status, path := parts[i], parts[i+1]
+		switch {
+		case strings.HasPrefix(status, "A"):
+			commit.Added = append(commit.Added, path)
+		case strings.HasPrefix(status, "D"):
+			commit.Removed = append(commit.Removed, path)
+		default:
+			commit.Modified = append(commit.Modified, path)
+		}
+	}
+
+	return commit, nil
+}
+
+// remotePattern 匹配 git@host:owner/repo.git 与 https://host/owner/repo.git 形式的远程地址
+var remotePattern = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^:/]+)[:/](?:\d+/)?(.+?)(?:\.git)?/?$`)
+
+// repository 根据 origin 远程地址推断仓库信息
+func (g *gitCmd) repository(branch string) Repository {
+	repo := Repository{
+		Name:          "blog",
+		FullName:      "local/blog",
+		HTMLURL:       "https://github.com/local/blog",
+		DefaultBranch: branch,
+	}
+
+	if top, err := g.run("rev-parse", "--show-toplevel"); err == nil {
+		parts := strings.Split(strings.TrimRight(top, "/"), "/")
+		repo.Name = parts[len(parts)-1]
+		repo.FullName = "local/" + repo.Name
+		repo.HTMLURL = "https://github.com/" + repo.FullName
+	}
+
+	if remote, err := g.run("remote", "get-url", "origin"); err == nil {
+		if m := remotePattern.FindStringSubmatch(remote); m != nil {
+			repo.FullName = m[2]
+			repo.Name = m[2][strings.LastIndex(m[2], "/")+1:]
+			repo.HTMLURL = "https://" + m[1] + "/" + m[2]
+		}
+	}
+	repo.CloneURL = repo.HTMLURL + ".git"
+	return repo
+}
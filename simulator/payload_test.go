@@ -0,0 +1,74 @@
+package simulator
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRepo 创建一个有两个提交的仓库
+func newRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", name)
+		git("commit", "-q", "-m", "add "+name)
+	}
+	return dir
+}
+
+func TestBuildPushRange(t *testing.T) {
+	dir := newRepo(t)
+	tests := []struct {
+		rng     string
+		commits int
+		created bool
+	}{
+		{"HEAD", 1, false},
+		{"HEAD~1..HEAD", 1, false},
+		// 从根提交开始的范围视为新建分支
+		{"HEAD~1", 1, true},
+		{"HEAD~2..HEAD", 2, true},
+		{"HEAD~1^..HEAD", 2, true},
+	}
+	for _, tt := range tests {
+		data, err := BuildPush(Options{RepoDir: dir, Range: tt.rng})
+		if err != nil {
+			t.Errorf("%s: BuildPush() error = %v", tt.rng, err)
+			continue
+		}
+		var push struct {
+			Before  string   `json:"before"`
+			Commits []Commit `json:"commits"`
+		}
+		if err := json.Unmarshal(data, &push); err != nil {
+			t.Fatal(err)
+		}
+		if len(push.Commits) != tt.commits || (push.Before == zeroSHA) != tt.created {
+			t.Errorf("%s: before = %s, %d commits", tt.rng, push.Before, len(push.Commits))
+		}
+	}
+
+	// 无法解析的起点报错，而不是把整个历史当作新建分支
+	for _, rng := range []string{"mian..HEAD", "HEAD~3..HEAD", "origin/main..HEAD"} {
+		if _, err := BuildPush(Options{RepoDir: dir, Range: rng}); err == nil || !strings.Contains(err.Error(), "无法解析提交") {
+			t.Errorf("%s: BuildPush() error = %v", rng, err)
+		}
+	}
+}
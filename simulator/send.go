@@ -0,0 +1,91 @@
+package simulator
+
+import (
+	"Hexo-AutoCD/scripts"
+	sign "Hexo-AutoCD/signature"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Headers 构造与真实平台一致的请求头
+// 无论哪种格式都会附带 X-GitHub-Event 与 X-Hub-Signature-256，
+// 签名方式与服务端 verifySignature 的校验方式完全相同
+func Headers(format, event string, payload []byte, secret string) (http.Header, error) {
+	if event == "" {
+		return nil, fmt.Errorf("事件类型不能为空")
+	}
+	delivery := NewDeliveryID()
+	signature := sign.Sign(payload, secret)
+
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	h.Set("X-GitHub-Event", event)
+	h.Set("X-GitHub-Delivery", delivery)
+	h.Set("X-Hub-Signature-256", signature)
+
+	switch format {
+	case FormatGitea:
+		h.Set("User-Agent", "Go-http-client/1.1")
+		h.Set("X-Gitea-Event", event)
+		h.Set("X-Gitea-Delivery", delivery)
+		h.Set("X-Gitea-Signature", strings.TrimPrefix(signature, sign.Prefix))
+	case FormatGitLab:
+		h.Set("User-Agent", "GitLab/hexo-autocd")
+		h.Set("X-Gitlab-Event", strings.ToUpper(event[:1])+event[1:]+" Hook")
+		h.Set("X-Gitlab-Token", secret)
+	default:
+		h.Set("User-Agent", "GitHub-Hookshot/hexo-autocd")
+	}
+	return h, nil
+}
+
+// Send 将载荷发送到正在运行的服务，返回响应状态与响应体
+func Send(url string, payload []byte, headers http.Header) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header = headers.Clone()
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("发送失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, string(body), nil
+}
+
+// Curl 生成等价的 curl 命令，载荷内容与签名逐字节一致
+func Curl(url string, payload []byte, headers http.Header) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "curl -X POST %s \\\n", scripts.ShellQuote(url))
+	for _, k := range keys {
+		fmt.Fprintf(&b, "  -H %s \\\n", scripts.ShellQuote(k+": "+headers.Get(k)))
+	}
+	fmt.Fprintf(&b, "  --data-binary %s", scripts.ShellQuote(string(payload)))
+	return b.String()
+}
+
+// NewDeliveryID 生成 GUID 格式的投递ID，与 GitHub 的 X-GitHub-Delivery 格式一致
+func NewDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,63 @@
+package smoke
+
+import (
+	"Hexo-AutoCD/posts"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Permalink 按 Hexo 的 permalink 规则计算文章的访问路径
+// name 为相对于 source/_posts 的文件路径；front-matter 中写了 permalink 时直接使用
+// 支持 :year、:month、:i_month、:day、:i_day、:hour、:minute、:second、:title、:name、:post_title、:category
+func Permalink(pattern, name string, fm *posts.FrontMatter, dateFormats []string) (string, error) {
+	if link := fm.String("permalink"); link != "" {
+		return "/" + strings.TrimPrefix(link, "/"), nil
+	}
+
+	value := fm.String("date")
+	if value == "" {
+		return "", fmt.Errorf("%s 缺少 date，无法计算访问路径", name)
+	}
+	var date time.Time
+	var err error
+	for _, format := range dateFormats {
+		if date, err = time.ParseInLocation(format, value, time.Local); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s 的 date 无法解析: %q", name, value)
+	}
+
+	// Hexo 的 :title 为 slug，未填写时是不含扩展名的文件路径
+	slug := fm.String("slug")
+	if slug == "" {
+		slug = strings.TrimSuffix(name, path.Ext(name))
+	}
+	category := "uncategorized" // Hexo 的 default_category
+	if categories := fm.Strings("categories"); len(categories) > 0 {
+		category = strings.ToLower(categories[0])
+	}
+
+	r := strings.NewReplacer(
+		":year", date.Format("2006"),
+		":i_month", date.Format("1"),
+		":month", date.Format("01"),
+		":i_day", date.Format("2"),
+		":day", date.Format("02"),
+		":hour", date.Format("15"),
+		":minute", date.Format("04"),
+		":second", date.Format("05"),
+		":post_title", strings.ToLower(fm.String("title")),
+		":title", slug,
+		":name", path.Base(slug),
+		":category", category,
+	)
+	link := r.Replace(pattern)
+	if strings.HasSuffix(link, "/") || path.Ext(link) == "" {
+		link = strings.TrimSuffix(link, "/") + "/"
+	}
+	return "/" + strings.TrimPrefix(link, "/"), nil
+}
@@ -0,0 +1,219 @@
+package smoke
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/posts"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Result 描述一项检查的结果
+type Result struct {
+	Name    string        // 检查名称，如 GET /、post 文章.md、min_pages
+	OK      bool          // 是否通过
+	Detail  string        // 通过时为状态码与耗时等信息，失败时为失败原因
+	Latency time.Duration // HTTP 检查的响应时间
+}
+
+func (r Result) String() string {
+	mark := "✓"
+	if !r.OK {
+		mark = "✗"
+	}
+	return fmt.Sprintf("%s %s: %s", mark, r.Name, r.Detail)
+}
+
+// Checker 在部署后检查站点
+type Checker struct {
+	Config config.Smoke
+	Posts  config.Posts
+	Client *http.Client
+}
+
+// New 根据配置创建检查器
+func New(cfg config.Smoke, postsCfg config.Posts) *Checker {
+	timeout, _ := time.ParseDuration(cfg.Timeout)
+	return &Checker{
+		Config: cfg,
+		Posts:  postsCfg,
+		// 不跟随重定向，期望状态码按第一次响应判断
+		Client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run 依次执行所有检查，publicDir 为生成目录，changed 为推送中相对于文章仓库的文件
+func (c *Checker) Run(publicDir string, changed []string) []Result {
+	if delay, _ := time.ParseDuration(c.Config.Delay); delay > 0 {
+		time.Sleep(delay)
+	}
+
+	var results []Result
+	if c.Config.MinPages > 0 {
+		results = append(results, c.checkPages(publicDir))
+	}
+	for _, check := range c.Config.Checks {
+		results = append(results, c.checkHTTP(check))
+	}
+	if c.Config.Posts {
+		for _, file := range changed {
+			if posts.IsPost(file) {
+				if result, ok := c.checkPost(file); ok {
+					results = append(results, result)
+				}
+			}
+		}
+	}
+	return results
+}
+
+// Failed 返回未通过的检查
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if !r.OK {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// checkPages 检查生成目录中的 HTML 页面数量，避免主题或配置出错时生成出空站点
+func (c *Checker) checkPages(publicDir string) Result {
+	result := Result{Name: "min_pages"}
+	count := 0
+	err := filepath.WalkDir(publicDir, func(_ string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".html") {
+			count++
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		result.Detail = fmt.Sprintf("读取生成目录失败: %v", err)
+	case count < c.Config.MinPages:
+		result.Detail = fmt.Sprintf("%s 中只有 %d 个 HTML 页面，至少需要 %d 个", publicDir, count, c.Config.MinPages)
+	default:
+		result.OK = true
+		result.Detail = fmt.Sprintf("%d 个 HTML 页面", count)
+	}
+	return result
+}
+
+// checkHTTP 执行一个 HTTP 检查
+func (c *Checker) checkHTTP(check config.SmokeCheck) Result {
+	result := Result{Name: "GET " + check.Path}
+	status, body, latency, err := c.get(check.Path)
+	result.Latency = latency
+	maxLatency, _ := time.ParseDuration(check.MaxLatency)
+
+	switch {
+	case err != nil:
+		result.Detail = err.Error()
+	case status != check.Status:
+		result.Detail = fmt.Sprintf("状态码 %d，期望 %d", status, check.Status)
+	case check.Contains != "" && !strings.Contains(body, check.Contains):
+		result.Detail = fmt.Sprintf("响应内容中没有 %q", check.Contains)
+	case maxLatency > 0 && latency > maxLatency:
+		result.Detail = fmt.Sprintf("响应时间 %s，超过 %s", latency.Round(time.Millisecond), maxLatency)
+	default:
+		result.OK = true
+		result.Detail = fmt.Sprintf("%d，%s", status, latency.Round(time.Millisecond))
+	}
+	return result
+}
+
+// checkPost 检查变更的文章页面能否访问，且页面中包含文章标题
+// 已删除的文章不检查，返回 false
+func (c *Checker) checkPost(file string) (Result, bool) {
+	// 设置了输出目录时读取预处理后的文章，Hexo 生成的就是它
+	dir, name := c.Posts.SourceDir, file
+	if c.Posts.DestDir != "" {
+		dir, name = c.Posts.DestDir, path.Base(file)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return Result{}, false
+	}
+
+	result := Result{Name: "post " + name}
+	if err != nil {
+		result.Detail = err.Error()
+		return result, true
+	}
+	var fm *posts.FrontMatter
+	if front, _, _, ok := posts.Split(content); ok {
+		fm, err = posts.ParseFrontMatter(front)
+		if err != nil {
+			result.Detail = err.Error()
+			return result, true
+		}
+	} else {
+		fm, _ = posts.ParseFrontMatter(nil)
+	}
+
+	link, err := Permalink(c.Config.Permalink, name, fm, c.Posts.Lint.DateFormats)
+	if err != nil {
+		result.Detail = err.Error()
+		return result, true
+	}
+	result.Name = "post " + link
+
+	status, body, latency, err := c.get(link)
+	result.Latency = latency
+	title := fm.String("title")
+	switch {
+	case err != nil:
+		result.Detail = err.Error()
+	case status != http.StatusOK:
+		result.Detail = fmt.Sprintf("状态码 %d，期望 200", status)
+	case title != "" && !strings.Contains(body, title) && !strings.Contains(body, html.EscapeString(title)):
+		result.Detail = fmt.Sprintf("页面中没有文章标题 %q", title)
+	default:
+		result.OK = true
+		result.Detail = fmt.Sprintf("%d，%s", status, latency.Round(time.Millisecond))
+	}
+	return result, true
+}
+
+// get 请求站点中的路径，返回状态码、响应内容与响应时间
+func (c *Checker) get(p string) (int, string, time.Duration, error) {
+	base, err := url.Parse(strings.TrimSuffix(c.Config.BaseURL, "/"))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	// 文章路径中可能有中文与空格，按路径编码
+	target := base.JoinPath(strings.Split(strings.TrimPrefix(p, "/"), "/")...)
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(target.Path, "/") {
+		target.Path += "/"
+	}
+
+	start := time.Now()
+	resp, err := c.Client.Get(target.String())
+	if err != nil {
+		return 0, "", time.Since(start), fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	// 只读取前 2MB，足够判断页面内容
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	latency := time.Since(start)
+	if err != nil {
+		return resp.StatusCode, "", latency, fmt.Errorf("读取响应失败: %v", err)
+	}
+	return resp.StatusCode, string(body), latency, nil
+}
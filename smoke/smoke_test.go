@@ -0,0 +1,169 @@
+package smoke
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/posts"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// site 返回一个模拟的博客站点
+func site(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte("<title>我的博客</title>"))
+		case "/old/":
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		case "/2024/03/05/你好 世界/":
+			w.Write([]byte("<h1>Tom &amp; Jerry</h1>"))
+		case "/about/":
+			w.Write([]byte("<h1>其他页面</h1>"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckHTTP(t *testing.T) {
+	server := site(t)
+	tests := []struct {
+		check  config.SmokeCheck
+		ok     bool
+		detail string
+	}{
+		{config.SmokeCheck{Path: "/", Status: 200}, true, "200"},
+		{config.SmokeCheck{Path: "/", Status: 200, Contains: "我的博客"}, true, "200"},
+		{config.SmokeCheck{Path: "/", Status: 200, Contains: "不存在的内容"}, false, "响应内容中没有"},
+		{config.SmokeCheck{Path: "/missing", Status: 200}, false, "状态码 404，期望 200"},
+		{config.SmokeCheck{Path: "/missing", Status: 404}, true, "404"},
+		// 不跟随重定向
+		{config.SmokeCheck{Path: "/old/", Status: 301}, true, "301"},
+		{config.SmokeCheck{Path: "/old/", Status: 200}, false, "状态码 301，期望 200"},
+	}
+	c := New(config.Smoke{BaseURL: server.URL + "/", Timeout: "5s"}, config.Posts{})
+	for _, tt := range tests {
+		r := c.checkHTTP(tt.check)
+		if r.OK != tt.ok || !strings.Contains(r.Detail, tt.detail) {
+			t.Errorf("checkHTTP(%+v) = %v %q, want %v %q", tt.check, r.OK, r.Detail, tt.ok, tt.detail)
+		}
+	}
+
+	server.Close()
+	if r := c.checkHTTP(config.SmokeCheck{Path: "/", Status: 200}); r.OK || !strings.Contains(r.Detail, "请求失败") {
+		t.Errorf("checkHTTP() on closed server = %v %q", r.OK, r.Detail)
+	}
+}
+
+func TestRunPosts(t *testing.T) {
+	server := site(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"hello.md":   "---\ntitle: Tom & Jerry\ndate: 2024-03-05 10:00:00\nslug: 你好 世界\n---\n",
+		"wrong.md":   "---\ntitle: 标题不符\npermalink: about/\n---\n",
+		"missing.md": "---\ntitle: 未生成\ndate: 2024-03-06\n---\n",
+		"nodate.md":  "---\ntitle: 没有日期\n---\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := New(config.Smoke{
+		BaseURL:   server.URL,
+		Timeout:   "5s",
+		Posts:     true,
+		Permalink: ":year/:month/:day/:title/",
+		Checks:    []config.SmokeCheck{{Path: "/", Status: 200}},
+	}, config.Posts{SourceDir: dir, Lint: config.Lint{DateFormats: []string{"2006-01-02 15:04:05", "2006-01-02"}}})
+
+	results := c.Run("", []string{"hello.md", "wrong.md", "missing.md", "nodate.md", "deleted.md", "images/a.png"})
+	want := []struct {
+		name   string
+		ok     bool
+		detail string
+	}{
+		{"GET /", true, "200"},
+		{"post /2024/03/05/你好 世界/", true, "200"},
+		{"post /about/", false, "页面中没有文章标题"},
+		{"post /2024/03/06/missing/", false, "状态码 404"},
+		{"post nodate.md", false, "缺少 date"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Run() = %v, want %d results", results, len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Name != w.name || r.OK != w.ok || !strings.Contains(r.Detail, w.detail) {
+			t.Errorf("results[%d] = %s, want %s %v %q", i, r, w.name, w.ok, w.detail)
+		}
+	}
+	if failed := Failed(results); len(failed) != 3 {
+		t.Errorf("Failed() = %v", failed)
+	}
+}
+
+func TestCheckPages(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "about/index.HTML", "style.css"} {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		dir      string
+		minPages int
+		ok       bool
+	}{
+		{dir, 2, true},
+		{dir, 3, false},
+		{filepath.Join(dir, "missing"), 1, false},
+	}
+	for _, tt := range tests {
+		c := &Checker{Config: config.Smoke{MinPages: tt.minPages}}
+		if r := c.checkPages(tt.dir); r.OK != tt.ok {
+			t.Errorf("checkPages(%s) with min_pages %d = %s", tt.dir, tt.minPages, r)
+		}
+	}
+}
+
+func TestPermalink(t *testing.T) {
+	formats := []string{"2006-01-02 15:04:05", "2006-01-02"}
+	tests := []struct {
+		pattern, name, front string
+		want                 string
+	}{
+		{":year/:month/:day/:title/", "hello.md", "date: 2024-03-05 10:00:00", "/2024/03/05/hello/"},
+		{":year/:i_month/:i_day/:name.html", "dir/hello.md", "date: 2024-03-05", "/2024/3/5/hello.html"},
+		{":year/:title/", "hello.md", "date: 2024-03-05\nslug: world", "/2024/world/"},
+		{":category/:post_title", "hello.md", "date: 2024-03-05\ntitle: Hello\ncategories: [Go, 技术]", "/go/hello/"},
+		{":category/:title/", "hello.md", "date: 2024-03-05", "/uncategorized/hello/"},
+		{":hour:minute:second/", "hello.md", "date: 2024-03-05 10:20:30", "/102030/"},
+		{":year/:title/", "hello.md", "permalink: /custom/page/", "/custom/page/"},
+	}
+	for _, tt := range tests {
+		fm, err := posts.ParseFrontMatter([]byte(tt.front))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Permalink(tt.pattern, tt.name, fm, formats)
+		if err != nil || got != tt.want {
+			t.Errorf("Permalink(%q, %q, %q) = %q, %v, want %q", tt.pattern, tt.name, tt.front, got, err, tt.want)
+		}
+	}
+
+	fm, _ := posts.ParseFrontMatter([]byte("date: 2024年3月5日"))
+	if _, err := Permalink(":title/", "hello.md", fm, formats); err == nil {
+		t.Error("Permalink() with bad date error = nil")
+	}
+}
@@ -0,0 +1,220 @@
+package static
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/preview"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// encodings 预压缩文件的扩展名，按优先级排列
+var encodings = []struct {
+	name string // Content-Encoding
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Handler 返回静态文件服务
+// 每个请求开始时解析一次站点目录的符号链接，发布或回滚切换 current 后新请求立即使用新版本，
+// 同一个请求内读到的始终是同一个版本
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot := config.Get()
+	cfg := snapshot.Static
+	root, upath := cfg.Root, r.URL.Path
+
+	// 分支预览按路径前缀或子域名提供
+	if p := snapshot.Preview; p.Enabled {
+		if name, rest, ok := preview.Match(p.URL, r.Host, r.URL.Path); ok {
+			if rest == "" {
+				http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+				return
+			}
+			dir, err := filepath.EvalSymlinks(preview.New(p).Path(name))
+			if err != nil {
+				http.Error(w, "404 preview not found", http.StatusNotFound)
+				return
+			}
+			root, upath = dir, rest
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		logger.WithError(err).Error("站点目录不可用")
+		http.Error(w, "503 service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !strings.HasPrefix(upath, "/") {
+		upath = "/" + upath
+	}
+	clean := path.Clean(upath)
+	// 不提供 .git 等隐藏文件
+	for _, part := range strings.Split(clean, "/") {
+		if strings.HasPrefix(part, ".") {
+			notFound(w, r, root, cfg)
+			return
+		}
+	}
+
+	name := filepath.Join(root, filepath.FromSlash(clean))
+	info, err := os.Stat(name)
+	if err != nil {
+		notFound(w, r, root, cfg)
+		return
+	}
+	if info.IsDir() {
+		// 与 Hexo 生成的链接一致，目录地址以 / 结尾
+		if !strings.HasSuffix(upath, "/") {
+			target := r.URL.Path + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		name = filepath.Join(name, "index.html")
+		if info, err = os.Stat(name); err != nil || info.IsDir() {
+			notFound(w, r, root, cfg)
+			return
+		}
+	}
+	serveFile(w, r, name, cfg)
+}
+
+// serveFile 输出文件，客户端支持时优先使用预压缩的 .br、.gz 文件
+// ETag、If-None-Match、If-Modified-Since 与 Range 由 http.ServeContent 处理
+func serveFile(w http.ResponseWriter, r *http.Request, name string, cfg config.Static) {
+	h := w.Header()
+	if ctype := mime.TypeByExtension(filepath.Ext(name)); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	h.Set("Cache-Control", cacheControl(name, cfg))
+
+	file, info, encoding, err := open(name, r.Header.Get("Accept-Encoding"))
+	if err != nil {
+		http.Error(w, "500 internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	if encoding != "" || hasVariant(name) {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	h.Set("ETag", etag(info, encoding))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// notFound 输出自定义的 404 页面，不存在时输出纯文本
+func notFound(w http.ResponseWriter, r *http.Request, root string, cfg config.Static) {
+	page := filepath.Join(root, filepath.FromSlash(cfg.NotFound))
+	file, _, encoding, err := open(page, r.Header.Get("Accept-Encoding"))
+	if err != nil {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+		h.Add("Vary", "Accept-Encoding")
+	}
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		io.Copy(w, file)
+	}
+}
+
+// open 打开文件，客户端接受且存在预压缩文件时打开压缩后的文件并返回对应的编码
+func open(name, acceptEncoding string) (*os.File, os.FileInfo, string, error) {
+	for _, enc := range encodings {
+		if !accepts(acceptEncoding, enc.name) {
+			continue
+		}
+		if file, info, err := openRegular(name + enc.ext); err == nil {
+			return file, info, enc.name, nil
+		}
+	}
+	file, info, err := openRegular(name)
+	return file, info, "", err
+}
+
+func openRegular(name string) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, fmt.Errorf("不是普通文件: %s", name)
+	}
+	return file, info, nil
+}
+
+// hasVariant 判断是否存在预压缩文件，存在时响应需要带上 Vary，避免缓存把压缩内容发给不支持的客户端
+func hasVariant(name string) bool {
+	for _, enc := range encodings {
+		if _, err := os.Stat(name + enc.ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// accepts 判断 Accept-Encoding 是否接受指定编码，q=0 表示不接受
+func accepts(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) && strings.TrimSpace(name) != "*" {
+			continue
+		}
+		params = strings.ReplaceAll(params, " ", "")
+		return params != "q=0" && params != "q=0.0" && params != "q=0.00" && params != "q=0.000"
+	}
+	return false
+}
+
+// etag 根据修改时间、大小与编码生成 ETag，不同编码的内容使用不同的 ETag
+func etag(info os.FileInfo, encoding string) string {
+	tag := fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+	return `"` + tag + `"`
+}
+
+// cacheControl HTML 页面每次都向服务器确认，其他资源按 max_age 缓存
+func cacheControl(name string, cfg config.Static) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".html" || ext == ".htm" {
+		return "no-cache"
+	}
+	maxAge, _ := time.ParseDuration(cfg.MaxAge)
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
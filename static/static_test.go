@@ -0,0 +1,197 @@
+package static
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// writeFiles 在 dir 中写入文件
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// setup 创建 releases/v1 与指向它的 current，以及站点目录之外的 secret.txt 与预览目录，返回临时目录
+// previewURL 不为空时启用预览
+func setup(t *testing.T, previewURL string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"site/releases/v1/index.html":           "首页",
+		"site/releases/v1/about/index.html":     "关于",
+		"site/releases/v1/404.html":             "找不到页面",
+		"site/releases/v1/.git/config":          "[core]",
+		"site/releases/v1/style.css":            "body{}",
+		"site/secret.txt":                       "站点目录之外的文件",
+		"previews/.builds/feature-x/index.html": "预览首页",
+	})
+	if err := os.Symlink(filepath.Join("releases", "v1"), filepath.Join(dir, "site", "current")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(".builds", "feature-x"), filepath.Join(dir, "previews", "feature-x")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("static:\n  enabled: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Static.Root = filepath.Join(dir, "site", "current")
+	cfg.Preview.Enabled = previewURL != ""
+	cfg.Preview.Dir = filepath.Join(dir, "previews")
+	cfg.Preview.URL = previewURL
+	previous := config.Get()
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+	return dir
+}
+
+// get 请求静态文件服务，返回状态码、Location 与响应内容
+func get(host, target string) (int, string, string) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if host != "" {
+		r.Host = host
+	}
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, r)
+	return w.Code, w.Header().Get("Location"), w.Body.String()
+}
+
+func TestServe(t *testing.T) {
+	setup(t, "")
+	tests := []struct {
+		target   string
+		status   int
+		location string
+		body     string
+	}{
+		{"/", 200, "", "首页"},
+		{"/index.html", 200, "", "首页"},
+		{"/about/", 200, "", "关于"},
+		{"/about", 301, "/about/", ""},
+		{"/about?a=1", 301, "/about/?a=1", ""},
+		{"/style.css", 200, "", "body{}"},
+		{"/missing/", 404, "", "找不到页面"},
+		{"/404.html", 200, "", "找不到页面"},
+		// 隐藏文件
+		{"/.git/config", 404, "", "找不到页面"},
+		// 路径穿越不能读到站点目录之外的文件
+		{"/../secret.txt", 404, "", "找不到页面"},
+		{"/../../site/secret.txt", 404, "", "找不到页面"},
+		{"/about/../../secret.txt", 404, "", "找不到页面"},
+		{"/..%2fsecret.txt", 404, "", "找不到页面"},
+	}
+	for _, tt := range tests {
+		status, location, body := get("", tt.target)
+		if status != tt.status || location != tt.location || (tt.body != "" && body != tt.body) {
+			t.Errorf("GET %s = %d %q %q, want %d %q %q", tt.target, status, location, body, tt.status, tt.location, tt.body)
+		}
+	}
+}
+
+func TestNotFoundWithoutPage(t *testing.T) {
+	dir := setup(t, "")
+	if err := os.Remove(filepath.Join(dir, "site", "releases", "v1", "404.html")); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, body := get("", "/missing"); status != 404 || body != "404 page not found\n" {
+		t.Errorf("GET /missing = %d %q", status, body)
+	}
+}
+
+func TestServeSwitchesRelease(t *testing.T) {
+	dir := setup(t, "")
+	writeFiles(t, dir, map[string]string{"site/releases/v2/index.html": "新版本"})
+	current := filepath.Join(dir, "site", "current")
+	if err := os.Symlink(filepath.Join("releases", "v2"), current+".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(current+".tmp", current); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, body := get("", "/"); body != "新版本" {
+		t.Errorf("GET / after switching current = %q", body)
+	}
+}
+
+func TestServePreview(t *testing.T) {
+	tests := []struct {
+		url, host, target string
+		status            int
+		location          string
+		body              string
+	}{
+		// 路径前缀
+		{"https://blog.example.com/preview/{name}/", "", "/preview/feature-x/", 200, "", "预览首页"},
+		{"https://blog.example.com/preview/{name}/", "", "/preview/feature-x", 301, "/preview/feature-x/", ""},
+		{"https://blog.example.com/preview/{name}/", "", "/preview/missing/", 404, "", "404 preview not found\n"},
+		{"https://blog.example.com/preview/{name}/", "", "/preview/feature-x/../../secret.txt", 404, "", ""},
+		{"https://blog.example.com/preview/{name}/", "", "/about/", 200, "", "关于"},
+		// 子域名
+		{"https://{name}.preview.example.com/", "feature-x.preview.example.com:4080", "/", 200, "", "预览首页"},
+		{"https://{name}.preview.example.com/", "missing.preview.example.com", "/", 404, "", "404 preview not found\n"},
+		{"https://{name}.preview.example.com/", "blog.example.com", "/", 200, "", "首页"},
+	}
+	for _, tt := range tests {
+		setup(t, tt.url)
+		status, location, body := get(tt.host, tt.target)
+		if status != tt.status || location != tt.location || (tt.body != "" && body != tt.body) {
+			t.Errorf("GET %s%s with %s = %d %q %q, want %d %q %q", tt.host, tt.target, tt.url, status, location, body, tt.status, tt.location, tt.body)
+		}
+		if body == "站点目录之外的文件" {
+			t.Errorf("GET %s%s served a file outside the site", tt.host, tt.target)
+		}
+	}
+}
+
+func TestServeMethodAndEncoding(t *testing.T) {
+	dir := setup(t, "")
+	writeFiles(t, dir, map[string]string{"site/releases/v1/style.css.gz": "gzip"})
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST / = %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/style.css", nil)
+	r.Header.Set("Accept-Encoding", "br, gzip")
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, r)
+	if w.Body.String() != "gzip" || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("GET /style.css with gzip = %q, headers %v", w.Body.String(), w.Header())
+	}
+
+	r.Header.Set("Accept-Encoding", "gzip;q=0")
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, r)
+	if w.Body.String() != "body{}" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("GET /style.css without gzip = %q, headers %v", w.Body.String(), w.Header())
+	}
+}
@@ -0,0 +1,142 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/git"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/scripts"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// checkoutStep 检出步骤在执行结果中的名称
+const checkoutStep = "checkout"
+
+// checkoutPush 在博客仓库中获取并检出推送的提交，结果作为一个步骤记录在执行结果中，并返回检出的提交
+// ref 为 refs/tags/ 开头时检出标签；verify 不为 nil 时在检出前校验提交签名
+// retry 不为 nil 时，无法连接远程仓库、超时或错误信息匹配 retry.patterns 的失败按策略重试
+func checkoutPush(repoDir, remote string, refuseDirty bool, timeout time.Duration, verify *git.VerifyPolicy, retry *scripts.RetryPolicy, pushEvent PushEvent) (scripts.StepResult, string, error) {
+	startTime := time.Now()
+	step := scripts.StepResult{
+		Name:      checkoutStep,
+		StartedAt: startTime.Format(time.RFC3339),
+	}
+
+	commit := pushEvent.After
+	if commit == "" {
+		commit = pushEvent.HeadCommit.ID
+	}
+
+	tag, isTag := strings.CutPrefix(pushEvent.Ref, "refs/tags/")
+	if !isTag {
+		tag = ""
+	}
+	if retry != nil {
+		// 不修改配置中的策略，检出有自己的重试条件
+		policy := *retry
+		policy.Classify = func(outcome scripts.Outcome) bool { return retryableCheckout(retry, outcome.Err) }
+		retry = &policy
+	}
+	var err error
+	result, attempts := scripts.Retry(retry, logger.WithField("仓库", repoDir), func() (*git.Result, scripts.Outcome) {
+		var result *git.Result
+		result, err = git.Sync(git.Options{
+			Dir:     repoDir,
+			Remote:  remote,
+			Branch:  strings.TrimPrefix(pushEvent.Ref, "refs/heads/"),
+			Tag:     tag,
+			Commit:  commit,
+			Clean:   !refuseDirty,
+			Timeout: timeout,
+			Verify:  verify,
+		})
+		if err != nil {
+			return result, scripts.Outcome{ExitCode: -1, Err: err}
+		}
+		return result, scripts.Outcome{}
+	})
+	step.Attempts = len(attempts)
+	if len(attempts) > 1 {
+		step.History = attempts
+	}
+	step.DurationMs = time.Since(startTime).Milliseconds()
+	if result != nil {
+		step.Output = signatureReport(result.Signatures)
+	}
+
+	if err != nil {
+		step.Status = scripts.StepFailed
+		step.ExitCode = -1
+		step.Error = err.Error()
+		step.ErrorKind = git.Kind(err)
+		logger.WithFields(logrus.Fields{
+			"仓库":   repoDir,
+			"错误类型": step.ErrorKind,
+		}).WithError(err).Error("检出推送的提交失败")
+		return step, "", err
+	}
+
+	step.Status = scripts.StepSuccess
+	step.Output += "HEAD " + result.Head + "\n"
+	fields := logrus.Fields{
+		"仓库": repoDir,
+		"分支": result.Branch,
+		"提交": result.Head,
+	}
+	if result.Forced {
+		step.Output += "检测到强制推送，原 HEAD " + result.Previous + "\n"
+		fields["原HEAD"] = result.Previous
+	}
+	if len(result.Discard) > 0 {
+		step.Output += "已丢弃本地修改:\n" + strings.Join(result.Discard, "\n") + "\n"
+		fields["丢弃修改数"] = len(result.Discard)
+	}
+	if tag != "" {
+		fields["标签"] = tag
+	}
+	logger.WithFields(fields).Info("已检出推送的提交")
+	return step, result.Head, nil
+}
+
+// retryableCheckout 返回检出失败是否可以重试，认证失败、签名未通过等重试也不会成功的错误不重试
+func retryableCheckout(retry *scripts.RetryPolicy, err error) bool {
+	switch git.Kind(err) {
+	case git.ErrNetwork, git.ErrTimeout:
+		return true
+	}
+	return retry.Matches(err.Error())
+}
+
+// signatureReport 生成签名校验结果的文字描述
+func signatureReport(signatures []git.Signature) string {
+	var b strings.Builder
+	for _, sig := range signatures {
+		signer := sig.Signer
+		if signer == "" {
+			signer = "-"
+		}
+		fmt.Fprintf(&b, "签名 %s %s %s %s\n", sig.Commit, sig.Status, signer, sig.Key)
+	}
+	return b.String()
+}
+
+// verifyPolicy 根据推送构造签名校验策略
+func verifyPolicy(all bool, gpgHome, allowedSigners string, pushEvent PushEvent) *git.VerifyPolicy {
+	policy := &git.VerifyPolicy{
+		All:            all,
+		GPGHome:        gpgHome,
+		AllowedSigners: allowedSigners,
+		Before:         pushEvent.Before,
+	}
+	for _, commit := range pushEvent.Commits {
+		policy.Commits = append(policy.Commits, commit.ID)
+	}
+	return policy
+}
+
+// rejected 判断检出失败是否因为提交签名未通过校验
+func rejected(step scripts.StepResult) bool {
+	return step.ErrorKind == git.ErrUnsigned || step.ErrorKind == git.ErrUntrusted
+}
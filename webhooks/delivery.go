@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/scripts"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Delivery 描述收到的一次事件投递，执行脚本时写入载荷文件并导出为环境变量
+type Delivery struct {
+	ID         string      // 投递ID，来自 X-GitHub-Delivery 头，本地执行时为空
+	Event      string      // 事件类型
+	Payload    []byte      // 原始载荷
+	Repo       string      // 仓库全名，如 owner/blog
+	Pusher     string      // 推送者，没有时为触发事件的用户
+	Normalized interface{} // 解析并补全后的事件
+}
+
+// newDelivery 解析各类事件共有的仓库与用户字段
+func newDelivery(id, event string, payload []byte) Delivery {
+	var common struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Pusher struct {
+			Name string `json:"name"`
+		} `json:"pusher"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+		UserName string `json:"user_username"` // GitLab
+	}
+	json.Unmarshal(payload, &common)
+
+	d := Delivery{ID: id, Event: event, Payload: payload, Repo: common.Repository.FullName}
+	for _, name := range []string{common.Pusher.Name, common.Sender.Login, common.UserName} {
+		if name != "" {
+			d.Pusher = name
+			break
+		}
+	}
+	return d
+}
+
+// deliveryID 返回请求头中的投递ID，兼容 Gitea 与 Gogs
+func deliveryID(c *gin.Context) string {
+	for _, header := range []string{"X-GitHub-Delivery", "X-Gitea-Delivery", "X-Gogs-Delivery"} {
+		if id := c.GetHeader(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// with 返回附带解析后事件的副本
+func (d Delivery) with(normalized interface{}) Delivery {
+	d.Normalized = normalized
+	return d
+}
+
+// payload 返回导出给脚本的事件信息，ref、提交与变更文件由调用方补充
+func (d Delivery) payload(runID, site string) *scripts.Payload {
+	return &scripts.Payload{
+		Event:      d.Event,
+		DeliveryID: d.ID,
+		RunID:      runID,
+		Site:       site,
+		Repo:       d.Repo,
+		Pusher:     d.Pusher,
+		Raw:        d.Payload,
+		Normalized: d.Normalized,
+	}
+}
@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/events"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushPayload(t *testing.T) {
+	// 接收出站的 deploy.finished 事件
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		select {
+		case received <- body:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	dir := useConfig(t, map[string]string{
+		"deploy.sh": "#!/bin/bash\nout={{dir}}/out\n" +
+			"cp \"$WEBHOOK_PAYLOAD_FILE\" $out/payload.json\n" +
+			"cp \"$CHANGED_FILES_FILE\" $out/changed.txt\n" +
+			"cp \"$CHANGED_FILES_NUL_FILE\" $out/changed.nul\n" +
+			"cp \"$ADDED_FILES_NUL_FILE\" $out/added.nul\n" +
+			"cp \"$MODIFIED_FILES_NUL_FILE\" $out/modified.nul\n" +
+			"cp \"$REMOVED_FILES_NUL_FILE\" $out/removed.nul\n" +
+			"env -0 > $out/env.tmp && mv $out/env.tmp $out/env\n",
+	}, "site:\n  name: blog\n"+
+		"scripts:\n  path: {{dir}}\n  push: deploy.sh\n"+
+		"outbound:\n  targets:\n    - name: receiver\n      url: "+server.URL+"\n      events: [deploy.finished]\n")
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 文件名中含有逗号与换行，只有 NUL 分隔的列表能够区分
+	body := []byte(`{
+		"ref": "refs/heads/main",
+		"before": "0000000000000000000000000000000000000001",
+		"after": "2222222222222222222222222222222222222222",
+		"repository": {"full_name": "owner/blog"},
+		"pusher": {"name": "alice"},
+		"commits": [
+			{"id": "1111111111111111111111111111111111111111", "message": "第一个提交", "added": ["source/_posts/a,b.md"], "modified": ["_config.yml"]},
+			{"id": "2222222222222222222222222222222222222222", "message": "第二个提交", "added": ["source/_posts/line\nbreak.md"], "modified": ["source/_posts/a,b.md"], "removed": ["source/_posts/old.md"]}
+		],
+		"head_commit": {"id": "2222222222222222222222222222222222222222", "message": "第二个提交", "added": ["source/_posts/line\nbreak.md"], "modified": ["source/_posts/a,b.md"], "removed": ["source/_posts/old.md"]}
+	}`)
+	status, response := send(t, "push", body, http.Header{"X-Github-Delivery": {"delivery-1"}})
+	if status != http.StatusOK || response["状态"] != "running" {
+		t.Fatalf("push: %d %v", status, response)
+	}
+	runID := response["运行ID"].(string)
+
+	out := filepath.Join(dir, "out")
+	env := envMap(waitFile(t, filepath.Join(out, "env")))
+	want := map[string]string{
+		"EVENT":       "push",
+		"DELIVERY_ID": "delivery-1",
+		"RUN_ID":      runID,
+		"SITE":        "blog",
+		"REPO":        "owner/blog",
+		"REF":         "refs/heads/main",
+		"BRANCH":      "main",
+		"BEFORE":      "0000000000000000000000000000000000000001",
+		"AFTER":       "2222222222222222222222222222222222222222",
+		"PUSHER":      "alice",
+		"COMMIT_ID":   "2222222222222222222222222222222222222222",
+	}
+	for name, value := range want {
+		if env[name] != value {
+			t.Errorf("%s = %q, want %q", name, env[name], value)
+		}
+	}
+
+	// 文件列表汇总推送中的所有提交，按新增、修改、删除的顺序
+	lists := map[string]string{
+		"changed.nul":  "source/_posts/a,b.md\x00source/_posts/line\nbreak.md\x00_config.yml\x00source/_posts/old.md\x00",
+		"changed.txt":  "source/_posts/a,b.md\nsource/_posts/line\nbreak.md\n_config.yml\nsource/_posts/old.md\n",
+		"added.nul":    "source/_posts/a,b.md\x00source/_posts/line\nbreak.md\x00",
+		"modified.nul": "_config.yml\x00source/_posts/a,b.md\x00",
+		"removed.nul":  "source/_posts/old.md\x00",
+	}
+	for name, want := range lists {
+		data, _ := os.ReadFile(filepath.Join(out, name))
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+
+	var payload struct {
+		Event      string `json:"event"`
+		DeliveryID string `json:"delivery_id"`
+		RunID      string `json:"run_id"`
+		Site       string `json:"site"`
+		Raw        struct {
+			Ref     string            `json:"ref"`
+			Commits []json.RawMessage `json:"commits"`
+		} `json:"raw"`
+		Normalized struct {
+			Ref        string     `json:"ref"`
+			HeadCommit HeadCommit `json:"head_commit"`
+		} `json:"normalized"`
+	}
+	data, _ := os.ReadFile(filepath.Join(out, "payload.json"))
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("payload.json: %v\n%s", err, data)
+	}
+	if payload.Event != "push" || payload.DeliveryID != "delivery-1" || payload.RunID != runID || payload.Site != "blog" ||
+		payload.Raw.Ref != "refs/heads/main" || len(payload.Raw.Commits) != 2 ||
+		payload.Normalized.Ref != "refs/heads/main" || payload.Normalized.HeadCommit.Message != "第二个提交" {
+		t.Errorf("payload.json = %s", data)
+	}
+
+	// deploy.finished 中的变更文件同样汇总所有提交
+	select {
+	case data := <-received:
+		var evt events.Event
+		if err := json.Unmarshal(data, &evt); err != nil {
+			t.Fatal(err)
+		}
+		wantFiles := events.ChangedFiles{
+			Added:    []string{"source/_posts/a,b.md", "source/_posts/line\nbreak.md"},
+			Modified: []string{"_config.yml", "source/_posts/a,b.md"},
+			Removed:  []string{"source/_posts/old.md"},
+		}
+		if evt.Type != events.TypeDeployFinished || evt.RunID != runID || evt.Outcome != events.OutcomeSuccess || !reflect.DeepEqual(evt.ChangedFiles, wantFiles) {
+			t.Errorf("deploy.finished = %s", data)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("deploy.finished not delivered")
+	}
+
+	// 等待投递记录写完，避免与临时目录的清理同时进行
+	deliveries := filepath.Join(dir, "logs", "deliveries.log")
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if data, _ := os.ReadFile(deliveries); strings.Contains(string(data), "delivered") {
+			break
+		}
+	}
+}
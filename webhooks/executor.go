@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/scripts"
+	"time"
+)
+
+// newExecutor 按 executor.type 创建执行器
+// 在容器中执行时，dirs 中的目录与 executor.docker.mounts 一起以相同路径挂载到容器中；在远程主机上执行时忽略 dirs
+func newExecutor(cfg config.Executor, ec scripts.ExecutorConfig, dirs ...string) (scripts.Backend, error) {
+	switch cfg.Type {
+	case "docker":
+		return newContainerExecutor(cfg.Docker, ec, dirs)
+	case "ssh":
+		return newSSHExecutor(cfg.SSH, ec)
+	default:
+		return scripts.NewDefaultExecutor(ec), nil
+	}
+}
+
+// newContainerExecutor 创建容器执行器
+func newContainerExecutor(cfg config.Docker, ec scripts.ExecutorConfig, dirs []string) (scripts.Backend, error) {
+	// 大小已在加载配置时校验过
+	memory, _ := config.ParseBytes(cfg.Memory)
+	mounts := append([]string{}, cfg.Mounts...)
+	for _, dir := range dirs {
+		if dir != "" {
+			mounts = append(mounts, dir)
+		}
+	}
+	return scripts.NewContainerExecutor(ec, scripts.ContainerConfig{
+		Host:     cfg.Host,
+		Image:    cfg.Image,
+		Pull:     cfg.Pull,
+		Mounts:   mounts,
+		User:     cfg.User,
+		Memory:   memory,
+		NanoCPUs: int64(cfg.CPUs * 1e9),
+		Network:  cfg.Network,
+		Env:      cfg.Env,
+	})
+}
+
+// newSSHExecutor 创建远程执行器
+func newSSHExecutor(cfg config.SSH, ec scripts.ExecutorConfig) (scripts.Backend, error) {
+	// 时间间隔已在加载配置时校验过
+	connectTimeout, _ := time.ParseDuration(cfg.ConnectTimeout)
+	hosts := make([]scripts.SSHHost, 0, len(cfg.Hosts))
+	for _, host := range cfg.Hosts {
+		hosts = append(hosts, scripts.SSHHost{
+			Name:    host.Name,
+			Address: host.Address,
+			User:    host.User,
+			Dir:     host.Dir,
+		})
+	}
+	return scripts.NewSSHExecutor(ec, scripts.SSHConfig{
+		Hosts:          hosts,
+		IdentityFile:   cfg.IdentityFile,
+		Agent:          cfg.Agent,
+		KnownHosts:     cfg.KnownHosts,
+		Parallel:       cfg.Strategy == "parallel",
+		ConnectTimeout: connectTimeout,
+		Env:            cfg.Env,
+	})
+}
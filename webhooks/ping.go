@@ -0,0 +1,185 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/redact"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// supportedEvents 服务能够处理的全部 GitHub 事件
+var supportedEvents = []string{"push", "pull_request", "release", "create", "ping"}
+
+// PingEvent GitHub 创建 Webhook 后发送的 ping 事件
+type PingEvent struct {
+	Zen    string `json:"zen"`
+	HookID int64  `json:"hook_id"`
+	Hook   struct {
+		Type   string   `json:"type"`
+		Events []string `json:"events"`
+		Active bool     `json:"active"`
+		Config struct {
+			ContentType string `json:"content_type"`
+			URL         string `json:"url"`
+		} `json:"config"`
+	} `json:"hook"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// pingSummary 描述该 Webhook 地址按当前配置会做什么
+type pingSummary struct {
+	Events   []string            `json:"接受的事件"`
+	Deploy   string              `json:"部署方式"`
+	Ready    bool                `json:"脚本就绪"`
+	Branches map[string][]string `json:"分支过滤,omitempty"`
+	Tags     map[string][]string `json:"标签过滤,omitempty"`
+	Warnings []string            `json:"警告,omitempty"`
+}
+
+// enabledEvents 返回按当前配置会处理的事件，及未启用的事件对应的配置项
+func enabledEvents(preview config.Preview, release, tag config.Trigger) ([]string, map[string]string) {
+	events := []string{"ping", "push"}
+	disabled := map[string]string{}
+	if preview.Enabled && preview.PullRequests {
+		events = append(events, "pull_request")
+	} else {
+		disabled["pull_request"] = "preview.pull_requests"
+	}
+	if release.Enabled {
+		events = append(events, "release")
+	} else {
+		disabled["release"] = "triggers.release.enabled"
+	}
+	if tag.Enabled {
+		events = append(events, "create")
+	} else {
+		disabled["create"] = "triggers.tag.enabled"
+	}
+	return events, disabled
+}
+
+// summarize 汇总当前配置，并把 Webhook 订阅的事件与服务支持的事件对比
+func summarize(e PingEvent) pingSummary {
+	cfg := config.Get()
+	events, disabled := enabledEvents(cfg.Preview, cfg.Triggers.Release, cfg.Triggers.Tag)
+	s := pingSummary{Events: events}
+
+	// 部署方式与脚本是否存在
+	switch {
+	case len(cfg.Pipeline.Steps) > 0:
+		s.Deploy = fmt.Sprintf("流水线，%d 个步骤", len(cfg.Pipeline.Steps))
+		s.Ready = true
+	case cfg.Scripts.Push != "":
+		script := filepath.Join(cfg.Scripts.Path, cfg.Scripts.Push)
+		s.Deploy = "脚本 " + script
+		info, err := os.Stat(script)
+		s.Ready = err == nil && info.Mode().IsRegular()
+		if !s.Ready {
+			s.Warnings = append(s.Warnings, "部署脚本不存在: "+script)
+		}
+	default:
+		s.Deploy = "未配置"
+		s.Warnings = append(s.Warnings, "未配置 scripts.push 或 pipeline.steps")
+	}
+	if cfg.Pipeline.AllowRepoFile {
+		s.Deploy += fmt.Sprintf("，仓库中的 %s 优先", cfg.Pipeline.File)
+	}
+
+	// 分支与标签过滤
+	s.Branches = map[string][]string{}
+	if cfg.Git.Enabled && len(cfg.Git.Branches) > 0 {
+		s.Branches["git.branches"] = cfg.Git.Branches
+	}
+	if cfg.Preview.Enabled {
+		s.Branches["preview.production"] = cfg.Preview.Production
+		if len(cfg.Preview.Branches) > 0 {
+			s.Branches["preview.branches"] = cfg.Preview.Branches
+		}
+	}
+	for _, step := range cfg.Pipeline.Steps {
+		if len(step.When.Branches) > 0 {
+			s.Branches["pipeline.steps."+step.Name] = step.When.Branches
+		}
+	}
+	s.Tags = map[string][]string{}
+	if t := cfg.Triggers.Release; t.Enabled && len(t.Tags) > 0 {
+		s.Tags["triggers.release.tags"] = t.Tags
+	}
+	if t := cfg.Triggers.Tag; t.Enabled && len(t.Tags) > 0 {
+		s.Tags["triggers.tag.tags"] = t.Tags
+	}
+
+	// 对比 Webhook 订阅的事件
+	for _, event := range e.Hook.Events {
+		switch {
+		case event == "*":
+			s.Warnings = append(s.Warnings, "Webhook 订阅了所有事件，不支持的事件会返回 400，建议只选择需要的事件")
+		case !slices.Contains(supportedEvents, event):
+			s.Warnings = append(s.Warnings, fmt.Sprintf("Webhook 订阅了不支持的 %s 事件，收到时会返回 400", event))
+		case disabled[event] != "":
+			s.Warnings = append(s.Warnings, fmt.Sprintf("Webhook 订阅了 %s 事件，但未启用 %s", event, disabled[event]))
+		}
+	}
+	if len(e.Hook.Events) > 0 && !slices.Contains(e.Hook.Events, "*") {
+		for _, event := range events {
+			if event != "ping" && !slices.Contains(e.Hook.Events, event) {
+				s.Warnings = append(s.Warnings, fmt.Sprintf("已启用 %s 事件，但 Webhook 没有订阅", event))
+			}
+		}
+	}
+	if ct := e.Hook.Config.ContentType; ct != "" && ct != "json" {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("Webhook 的 Content type 为 %s，应设置为 application/json", ct))
+	}
+	return s
+}
+
+// handlePingEvent 响应 GitHub 的 ping 事件，返回当前配置的摘要
+func handlePingEvent(c *gin.Context, body []byte) {
+	// Content type 误选为 form 时载荷在 payload 字段中，解析出来以便在摘要中提示
+	if c.ContentType() == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			body = []byte(values.Get("payload"))
+		}
+	}
+
+	var event PingEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		logger.WithError(err).Error("无法解析 ping 事件数据")
+		c.JSON(http.StatusBadRequest, gin.H{"错误": "无法解析 ping 事件数据"})
+		return
+	}
+
+	summary := ping(event)
+	c.JSON(http.StatusOK, gin.H{
+		"消息":   "pong",
+		"钩子ID": event.HookID,
+		"禅语":   event.Zen,
+		"配置":   redact.Value(summary),
+	})
+}
+
+// ping 汇总配置并记录日志，订阅的事件与配置不一致时记录警告
+func ping(event PingEvent) pingSummary {
+	summary := summarize(event)
+	pingLogger := logger.WithFields(logrus.Fields{
+		"钩子ID": event.HookID,
+		"仓库":   event.Repository.FullName,
+		"订阅事件": event.Hook.Events,
+	})
+	pingLogger.WithField("禅语", event.Zen).Info("收到 ping 事件，Webhook 配置成功")
+	for _, warning := range summary.Warnings {
+		pingLogger.Warn(warning)
+	}
+	return summary
+}
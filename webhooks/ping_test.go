@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// pingBody 返回订阅了 events 的 ping 事件
+func pingBody(contentType string, events ...string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"zen":     "Keep it logically awesome.",
+		"hook_id": 42,
+		"hook": map[string]interface{}{
+			"type":   "Repository",
+			"events": events,
+			"active": true,
+			"config": map[string]interface{}{"content_type": contentType, "url": "https://blog.example.com/webhook"},
+		},
+		"repository": map[string]interface{}{"full_name": "owner/blog"},
+	})
+	return body
+}
+
+// pingSummaryOf 发送 ping 事件，返回响应中的配置摘要
+func pingSummaryOf(t *testing.T, body []byte, header http.Header) pingSummary {
+	t.Helper()
+	status, response := send(t, "ping", body, header)
+	if status != http.StatusOK || response["消息"] != "pong" || response["钩子ID"] != float64(42) || response["禅语"] != "Keep it logically awesome." {
+		t.Fatalf("ping: %d %v", status, response)
+	}
+	data, _ := json.Marshal(response["配置"])
+	var summary pingSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+func TestPing(t *testing.T) {
+	dir := useConfig(t, map[string]string{"deploy.sh": "#!/bin/bash\n", "tag.sh": "#!/bin/bash\n"},
+		"scripts:\n  path: {{dir}}\n  push: deploy.sh\n"+
+			"triggers:\n  tag:\n    enabled: true\n    tags: [\"v*\"]\n    script: tag.sh\n")
+
+	got := pingSummaryOf(t, pingBody("json", "push", "release", "issues", "create"), nil)
+	want := pingSummary{
+		Events:   []string{"ping", "push", "create"},
+		Deploy:   "脚本 " + filepath.Join(dir, "deploy.sh"),
+		Ready:    true,
+		Branches: map[string][]string{},
+		Tags:     map[string][]string{"triggers.tag.tags": {"v*"}},
+		Warnings: []string{
+			"Webhook 订阅了 release 事件，但未启用 triggers.release.enabled",
+			"Webhook 订阅了不支持的 issues 事件，收到时会返回 400",
+		},
+	}
+	if got.Branches == nil {
+		got.Branches = map[string][]string{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ping summary = %+v, want %+v", got, want)
+	}
+
+	// 已启用但没有订阅的事件
+	got = pingSummaryOf(t, pingBody("json", "push"), nil)
+	if want := []string{"已启用 create 事件，但 Webhook 没有订阅"}; !reflect.DeepEqual(got.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", got.Warnings, want)
+	}
+
+	got = pingSummaryOf(t, pingBody("json", "*"), nil)
+	if want := []string{"Webhook 订阅了所有事件，不支持的事件会返回 400，建议只选择需要的事件"}; !reflect.DeepEqual(got.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", got.Warnings, want)
+	}
+}
+
+func TestPingMissingScriptForm(t *testing.T) {
+	dir := useConfig(t, map[string]string{}, "scripts:\n  path: {{dir}}\n  push: deploy.sh\n")
+
+	// Content type 误选为 form 时载荷在 payload 字段中
+	body := []byte("payload=" + url.QueryEscape(string(pingBody("form", "push"))))
+	got := pingSummaryOf(t, body, http.Header{"Content-Type": {"application/x-www-form-urlencoded"}})
+	want := []string{
+		"部署脚本不存在: " + filepath.Join(dir, "deploy.sh"),
+		"Webhook 的 Content type 为 form，应设置为 application/json",
+	}
+	if got.Ready || !reflect.DeepEqual(got.Warnings, want) {
+		t.Errorf("ping summary = %+v, want warnings %q", got, want)
+	}
+}
@@ -0,0 +1,372 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/events"
+	"Hexo-AutoCD/git"
+	"Hexo-AutoCD/glob"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/pipeline"
+	"Hexo-AutoCD/preview"
+	"Hexo-AutoCD/redact"
+	"Hexo-AutoCD/scripts"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PullRequestEvent GitHub pull_request 事件中用到的字段
+type PullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			Ref  string `json:"ref"`
+			SHA  string `json:"sha"`
+			Repo struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// previewTarget 描述一次预览的构建或删除
+type previewTarget struct {
+	Name        string
+	Branch      string
+	Commit      string
+	Message     string
+	PullRequest int    // 拉取请求编号，分支预览时为 0
+	Remove      bool   // 分支被删除或拉取请求已关闭，删除预览
+	Skip        string // 既不是生产分支也不在 preview.branches 中时，不部署的原因
+
+	Delivery Delivery // 收到的原始投递
+}
+
+// previewForPush 判断推送是否应构建或删除预览，推送到生产分支时返回 false
+func previewForPush(cfg config.Preview, e PushEvent) (previewTarget, bool) {
+	if !cfg.Enabled || !strings.HasPrefix(e.Ref, "refs/heads/") {
+		return previewTarget{}, false
+	}
+	branch := strings.TrimPrefix(e.Ref, "refs/heads/")
+	if glob.MatchAny(cfg.Production, branch) {
+		return previewTarget{}, false
+	}
+	t := previewTarget{
+		Name:    preview.Name(branch),
+		Branch:  branch,
+		Commit:  e.After,
+		Message: e.HeadCommit.Message,
+		Remove:  e.Deleted || e.After == "0000000000000000000000000000000000000000",
+
+		Delivery: e.Delivery.with(e),
+	}
+	if t.Commit == "" {
+		t.Commit = e.HeadCommit.ID
+	}
+	if !t.Remove && len(cfg.Branches) > 0 && !glob.MatchAny(cfg.Branches, branch) {
+		t.Skip = fmt.Sprintf("分支 %s 不是生产分支，也不在 preview.branches 中", branch)
+	}
+	return t, true
+}
+
+// previewForPullRequest 把拉取请求事件转换为预览，不需要处理的动作返回 false
+// 来自 fork 的拉取请求不构建，避免在服务器上执行他人提交的代码
+func previewForPullRequest(e PullRequestEvent, d Delivery) (previewTarget, bool, error) {
+	t := previewTarget{
+		Name:        preview.PullRequestName(e.Number),
+		Branch:      e.PullRequest.Head.Ref,
+		Commit:      e.PullRequest.Head.SHA,
+		Message:     e.PullRequest.Title,
+		PullRequest: e.Number,
+		Delivery:    d.with(e),
+	}
+	switch e.Action {
+	case "opened", "reopened", "synchronize":
+		if e.PullRequest.Head.Repo.FullName != e.Repository.FullName {
+			return t, false, fmt.Errorf("拉取请求 #%d 来自 fork 仓库 %s，不构建预览", e.Number, e.PullRequest.Head.Repo.FullName)
+		}
+		return t, true, nil
+	case "closed":
+		t.Remove = true
+		return t, true, nil
+	}
+	return t, false, nil
+}
+
+// handlePullRequestEvent 处理 pull_request 事件
+func handlePullRequestEvent(c *gin.Context, delivery Delivery) {
+	cfg := config.Get().Preview
+	if !cfg.Enabled || !cfg.PullRequests {
+		c.JSON(http.StatusBadRequest, gin.H{"错误": "未启用拉取请求预览"})
+		return
+	}
+
+	var event PullRequestEvent
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		logger.WithError(err).Error("无法解析 pull_request 事件数据")
+		c.JSON(http.StatusBadRequest, gin.H{"错误": "无法解析 pull_request 事件数据"})
+		return
+	}
+	target, ok, err := previewForPullRequest(event, delivery)
+	if err != nil {
+		logger.WithField("拉取请求", event.Number).Warn(err.Error())
+		c.JSON(http.StatusOK, gin.H{"消息": redact.String(err.Error())})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"消息": fmt.Sprintf("忽略 %s 动作", event.Action)})
+		return
+	}
+	startPreview(c, target)
+}
+
+// startPreview 立即返回响应并在后台构建或删除预览
+func startPreview(c *gin.Context, target previewTarget) {
+	runID := NewRunID()
+	status := "building"
+	if target.Remove {
+		status = "removing"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"消息":   "预览开始处理",
+		"状态":   status,
+		"运行ID": runID,
+		"预览":   target.Name,
+		"地址":   preview.URL(config.Get().Preview.URL, target.Name),
+	})
+	go runPreview(target, runID)
+}
+
+// runPreview 构建或删除预览
+func runPreview(target previewTarget, runID string) (*scripts.ExecutionResult, error) {
+	if target.Remove {
+		return nil, RemovePreview(target, runID)
+	}
+	return DeployPreview(target, runID)
+}
+
+// DeployPreview 把分支构建到独立的预览目录中，结束后通知下游系统预览地址
+func DeployPreview(target previewTarget, runID string) (*scripts.ExecutionResult, error) {
+	cfg := config.Get()
+	m := preview.New(cfg.Preview)
+	link := preview.URL(cfg.Preview.URL, target.Name)
+
+	unlock := preview.Lock(target.Name)
+	defer unlock()
+
+	previewLogger := logger.WithFields(logrus.Fields{
+		"运行ID": runID,
+		"预览":   target.Name,
+		"分支":   target.Branch,
+		"提交ID": shortID(target.Commit),
+	})
+	previewLogger.Info("开始构建预览")
+	startTime := time.Now()
+
+	evt := events.NewEvent(events.TypePreviewDeployed)
+	evt.RunID = runID
+	evt.Commit = target.Commit
+	evt.Data = previewData(target, link)
+	defer func() {
+		evt.DurationMs = time.Since(startTime).Milliseconds()
+		events.Publish(evt)
+	}()
+	fail := func(result *scripts.ExecutionResult, err error) (*scripts.ExecutionResult, error) {
+		evt.Outcome = events.OutcomeFailure
+		evt.ExitCode = -1
+		evt.Error = err.Error()
+		if result != nil {
+			evt.ExitCode = result.ExitCode
+			evt.Data["steps"] = result.Steps
+		}
+		previewLogger.WithError(err).Error("构建预览失败")
+		return result, err
+	}
+
+	// 在独立的工作树中检出分支，部署用的仓库与生成目录保持不变
+	// 配置校验要求启用 preview 时同时启用 git，这里再检查一次，避免在生产目录中构建预览
+	if !cfg.Git.Enabled {
+		return fail(nil, fmt.Errorf("预览需要启用 git.enabled"))
+	}
+	gitTimeout, _ := time.ParseDuration(cfg.Git.Timeout)
+	var verify *git.VerifyPolicy
+	if v := cfg.Git.VerifySignatures; v.Enabled {
+		// 预览只校验最新提交
+		verify = &git.VerifyPolicy{GPGHome: v.GPGHome, AllowedSigners: v.AllowedSigners}
+	}
+	baseDir := m.Worktree(target.Name)
+	step, err := checkoutPreview(cfg.Site.RepoDir, cfg.Git.Remote, gitTimeout, verify, baseDir, target)
+	steps := []scripts.StepResult{step}
+	if err != nil {
+		return fail(&scripts.ExecutionResult{ExitCode: -1, Error: err.Error(), Steps: steps}, err)
+	}
+
+	build, err := m.Prepare(target.Name)
+	if err != nil {
+		return fail(nil, err)
+	}
+
+	payload := target.Delivery.payload(runID, cfg.Site.Name)
+	payload.Ref, payload.Branch, payload.After = "refs/heads/"+target.Branch, target.Branch, target.Commit
+
+	timeout, _ := time.ParseDuration(cfg.Scripts.Timeout)
+	backend, err := newExecutor(cfg.Executor, scripts.ExecutorConfig{
+		ScriptsPath:   cfg.Scripts.Path,
+		Timeout:       timeout,
+		MaxConcurrent: 5,
+		DefaultEnv: []string{
+			"COMMIT_ID=" + target.Commit,
+			"COMMIT_MESSAGE=" + target.Message,
+			"PREVIEW_NAME=" + target.Name,
+			"PREVIEW_BRANCH=" + target.Branch,
+			"PREVIEW_PR=" + prNumber(target.PullRequest),
+			"PREVIEW_URL=" + link,
+			"PREVIEW_ROOT=" + preview.Root(link),
+			"PREVIEW_DIR=" + build,
+		},
+		Payload: payload,
+		Sandbox: pipeline.Sandbox(cfg.Scripts.Sandbox),
+	}, baseDir, build)
+	if err != nil {
+		m.Discard(build)
+		return fail(nil, err)
+	}
+	defer backend.Close()
+	executor := pipeline.New(backend, pipeline.Config{
+		Steps:   cfg.Preview.Steps,
+		BaseDir: baseDir,
+		RepoDir: baseDir,
+		Retry:   cfg.Scripts.Retry,
+	})
+	result, err := executor.Execute("", &pipeline.Context{Branch: target.Branch, Messages: []string{target.Message}})
+	if result != nil {
+		result.Steps = append(steps, result.Steps...)
+	}
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("%s", result.Error)
+	}
+	if err == nil {
+		err = m.Activate(target.Name, build)
+	}
+	if err != nil {
+		m.Discard(build)
+		return fail(result, err)
+	}
+
+	evt.Outcome = events.OutcomeSuccess
+	evt.Data["steps"] = result.Steps
+	previewLogger.WithField("地址", link).Info("预览构建成功")
+	return result, nil
+}
+
+// RemovePreview 删除预览目录与工作树，并通知下游系统
+func RemovePreview(target previewTarget, runID string) error {
+	cfg := config.Get()
+	m := preview.New(cfg.Preview)
+
+	unlock := preview.Lock(target.Name)
+	defer unlock()
+
+	previewLogger := logger.WithFields(logrus.Fields{
+		"运行ID": runID,
+		"预览":   target.Name,
+		"分支":   target.Branch,
+	})
+	existed, err := m.Remove(target.Name)
+	if err == nil && cfg.Git.Enabled {
+		gitTimeout, _ := time.ParseDuration(cfg.Git.Timeout)
+		err = git.RemoveWorktree(cfg.Site.RepoDir, m.Worktree(target.Name), gitTimeout)
+	}
+	if err != nil {
+		previewLogger.WithError(err).Error("删除预览失败")
+		return err
+	}
+	if !existed {
+		previewLogger.Info("预览不存在，无需删除")
+		return nil
+	}
+
+	evt := events.NewEvent(events.TypePreviewRemoved)
+	evt.RunID = runID
+	evt.Outcome = events.OutcomeSuccess
+	evt.Data = previewData(target, preview.URL(cfg.Preview.URL, target.Name))
+	events.Publish(evt)
+	previewLogger.Info("预览已删除")
+	return nil
+}
+
+// checkoutPreview 在预览的工作树 dir 中检出提交，结果作为一个步骤记录在执行结果中
+func checkoutPreview(repoDir, remote string, timeout time.Duration, verify *git.VerifyPolicy, dir string, target previewTarget) (scripts.StepResult, error) {
+	startTime := time.Now()
+	step := scripts.StepResult{
+		Name:      checkoutStep,
+		StartedAt: startTime.Format(time.RFC3339),
+		Attempts:  1,
+	}
+
+	result, err := git.Worktree(git.Options{
+		Dir:     repoDir,
+		Remote:  remote,
+		Branch:  target.Branch,
+		Commit:  target.Commit,
+		Timeout: timeout,
+		Verify:  verify,
+	}, dir)
+	step.DurationMs = time.Since(startTime).Milliseconds()
+	if result != nil {
+		step.Output = signatureReport(result.Signatures)
+	}
+	if err != nil {
+		step.Status = scripts.StepFailed
+		step.ExitCode = -1
+		step.Error = err.Error()
+		step.ErrorKind = git.Kind(err)
+		logger.WithFields(logrus.Fields{
+			"工作树":  dir,
+			"错误类型": step.ErrorKind,
+		}).WithError(err).Error("检出预览分支失败")
+		return step, err
+	}
+	step.Status = scripts.StepSuccess
+	step.Output += "HEAD " + result.Head + "\n"
+	return step, nil
+}
+
+// previewData 预览事件的附加信息
+func previewData(target previewTarget, link string) map[string]interface{} {
+	data := map[string]interface{}{
+		"name":   target.Name,
+		"url":    link,
+		"branch": target.Branch,
+	}
+	if target.PullRequest > 0 {
+		data["pull_request"] = target.PullRequest
+	}
+	return data
+}
+
+func prNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func shortID(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
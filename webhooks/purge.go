@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/git"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/posts"
+	"Hexo-AutoCD/purge"
+	"Hexo-AutoCD/scripts"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// purgeStep 刷新 CDN 缓存在执行结果中的步骤名称
+const purgeStep = "purge"
+
+// purgeCache 刷新推送中变更的文章所影响的页面，结果作为一个步骤记录在执行结果中
+// publicDir 为本次生成的站点目录，用于找出列表页的分页；站点此时已经更新，刷新失败只把该步骤记为失败，不影响部署结果
+func purgeCache(cfg config.Purge, postsCfg config.Posts, repoDir, publicDir, before string, changed []string) scripts.StepResult {
+	startTime := time.Now()
+	step := scripts.StepResult{
+		Name:      purgeStep,
+		StartedAt: startTime.Format(time.RFC3339),
+		Attempts:  1,
+	}
+
+	paths, warnings := purge.Paths(cfg, postsCfg.Lint.DateFormats, publicDir, changedPosts(postsCfg, repoDir, before, changed))
+	results := purge.New(cfg).Purge(paths)
+	var b strings.Builder
+	for _, w := range warnings {
+		fmt.Fprintf(&b, "! %s\n", w)
+	}
+	for _, r := range results {
+		fmt.Fprintln(&b, r.String())
+	}
+	step.Output = b.String()
+	step.DurationMs = time.Since(startTime).Milliseconds()
+
+	failed := purge.Failed(results)
+	purgeLogger := logger.WithFields(logrus.Fields{
+		"页面数量": len(paths),
+		"刷新数量": len(results),
+	})
+	if len(failed) == 0 {
+		step.Status = scripts.StepSuccess
+		purgeLogger.Info("CDN 缓存刷新完成")
+		return step
+	}
+	step.Status = scripts.StepFailed
+	step.ExitCode = 1
+	step.Error = fmt.Sprintf("%d 个 URL 刷新失败，首个失败: %s", len(failed), failed[0].String())
+	purgeLogger.WithField("失败数量", len(failed)).Error("部分 CDN 缓存刷新失败")
+	return step
+}
+
+// changedPosts 读取变更文章推送前后的内容
+// 推送后的内容从文章目录读取（设置了输出目录时读取预处理后的文章），推送前的内容从仓库中 before 提交读取
+func changedPosts(postsCfg config.Posts, repoDir, before string, changed []string) []purge.Post {
+	var result []purge.Post
+	for _, file := range changed {
+		names := posts.Relative(postsCfg.SourceDir, repoDir, []string{file})
+		if len(names) == 0 || !posts.IsPost(names[0]) {
+			continue
+		}
+		post := purge.Post{Name: names[0]}
+
+		dir, name := postsCfg.SourceDir, post.Name
+		if postsCfg.DestDir != "" {
+			dir, name = postsCfg.DestDir, path.Base(post.Name)
+		}
+		if content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+			post.After = content
+		}
+		if repoDir != "" {
+			if content, err := git.Show(repoDir, before, file, 0); err == nil {
+				post.Before = content
+			}
+		}
+		result = append(result, post)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/release"
+	"Hexo-AutoCD/scripts"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// releaseStep 发布步骤在执行结果中的名称
+const releaseStep = "release"
+
+// publishRelease 把构建结果写入版本目录、切换 current 并清理旧版本，结果作为一个步骤记录在执行结果中
+// 切换前失败时删除该版本目录，站点保持原来的版本；成功时返回切换前的版本
+func publishRelease(m *release.Manager, r *release.Release) (scripts.StepResult, string, error) {
+	startTime := time.Now()
+	step := scripts.StepResult{
+		Name:      releaseStep,
+		StartedAt: startTime.Format(time.RFC3339),
+		Attempts:  1,
+	}
+	releaseLogger := logger.WithFields(logrus.Fields{
+		"版本":   r.ID,
+		"发布目录": m.Dir,
+	})
+
+	fail := func(err error) (scripts.StepResult, string, error) {
+		step.DurationMs = time.Since(startTime).Milliseconds()
+		step.Status = scripts.StepFailed
+		step.ExitCode = 1
+		step.Error = err.Error()
+		releaseLogger.WithError(err).Error("发布失败")
+		return step, "", err
+	}
+
+	if err := m.Publish(r); err != nil {
+		m.Discard(r)
+		return fail(err)
+	}
+	previous, err := m.Activate(r.ID)
+	if err != nil {
+		m.Discard(r)
+		return fail(err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "current -> %s（原版本 %s）\n", r.ID, orNone(previous))
+	removed, err := m.Prune()
+	if len(removed) > 0 {
+		fmt.Fprintf(&b, "删除旧版本: %s\n", strings.Join(removed, ", "))
+	}
+	if err != nil {
+		// 站点已切换到新版本，清理失败只记录警告
+		releaseLogger.WithError(err).Warn("清理旧版本失败")
+		fmt.Fprintf(&b, "清理旧版本失败: %v\n", err)
+	}
+
+	step.Output = b.String()
+	step.DurationMs = time.Since(startTime).Milliseconds()
+	step.Status = scripts.StepSuccess
+	releaseLogger.WithFields(logrus.Fields{
+		"原版本":   orNone(previous),
+		"删除旧版本": len(removed),
+	}).Info("新版本已发布")
+	return step, previous, nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "无"
+	}
+	return s
+}
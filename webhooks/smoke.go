@@ -0,0 +1,95 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/release"
+	"Hexo-AutoCD/scripts"
+	"Hexo-AutoCD/smoke"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 冒烟检查与自动回滚在执行结果中的步骤名称
+const (
+	smokeStep    = "smoke"
+	rollbackStep = "rollback"
+)
+
+// smokeCheck 执行部署后的冒烟检查，结果作为一个步骤记录在执行结果中
+func smokeCheck(checker *smoke.Checker, publicDir string, changed []string) (scripts.StepResult, error) {
+	startTime := time.Now()
+	step := scripts.StepResult{
+		Name:      smokeStep,
+		StartedAt: startTime.Format(time.RFC3339),
+		Attempts:  1,
+	}
+
+	results := checker.Run(publicDir, changed)
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintln(&b, r.String())
+	}
+	step.Output = b.String()
+	step.DurationMs = time.Since(startTime).Milliseconds()
+
+	failed := smoke.Failed(results)
+	if len(failed) == 0 {
+		step.Status = scripts.StepSuccess
+		logger.WithField("检查数量", len(results)).Info("冒烟检查通过")
+		return step, nil
+	}
+
+	names := make([]string, 0, len(failed))
+	for _, r := range failed {
+		names = append(names, r.Name)
+	}
+	err := fmt.Errorf("%d 项检查未通过: %s", len(failed), strings.Join(names, "、"))
+	step.Status = scripts.StepFailed
+	step.ExitCode = 1
+	step.Error = err.Error()
+	logger.WithFields(logrus.Fields{
+		"检查数量":  len(results),
+		"未通过数量": len(failed),
+		"首个失败":  failed[0].String(),
+	}).Error("冒烟检查未通过")
+	return step, err
+}
+
+// rollbackRelease 冒烟检查未通过时把 current 切换回发布前的版本，结果作为一个步骤记录在执行结果中
+// 第一次发布时没有可以回滚的版本，步骤记为跳过
+func rollbackRelease(m *release.Manager, failed, previous string) scripts.StepResult {
+	startTime := time.Now()
+	step := scripts.StepResult{
+		Name:      rollbackStep,
+		StartedAt: startTime.Format(time.RFC3339),
+		Attempts:  1,
+	}
+	rollbackLogger := logger.WithFields(logrus.Fields{
+		"未通过的版本": failed,
+		"回滚到":    previous,
+	})
+
+	if previous == "" {
+		step.Status = scripts.StepSkipped
+		step.Reason = "没有可以回滚的旧版本"
+		rollbackLogger.Warn("冒烟检查未通过，但没有可以回滚的旧版本")
+		return step
+	}
+
+	_, err := m.Activate(previous)
+	step.DurationMs = time.Since(startTime).Milliseconds()
+	if err != nil {
+		step.Status = scripts.StepFailed
+		step.ExitCode = 1
+		step.Error = err.Error()
+		rollbackLogger.WithError(err).Error("自动回滚失败")
+		return step
+	}
+	step.Status = scripts.StepSuccess
+	step.Output = fmt.Sprintf("current -> %s（未通过检查的版本 %s 已保留）\n", previous, failed)
+	rollbackLogger.Warn("冒烟检查未通过，已自动回滚")
+	return step
+}
@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/release"
+	"Hexo-AutoCD/scripts"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	logger.Log = logrus.New()
+	logger.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// useConfig 在临时目录中写入文件与配置并使其生效，{{dir}} 替换为该目录，测试结束后恢复原来的配置
+// 以 .sh 结尾的文件可执行
+func useConfig(t *testing.T, files map[string]string, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["config.yaml"] = "webhook:\n  secret: 0123456789abcdef0123\nlogs:\n  path: {{dir}}/logs/webhooks.log\n" + content
+	for name, data := range files {
+		perm := os.FileMode(0644)
+		if strings.HasSuffix(name, ".sh") {
+			perm = 0755
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.ReplaceAll(data, "{{dir}}", dir)), perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := config.Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.Get()
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+	return dir
+}
+
+// stepStatus 返回执行结果中指定步骤的状态，没有该步骤时返回空字符串
+func stepStatus(result *scripts.ExecutionResult, name string) string {
+	for _, step := range result.Steps {
+		if step.Name == name {
+			return step.Status
+		}
+	}
+	return ""
+}
+
+func TestDeployRollsBackOnSmokeFailure(t *testing.T) {
+	// 站点直接提供 current 指向的版本目录
+	var root string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.FileServer(http.Dir(filepath.Join(root, "site", "current"))).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	root = useConfig(t, map[string]string{
+		// 把提交信息写入首页，提交信息为 损坏 时首页不包含检查的内容
+		"deploy.sh": "#!/bin/bash\nprintf '<h1>%s</h1>' \"$COMMIT_MESSAGE\" > \"$RELEASE_DIR/index.html\"\n",
+	}, "scripts:\n  path: {{dir}}\n  push: deploy.sh\n"+
+		"releases:\n  enabled: true\n  dir: {{dir}}/site\n"+
+		"smoke:\n  enabled: true\n  base_url: "+server.URL+"\n  checks:\n    - path: /\n      status: 200\n      contains: 正常\n")
+	releases := release.New(config.Get().Releases)
+
+	push := func(commit, message string) *scripts.ExecutionResult {
+		t.Helper()
+		event := PushEvent{Ref: "refs/heads/main", After: commit, HeadCommit: HeadCommit{ID: commit, Message: message}}
+		result, err := DeployPush(event, NewRunID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	good := push("1111111111111111111111111111111111111111", "正常")
+	if good.ExitCode != 0 || stepStatus(good, smokeStep) != scripts.StepSuccess {
+		t.Fatalf("first deploy exit code %d, error %q, steps %+v", good.ExitCode, good.Error, good.Steps)
+	}
+	first, _ := releases.Current()
+	if first == "" {
+		t.Fatal("current not set after first deploy")
+	}
+
+	bad := push("2222222222222222222222222222222222222222", "损坏")
+	if bad.ExitCode == 0 || !strings.Contains(bad.Error, smokeStep) {
+		t.Errorf("failing deploy exit code %d, error %q", bad.ExitCode, bad.Error)
+	}
+	if got := stepStatus(bad, smokeStep); got != scripts.StepFailed {
+		t.Errorf("smoke step = %q, want %s", got, scripts.StepFailed)
+	}
+	if got := stepStatus(bad, rollbackStep); got != scripts.StepSuccess {
+		t.Errorf("rollback step = %q, want %s", got, scripts.StepSuccess)
+	}
+	if current, _ := releases.Current(); current != first {
+		t.Errorf("current = %s after failed smoke check, want %s", current, first)
+	}
+	// 未通过检查的版本保留下来，便于排查
+	list, _ := releases.List()
+	if len(list) != 2 {
+		t.Errorf("releases = %+v, want 2", list)
+	}
+}
+
+func TestRollbackWithoutPrevious(t *testing.T) {
+	m := &release.Manager{Dir: t.TempDir(), Keep: 5}
+	step := rollbackRelease(m, "20240101-000000-aaaaaaa", "")
+	if step.Status != scripts.StepSkipped {
+		t.Errorf("rollback without previous release = %q, want %s", step.Status, scripts.StepSkipped)
+	}
+}
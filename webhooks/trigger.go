@@ -0,0 +1,205 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/glob"
+	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/scripts"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ReleaseEvent GitHub release 事件中用到的字段
+type ReleaseEvent struct {
+	Action  string `json:"action"`
+	Release struct {
+		TagName         string         `json:"tag_name"`
+		Name            string         `json:"name"`
+		Body            string         `json:"body"`
+		HTMLURL         string         `json:"html_url"`
+		TargetCommitish string         `json:"target_commitish"`
+		Draft           bool           `json:"draft"`
+		Prerelease      bool           `json:"prerelease"`
+		Assets          []ReleaseAsset `json:"assets"`
+	} `json:"release"`
+}
+
+// ReleaseAsset release 中的附件
+type ReleaseAsset struct {
+	Name        string `json:"name"`
+	URL         string `json:"browser_download_url"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// CreateEvent GitHub create 事件中用到的字段
+type CreateEvent struct {
+	Ref     string `json:"ref"`      // 标签或分支名，不含 refs/ 前缀
+	RefType string `json:"ref_type"` // tag 或 branch
+}
+
+// trigger 描述由 release 或 create 事件触发的一次部署
+type trigger struct {
+	Event   string        // release 或 tag，与 releases.events 中的取值对应
+	Source  string        // 使用的配置项，如 triggers.release
+	Action  string        // release 事件的动作
+	Tag     string        // 标签名
+	Name    string        // release 的标题
+	Script  string        // 未配置步骤时执行的脚本
+	Steps   []config.Step // 流水线步骤
+	Env     []string      // 导出给脚本的环境变量
+	Publish bool          // 是否检出标签并发布，release 被删除或撤回时只执行脚本
+
+	Delivery Delivery // 收到的原始投递
+}
+
+// data 返回出站事件中的附加数据
+func (t *trigger) data() map[string]interface{} {
+	data := map[string]interface{}{"trigger": t.Event, "tag": t.Tag}
+	if t.Event == "release" {
+		data["action"] = t.Action
+		data["name"] = t.Name
+	}
+	return data
+}
+
+// pushEvent 把触发的部署转换为推送事件，复用推送部署的检出与发布流程
+func (t *trigger) pushEvent() PushEvent {
+	message := t.Name
+	if message == "" {
+		message = t.Tag
+	}
+	return PushEvent{Ref: "refs/tags/" + t.Tag, HeadCommit: HeadCommit{Message: message}, Delivery: t.Delivery}
+}
+
+// triggerForRelease 把 release 事件转换为部署，不需要部署时返回原因
+func triggerForRelease(cfg config.Trigger, e ReleaseEvent, d Delivery) (*trigger, string) {
+	r := e.Release
+	switch {
+	case !cfg.Enabled:
+		return nil, "未启用 release 部署"
+	case r.TagName == "":
+		return nil, "release 事件中没有标签"
+	case !slices.Contains(cfg.Actions, e.Action):
+		return nil, fmt.Sprintf("忽略 release 的 %s 动作", e.Action)
+	case r.Draft && e.Action != "deleted":
+		return nil, fmt.Sprintf("release %s 是草稿，不部署", r.TagName)
+	case len(cfg.Tags) > 0 && !glob.MatchAny(cfg.Tags, r.TagName):
+		return nil, fmt.Sprintf("标签 %s 不在 triggers.release.tags 中", r.TagName)
+	}
+
+	assets, _ := json.Marshal(r.Assets)
+	if r.Assets == nil {
+		assets = []byte("[]")
+	}
+	return &trigger{
+		Event:  "release",
+		Source: "triggers.release",
+		Action: e.Action,
+		Tag:    r.TagName,
+		Name:   r.Name,
+		Script: cfg.Script,
+		Steps:  cfg.Steps,
+		Env: []string{
+			"TAG_NAME=" + r.TagName,
+			"RELEASE_ACTION=" + e.Action,
+			"RELEASE_NAME=" + r.Name,
+			"RELEASE_BODY=" + r.Body,
+			"RELEASE_URL=" + r.HTMLURL,
+			"RELEASE_TARGET=" + r.TargetCommitish,
+			"RELEASE_PRERELEASE=" + strconv.FormatBool(r.Prerelease),
+			"RELEASE_ASSETS=" + string(assets),
+		},
+		Publish:  e.Action != "deleted" && e.Action != "unpublished",
+		Delivery: d.with(e),
+	}, ""
+}
+
+// triggerForCreate 把创建标签的 create 事件转换为部署，不需要部署时返回原因
+// 创建分支的事件忽略，分支上的提交由 push 事件部署
+func triggerForCreate(cfg config.Trigger, e CreateEvent, d Delivery) (*trigger, string) {
+	switch {
+	case e.RefType != "tag":
+		return nil, fmt.Sprintf("忽略创建 %s 的事件", e.RefType)
+	case !cfg.Enabled:
+		return nil, "未启用标签部署"
+	case len(cfg.Tags) > 0 && !glob.MatchAny(cfg.Tags, e.Ref):
+		return nil, fmt.Sprintf("标签 %s 不在 triggers.tag.tags 中", e.Ref)
+	}
+	return &trigger{
+		Event:    "tag",
+		Source:   "triggers.tag",
+		Tag:      e.Ref,
+		Script:   cfg.Script,
+		Steps:    cfg.Steps,
+		Env:      []string{"TAG_NAME=" + e.Ref},
+		Publish:  true,
+		Delivery: d.with(e),
+	}, ""
+}
+
+// handleReleaseEvent 处理 release 事件
+func handleReleaseEvent(c *gin.Context, delivery Delivery) {
+	var event ReleaseEvent
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		logger.WithError(err).Error("无法解析 release 事件数据")
+		c.JSON(http.StatusBadRequest, gin.H{"错误": "无法解析 release 事件数据"})
+		return
+	}
+	t, skip := triggerForRelease(config.Get().Triggers.Release, event, delivery)
+	if t == nil {
+		logger.WithField("标签", event.Release.TagName).Info(skip)
+		c.JSON(http.StatusOK, gin.H{"消息": skip})
+		return
+	}
+	startTrigger(c, t)
+}
+
+// handleCreateEvent 处理 create 事件
+func handleCreateEvent(c *gin.Context, delivery Delivery) {
+	var event CreateEvent
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		logger.WithError(err).Error("无法解析 create 事件数据")
+		c.JSON(http.StatusBadRequest, gin.H{"错误": "无法解析 create 事件数据"})
+		return
+	}
+	t, skip := triggerForCreate(config.Get().Triggers.Tag, event, delivery)
+	if t == nil {
+		logger.WithField("引用", event.Ref).Info(skip)
+		c.JSON(http.StatusOK, gin.H{"消息": skip})
+		return
+	}
+	startTrigger(c, t)
+}
+
+// startTrigger 立即返回响应并在后台执行部署
+func startTrigger(c *gin.Context, t *trigger) {
+	runID := NewRunID()
+	c.JSON(http.StatusOK, gin.H{
+		"消息":   "脚本开始执行",
+		"状态":   "running",
+		"运行ID": runID,
+		"标签":   t.Tag,
+	})
+	go deployTrigger(t, runID)
+}
+
+// deployTrigger 同步执行一次 release 或标签事件触发的部署
+func deployTrigger(t *trigger, runID string) (*scripts.ExecutionResult, error) {
+	fields := logrus.Fields{
+		"事件类型": t.Event,
+		"标签":   t.Tag,
+	}
+	if t.Event == "release" {
+		fields["动作"] = t.Action
+		fields["标题"] = t.Name
+	}
+	logger.WithFields(fields).Info("收到标签部署事件")
+	return deploy(t.pushEvent(), runID, t)
+}
@@ -0,0 +1,130 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// triggerConfig 启用 release 与标签部署，脚本把环境变量写入 out 目录中与脚本同名的文件
+func triggerConfig(t *testing.T) string {
+	t.Helper()
+	script := "#!/bin/bash\nenv -0 > {{dir}}/out/$(basename \"$0\").tmp && mv {{dir}}/out/$(basename \"$0\").tmp {{dir}}/out/$(basename \"$0\")\n"
+	dir := useConfig(t, map[string]string{
+		"deploy.sh":  script,
+		"release.sh": script,
+		"tag.sh":     script,
+	}, "scripts:\n  path: {{dir}}\n  push: deploy.sh\n"+
+		"triggers:\n"+
+		"  release:\n    enabled: true\n    actions: [published, deleted]\n    tags: [\"v*\"]\n    script: release.sh\n"+
+		"  tag:\n    enabled: true\n    tags: [\"v*\"]\n    script: tag.sh\n")
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReleaseEvent(t *testing.T) {
+	dir := triggerConfig(t)
+	release := func(action, tag string, draft bool) []byte {
+		body, _ := json.Marshal(map[string]interface{}{
+			"action": action,
+			"release": map[string]interface{}{
+				"tag_name": tag,
+				"name":     "第一版",
+				"body":     "更新说明\n第二行",
+				"html_url": "https://github.com/owner/blog/releases/tag/" + tag,
+				"draft":    draft,
+				"assets":   []map[string]interface{}{{"name": "site.zip", "browser_download_url": "https://example.com/site.zip", "size": 3}},
+			},
+			"repository": map[string]interface{}{"full_name": "owner/blog"},
+			"sender":     map[string]interface{}{"login": "alice"},
+		})
+		return body
+	}
+
+	// 不部署的事件只返回原因
+	skips := []struct {
+		body    []byte
+		message string
+	}{
+		{release("edited", "v1.0.0", false), "忽略 release 的 edited 动作"},
+		{release("published", "v1.0.0", true), "release v1.0.0 是草稿，不部署"},
+		{release("published", "nightly", false), "标签 nightly 不在 triggers.release.tags 中"},
+	}
+	for _, tt := range skips {
+		status, response := send(t, "release", tt.body, nil)
+		if status != http.StatusOK || response["消息"] != tt.message {
+			t.Errorf("skipped release: %d %v, want %q", status, response, tt.message)
+		}
+	}
+
+	status, response := send(t, "release", release("published", "v1.0.0", false), http.Header{"X-Github-Delivery": {"delivery-1"}})
+	if status != http.StatusOK || response["状态"] != "running" || response["标签"] != "v1.0.0" {
+		t.Fatalf("release: %d %v", status, response)
+	}
+	env := envMap(waitFile(t, filepath.Join(dir, "out", "release.sh")))
+	want := map[string]string{
+		"EVENT":          "release",
+		"DELIVERY_ID":    "delivery-1",
+		"RUN_ID":         response["运行ID"].(string),
+		"REPO":           "owner/blog",
+		"PUSHER":         "alice",
+		"REF":            "refs/tags/v1.0.0",
+		"TAG_NAME":       "v1.0.0",
+		"RELEASE_ACTION": "published",
+		"RELEASE_NAME":   "第一版",
+		"RELEASE_BODY":   "更新说明\n第二行",
+		"RELEASE_URL":    "https://github.com/owner/blog/releases/tag/v1.0.0",
+		"RELEASE_ASSETS": `[{"name":"site.zip","browser_download_url":"https://example.com/site.zip","size":3,"content_type":""}]`,
+		"COMMIT_MESSAGE": "第一版",
+	}
+	for name, value := range want {
+		if env[name] != value {
+			t.Errorf("release.sh %s = %q, want %q", name, env[name], value)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "deploy.sh")); err == nil {
+		t.Error("release event ran the push script")
+	}
+}
+
+func TestCreateEvent(t *testing.T) {
+	dir := triggerConfig(t)
+
+	skips := []struct {
+		body    string
+		message string
+	}{
+		{`{"ref":"feature","ref_type":"branch"}`, "忽略创建 branch 的事件"},
+		{`{"ref":"nightly","ref_type":"tag"}`, "标签 nightly 不在 triggers.tag.tags 中"},
+	}
+	for _, tt := range skips {
+		status, response := send(t, "create", []byte(tt.body), nil)
+		if status != http.StatusOK || response["消息"] != tt.message {
+			t.Errorf("skipped create: %d %v, want %q", status, response, tt.message)
+		}
+	}
+
+	// 启用标签部署时，同时收到的标签推送不重复部署
+	status, response := send(t, "push", []byte(`{"ref":"refs/tags/v2.0.0","after":"1111111111111111111111111111111111111111"}`), nil)
+	if status != http.StatusOK || response["消息"] != "标签由 create 事件部署，忽略推送" {
+		t.Errorf("tag push: %d %v", status, response)
+	}
+
+	status, response = send(t, "create", []byte(`{"ref":"v2.0.0","ref_type":"tag","sender":{"login":"bob"}}`), nil)
+	if status != http.StatusOK || response["标签"] != "v2.0.0" {
+		t.Fatalf("create: %d %v", status, response)
+	}
+	env := envMap(waitFile(t, filepath.Join(dir, "out", "tag.sh")))
+	for name, value := range map[string]string{"EVENT": "create", "TAG_NAME": "v2.0.0", "REF": "refs/tags/v2.0.0", "PUSHER": "bob"} {
+		if env[name] != value {
+			t.Errorf("tag.sh %s = %q, want %q", name, env[name], value)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "deploy.sh")); err == nil {
+		t.Error("tag push ran the push script")
+	}
+}
@@ -2,15 +2,23 @@ package webhooks
 
 import (
 	"Hexo-AutoCD/config"
+	"Hexo-AutoCD/events"
+	"Hexo-AutoCD/git"
+	"Hexo-AutoCD/glob"
 	"Hexo-AutoCD/logger"
+	"Hexo-AutoCD/pipeline"
+	"Hexo-AutoCD/posts"
+	"Hexo-AutoCD/release"
 	"Hexo-AutoCD/scripts"
-	"crypto/hmac"
-	"crypto/sha256"
+	sign "Hexo-AutoCD/signature"
+	"Hexo-AutoCD/smoke"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,21 +28,7 @@ import (
 
 // Github 的 signature = "sha256=" + HMAC-SHA256(secret, body)
 func verifySignature(signature string, body []byte, secret string) bool {
-	// 检查前缀
-	const prefix = "sha256="
-	if len(signature) <= len(prefix) || signature[:len(prefix)] != prefix {
-		return false
-	}
-
-	// 去除 "sha256=" 前缀
-	signature = signature[len(prefix):]
-
-	// 计算 HMAC
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(body)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
-
-	return signature == expectedSignature
+	return sign.Verify(signature, body, secret)
 }
 
 func HandleWebhook(c *gin.Context) {
@@ -55,7 +49,7 @@ func HandleWebhook(c *gin.Context) {
 	}
 
 	// 验证签名
-	isValid := verifySignature(signature, body, config.Config.Webhook.Secret)
+	isValid := verifySignature(signature, body, config.Get().Webhook.Secret)
 	if !isValid {
 		logger.Errorf("签名验证失败。收到的签名：%s", signature)
 		c.JSON(http.StatusUnauthorized, gin.H{"错误": "X-Hub-Signature-256 头不匹配"})
@@ -71,12 +65,24 @@ func HandleWebhook(c *gin.Context) {
 	}
 
 	logger.Infof("收到 GitHub %s 事件", eventType)
+	delivery := newDelivery(deliveryID(c), eventType, body)
 
 	// 根据事件类型进行不同的处理
 	switch eventType {
 	case "push":
 		logger.WithField("事件类型", "push").Info("处理推送事件")
-		handlePushEvent(c, body)
+		handlePushEvent(c, delivery)
+	case "pull_request":
+		logger.WithField("事件类型", "pull_request").Info("处理拉取请求事件")
+		handlePullRequestEvent(c, delivery)
+	case "ping":
+		handlePingEvent(c, body)
+	case "release":
+		logger.WithField("事件类型", "release").Info("处理 release 事件")
+		handleReleaseEvent(c, delivery)
+	case "create":
+		logger.WithField("事件类型", "create").Info("处理创建标签事件")
+		handleCreateEvent(c, delivery)
 	default:
 		logger.WithFields(logrus.Fields{
 			"事件类型": eventType,
@@ -97,32 +103,198 @@ type HeadCommit struct {
 }
 
 type PushEvent struct {
-	HeadCommit HeadCommit `json:"head_commit"`
+	Ref        string       `json:"ref"`
+	Before     string       `json:"before"`
+	After      string       `json:"after"`
+	Deleted    bool         `json:"deleted"`
+	HeadCommit HeadCommit   `json:"head_commit"`
+	Commits    []HeadCommit `json:"commits"`
+	Repository struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+	Project struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"` // GitLab 的仓库信息
+
+	Delivery Delivery `json:"-"` // 收到的原始投递
+}
+
+// normalize 补全不同平台载荷之间的差异
+// GitLab 的 Push Hook 没有 head_commit，使用最后一个提交代替
+func (e *PushEvent) normalize() {
+	if e.HeadCommit.ID == "" && len(e.Commits) > 0 {
+		e.HeadCommit = e.Commits[len(e.Commits)-1]
+	}
+	if e.Repository.DefaultBranch == "" {
+		e.Repository.DefaultBranch = e.Project.DefaultBranch
+	}
+}
+
+// pipelineContext 汇总推送中的分支、提交信息与变更文件，用于判断流水线步骤的执行条件
+func (e *PushEvent) pipelineContext() *pipeline.Context {
+	commits := e.Commits
+	if len(commits) == 0 {
+		commits = []HeadCommit{e.HeadCommit}
+	}
+
+	ctx := &pipeline.Context{Branch: strings.TrimPrefix(e.Ref, "refs/heads/")}
+	if strings.HasPrefix(e.Ref, "refs/tags/") {
+		// 标签触发的部署没有分支
+		ctx.Branch = ""
+	}
+	seen := make(map[string]bool)
+	for _, commit := range commits {
+		ctx.Messages = append(ctx.Messages, commit.Message)
+		for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, file := range files {
+				if !seen[file] {
+					seen[file] = true
+					ctx.Changed = append(ctx.Changed, file)
+				}
+			}
+		}
+	}
+	return ctx
+}
+
+// files 汇总推送中所有提交新增、修改与删除的文件
+func (e *PushEvent) files() (added, modified, removed []string) {
+	commits := e.Commits
+	if len(commits) == 0 {
+		commits = []HeadCommit{e.HeadCommit}
+	}
+	for _, commit := range commits {
+		added = append(added, commit.Added...)
+		modified = append(modified, commit.Modified...)
+		removed = append(removed, commit.Removed...)
+	}
+	return unique(added), unique(modified), unique(removed)
+}
+
+// unique 去除重复的文件，保持原有顺序
+func unique(files []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, file := range files {
+		if !seen[file] {
+			seen[file] = true
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// tagPush 判断是否为启用了标签部署时推送的标签
+func tagPush(e PushEvent) bool {
+	return strings.HasPrefix(e.Ref, "refs/tags/") && config.Get().Triggers.Tag.Enabled
 }
 
-func handlePushEvent(c *gin.Context, body []byte) {
+// NewRunID 生成部署运行ID，格式为 时间戳-随机串，便于按时间排序
+func NewRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+func handlePushEvent(c *gin.Context, delivery Delivery) {
 	// 解析 body
 	var pushEvent PushEvent
-	if err := json.Unmarshal(body, &pushEvent); err != nil {
+	if err := json.Unmarshal(delivery.Payload, &pushEvent); err != nil {
 		logger.WithError(err).Error("无法解析 push 事件数据")
 		c.JSON(http.StatusBadRequest, gin.H{"错误": "无法解析 push 事件数据"})
 		return
 	}
+	pushEvent.normalize()
+	pushEvent.Delivery = delivery
+
+	// 启用标签部署时，标签由 create 事件部署，同时收到的推送不再重复部署
+	if tagPush(pushEvent) {
+		logger.WithField("引用", pushEvent.Ref).Info("标签由 create 事件部署，忽略推送")
+		c.JSON(http.StatusOK, gin.H{"消息": "标签由 create 事件部署，忽略推送"})
+		return
+	}
+
+	// 启用预览时，推送到非生产分支构建预览，分支被删除时删除预览
+	if target, ok := previewForPush(config.Get().Preview, pushEvent); ok {
+		if target.Skip != "" {
+			logger.Info(target.Skip)
+			c.JSON(http.StatusOK, gin.H{"消息": target.Skip})
+			return
+		}
+		startPreview(c, target)
+		return
+	}
 
-	// 截取提交ID的前8位以便于显示
-	shortCommitID := pushEvent.HeadCommit.ID
-	if len(shortCommitID) > 8 {
-		shortCommitID = shortCommitID[:8]
+	// 启用 git 时只部署生产分支，避免功能分支被检出到线上的仓库目录中
+	if reason := branchSkip(pushEvent); reason != "" {
+		logger.WithField("引用", pushEvent.Ref).Info(reason)
+		c.JSON(http.StatusOK, gin.H{"消息": reason})
+		return
 	}
 
+	runID := NewRunID()
+
+	// 立即返回成功响应
+	c.JSON(http.StatusOK, gin.H{
+		"消息":   "脚本开始执行",
+		"状态":   "running",
+		"运行ID": runID,
+	})
+
+	// 异步执行脚本
+	go DeployPush(pushEvent, runID)
+}
+
+// DeployPush 同步执行一次推送部署，并在结束后通知下游系统
+// webhook 收到推送后异步调用它，命令行的 run 与 replay 也直接调用它
+func DeployPush(pushEvent PushEvent, runID string) (*scripts.ExecutionResult, error) {
 	logger.WithFields(logrus.Fields{
-		"提交ID":  shortCommitID,
+		"提交ID":  shortID(pushEvent.HeadCommit.ID),
 		"提交信息":  pushEvent.HeadCommit.Message,
 		"提交时间":  pushEvent.HeadCommit.Timestamp,
 		"新增文件数": len(pushEvent.HeadCommit.Added),
 		"修改文件数": len(pushEvent.HeadCommit.Modified),
 		"删除文件数": len(pushEvent.HeadCommit.Removed),
 	}).Info("收到Git推送事件")
+	if reason := branchSkip(pushEvent); reason != "" {
+		logger.WithField("引用", pushEvent.Ref).Info(reason)
+		return nil, nil
+	}
+	return deploy(pushEvent, runID, nil)
+}
+
+// branchSkip 返回启用 git 时推送不部署的原因，推送到生产分支或不是分支推送时返回空字符串
+// 生产分支为 git.branches，未设置时为载荷中仓库的默认分支，载荷中没有时使用 preview.production
+func branchSkip(e PushEvent) string {
+	cfg := config.Get()
+	branch, ok := strings.CutPrefix(e.Ref, "refs/heads/")
+	if !cfg.Git.Enabled || !ok {
+		return ""
+	}
+	branches := cfg.Git.Branches
+	if len(branches) == 0 {
+		branches = cfg.Preview.Production
+		if e.Repository.DefaultBranch != "" {
+			branches = []string{e.Repository.DefaultBranch}
+		}
+	}
+	if glob.MatchAny(branches, branch) {
+		return ""
+	}
+	return fmt.Sprintf("分支 %s 不是生产分支（%s），不部署", branch, strings.Join(branches, "、"))
+}
+
+// deploy 执行一次部署：检出提交、执行脚本或流水线、发布版本并检查站点
+// t 不为 nil 时为 release 或标签事件触发的部署，使用其中配置的脚本或步骤
+func deploy(pushEvent PushEvent, runID string, t *trigger) (*scripts.ExecutionResult, error) {
+	// 获取当前配置的快照，保证同一次部署始终使用同一份配置
+	cfg := config.Get()
+
+	event, script := "push", cfg.Scripts.Push
+	publish := true
+	if t != nil {
+		event, script, publish = t.Event, t.Script, t.Publish
+	}
 
 	// 准备环境变量
 	commitEnv := []string{
@@ -133,46 +305,362 @@ func handlePushEvent(c *gin.Context, body []byte) {
 		fmt.Sprintf("COMMIT_REMOVED=%s", strings.Join(pushEvent.HeadCommit.Removed, ",")),
 		fmt.Sprintf("COMMIT_MODIFIED=%s", strings.Join(pushEvent.HeadCommit.Modified, ",")),
 	}
+	if t != nil {
+		commitEnv = append(commitEnv, t.Env...)
+	}
+	if cfg.Static.Enabled {
+		// 站点由内置静态文件服务提供，脚本不需要停止与启动 hexo serve
+		commitEnv = append(commitEnv, "STATIC_SERVE=1")
+	}
+
+	startTime := time.Now()
+
+	// 检出推送中的提交，之后的脚本与仓库中的流水线文件都基于该提交
+	var checkout []scripts.StepResult
+	var checkoutErr error
+	if cfg.Git.Enabled && publish {
+		gitTimeout, _ := time.ParseDuration(cfg.Git.Timeout)
+		var verify *git.VerifyPolicy
+		if v := cfg.Git.VerifySignatures; v.Enabled {
+			verify = verifyPolicy(v.Scope == "all", v.GPGHome, v.AllowedSigners, pushEvent)
+		}
+		var step scripts.StepResult
+		var head string
+		step, head, checkoutErr = checkoutPush(cfg.Site.RepoDir, cfg.Git.Remote, cfg.Git.RefuseDirty, gitTimeout, verify, pipeline.RetryPolicy(cfg.Git.Retry), pushEvent)
+		checkout = append(checkout, step)
+		commitEnv = append(commitEnv, "COMMIT_CHECKED_OUT=1")
+		// release 与 create 事件中没有提交ID，使用检出的标签所指向的提交
+		if pushEvent.HeadCommit.ID == "" && head != "" {
+			pushEvent.HeadCommit.ID = head
+			commitEnv[0] = "COMMIT_ID=" + head
+		}
+	}
+	shortCommitID := shortID(pushEvent.HeadCommit.ID)
+
+	// 启用发布目录时构建到新的版本目录中，全部步骤成功后才切换 current
+	var releases *release.Manager
+	var rel *release.Release
+	var releaseErr error
+	if cfg.Releases.Enabled && publish && checkoutErr == nil && slices.Contains(cfg.Releases.Events, event) {
+		commit := pushEvent.After
+		if commit == "" {
+			commit = pushEvent.HeadCommit.ID
+		}
+		releases = release.New(cfg.Releases)
+		rel, releaseErr = releases.Prepare(commit, time.Now())
+		if rel != nil {
+			commitEnv = append(commitEnv, "RELEASE_DIR="+rel.Path, "RELEASE_ID="+rel.ID)
+		}
+	}
+
+	// 脚本超时时间已在加载配置时校验过
+	timeout, _ := time.ParseDuration(cfg.Scripts.Timeout)
+
+	// 事件信息写入载荷文件，并导出为环境变量
+	delivery := pushEvent.Delivery
+	if delivery.Normalized == nil {
+		delivery.Normalized = pushEvent
+	}
+	payload := delivery.payload(runID, cfg.Site.Name)
+	payload.Ref, payload.Before = pushEvent.Ref, pushEvent.Before
+	payload.Branch = pushEvent.pipelineContext().Branch
+	payload.After = pushEvent.After
+	if payload.After == "" {
+		payload.After = pushEvent.HeadCommit.ID
+	}
+	payload.Added, payload.Modified, payload.Removed = pushEvent.files()
 
-	// 创建执行器
-	executor := scripts.NewExecutor(scripts.ExecutorConfig{
-		ScriptsPath:   config.Config.Scripts.Path,
-		Timeout:       5 * time.Minute,
+	// 创建执行器，在容器中执行时挂载博客仓库与发布目录
+	var releasesDir string
+	if rel != nil {
+		releasesDir = cfg.Releases.Dir
+	}
+	backend, executorErr := newExecutor(cfg.Executor, scripts.ExecutorConfig{
+		ScriptsPath:   cfg.Scripts.Path,
+		Timeout:       timeout,
 		MaxConcurrent: 5,
 		DefaultEnv:    commitEnv,
-	})
+		Payload:       payload,
+		Sandbox:       pipeline.Sandbox(cfg.Scripts.Sandbox),
+	}, cfg.Site.RepoDir, releasesDir)
+	if executorErr == nil {
+		defer backend.Close()
+	}
+	var executor scripts.ScriptExecutor = backend
+	var runner *pipeline.Runner
 
-	// 立即返回成功响应
-	c.JSON(http.StatusOK, gin.H{
-		"消息": "脚本开始执行",
-		"状态": "running",
-	})
+	// 配置了流水线时按步骤执行，否则执行部署脚本
+	var steps []config.Step
+	var source string
+	var stepsErr error
+	switch {
+	case t != nil:
+		steps, source = t.Steps, t.Source
+	case checkoutErr == nil:
+		steps, source, stepsErr = pipeline.Steps(&cfg.Pipeline, cfg.Site.RepoDir)
+	}
+	if len(steps) > 0 {
+		// 步骤直接构建到版本目录时同步该目录
+		var publicDir string
+		if rel != nil && cfg.Releases.Source == "" {
+			publicDir = rel.Path
+		}
+		runner = pipeline.New(backend, pipeline.Config{
+			Steps:     steps,
+			BaseDir:   cfg.Scripts.Path,
+			RepoDir:   cfg.Site.RepoDir,
+			Posts:     cfg.Posts,
+			Publish:   cfg.Publish,
+			PublicDir: publicDir,
+			Retry:     cfg.Scripts.Retry,
+		})
+		executor = runner
+	}
 
 	// 创建脚本执行的日志上下文
 	scriptExecLogger := logger.WithFields(logrus.Fields{
-		"脚本类型": config.Config.Scripts.Push,
+		"运行ID": runID,
+		"事件类型": event,
+		"脚本类型": script,
 		"提交ID": shortCommitID,
 		"提交信息": pushEvent.HeadCommit.Message,
 	})
+	if len(steps) > 0 {
+		scriptExecLogger = scriptExecLogger.WithFields(logrus.Fields{
+			"流水线":  source,
+			"步骤数量": len(steps),
+		})
+	}
 
-	scriptExecLogger.Info("开始异步执行部署脚本")
+	scriptExecLogger.Info("开始执行部署脚本")
 
-	// 异步执行脚本
-	go func() {
-		result, err := executor.Execute(config.Config.Scripts.Push, "")
-		if err != nil {
-			scriptExecLogger.WithError(err).Error("执行脚本失败")
-			return
+	var result *scripts.ExecutionResult
+	err := stepsErr
+	if err == nil {
+		err = releaseErr
+	}
+	if err == nil {
+		err = executorErr
+	}
+	switch {
+	case checkoutErr != nil:
+		result = &scripts.ExecutionResult{ExitCode: -1, Error: checkoutErr.Error(), Steps: checkout}
+	case err == nil:
+		// 流水线中的每个步骤各自重试，部署脚本整体重试
+		var policy *scripts.RetryPolicy
+		if len(steps) == 0 {
+			policy = pipeline.RetryPolicy(cfg.Scripts.Retry)
+		}
+		var attempts []scripts.Attempt
+		result, attempts, err = scripts.RetryExecution(policy, scriptExecLogger, func() (*scripts.ExecutionResult, error) {
+			return executor.Execute(script, pushEvent.pipelineContext())
+		})
+		if result != nil && len(attempts) > 1 {
+			result.Attempts, result.History = len(attempts), attempts
+		}
+		if result != nil && len(checkout) > 0 {
+			result.Steps = append(checkout, result.Steps...)
 		}
+	}
+	succeeded := err == nil && result.ExitCode == 0
+	if rel != nil && !succeeded {
+		// 构建失败时站点保持原来的版本
+		releases.Discard(rel)
+	}
+	var previousRelease, rolledBack string
+	if rel != nil && succeeded {
+		var step scripts.StepResult
+		var publishErr error
+		step, previousRelease, publishErr = publishRelease(releases, rel)
+		result.Steps = append(result.Steps, step)
+		if publishErr != nil {
+			succeeded = false
+			result.ExitCode = 1
+			result.Error = fmt.Sprintf("步骤 %s 失败: %v", releaseStep, publishErr)
+		}
+	}
 
-		if result.ExitCode != 0 {
-			scriptExecLogger.WithFields(logrus.Fields{
-				"退出码":  result.ExitCode,
-				"错误信息": result.Error,
-			}).Error("脚本执行返回非零退出码")
-			return
+	// 发布后检查站点，未通过时回滚到发布前的版本
+	if cfg.Smoke.Enabled && publish && succeeded {
+		publicDir := cfg.Smoke.PublicDir
+		if rel != nil {
+			publicDir = rel.Path
+		}
+		changed := posts.Relative(cfg.Posts.SourceDir, cfg.Site.RepoDir, pushEvent.pipelineContext().Changed)
+		step, smokeErr := smokeCheck(smoke.New(cfg.Smoke, cfg.Posts), publicDir, changed)
+		result.Steps = append(result.Steps, step)
+		if smokeErr != nil {
+			result.ExitCode = 1
+			result.Error = fmt.Sprintf("步骤 %s 失败: %v", smokeStep, smokeErr)
+			if rel != nil {
+				step := rollbackRelease(releases, rel.ID, previousRelease)
+				result.Steps = append(result.Steps, step)
+				if step.Status == scripts.StepSuccess {
+					rolledBack = previousRelease
+				}
+			}
 		}
+	}
+
+	// 流水线中的 site/publish 步骤在发布版本并通过冒烟检查后才执行，失败的构建不会同步到远程目标
+	if runner != nil && result != nil {
+		var reason string
+		switch {
+		case !publish:
+			reason = "本次事件不发布，不同步"
+		case !succeeded || result.ExitCode != 0:
+			reason = "部署未成功，不同步"
+		}
+		for _, step := range runner.Publish(reason) {
+			result.Steps = append(result.Steps, step)
+			if step.Status == scripts.StepFailed && result.ExitCode == 0 {
+				result.ExitCode = step.ExitCode
+				result.Error = fmt.Sprintf("步骤 %s 失败: %s", step.Name, step.Error)
+			}
+		}
+	}
+
+	// 部署成功后刷新 CDN 缓存
+	if cfg.Purge.Enabled && publish && succeeded && result.ExitCode == 0 {
+		publicDir := cfg.Publish.Source
+		if rel != nil {
+			publicDir = rel.Path
+		}
+		step := purgeCache(cfg.Purge, cfg.Posts, cfg.Site.RepoDir, publicDir, pushEvent.Before, pushEvent.pipelineContext().Changed)
+		result.Steps = append(result.Steps, step)
+	}
 
-		scriptExecLogger.WithField("日志行数", len(result.Logs)).Info("脚本执行成功完成")
+	// 通知下游系统部署结束
+	evt := events.NewEvent(events.TypeDeployFinished)
+	evt.RunID = runID
+	evt.Commit = pushEvent.HeadCommit.ID
+	// 汇总推送中的所有提交，而不只是最后一个提交
+	added, modified, removed := pushEvent.files()
+	evt.ChangedFiles = events.ChangedFiles{Added: added, Modified: modified, Removed: removed}
+	if t != nil {
+		evt.Data = t.data()
+	}
+	defer func() {
+		evt.DurationMs = time.Since(startTime).Milliseconds()
+		events.Publish(evt)
 	}()
+
+	if err != nil {
+		evt.Outcome = events.OutcomeFailure
+		evt.ExitCode = -1
+		evt.Error = err.Error()
+		scriptExecLogger.WithError(err).Error("执行脚本失败")
+		return nil, err
+	}
+
+	evt.ExitCode = result.ExitCode
+	if len(result.Steps) > 0 {
+		if evt.Data == nil {
+			evt.Data = map[string]interface{}{}
+		}
+		evt.Data["steps"] = result.Steps
+	}
+	if len(result.History) > 0 {
+		if evt.Data == nil {
+			evt.Data = map[string]interface{}{}
+		}
+		evt.Data["attempts"] = result.History
+	}
+	if result.ExitCode != 0 {
+		evt.Outcome = events.OutcomeFailure
+		evt.Error = result.Error
+		if rolledBack != "" {
+			evt.Data["rolled_back_to"] = rolledBack
+		}
+		if len(checkout) > 0 && rejected(checkout[0]) {
+			evt.Outcome = events.OutcomeRejected
+			scriptExecLogger.WithField("原因", result.Error).Error("提交签名未通过校验，拒绝部署")
+			return result, nil
+		}
+		scriptExecLogger.WithFields(logrus.Fields{
+			"退出码":  result.ExitCode,
+			"错误信息": result.Error,
+		}).Error("脚本执行返回非零退出码")
+		return result, nil
+	}
+
+	evt.Outcome = events.OutcomeSuccess
+	if rel != nil {
+		if evt.Data == nil {
+			evt.Data = map[string]interface{}{}
+		}
+		evt.Data["release"] = rel.ID
+	}
+	scriptExecLogger.WithField("日志行数", len(result.Logs)).Info("脚本执行成功完成")
+	return result, nil
+}
+
+// Run 在本地同步处理一个事件，跳过签名校验
+// 供命令行 run 与 replay 使用，payload 为事件的原始 JSON
+func Run(eventType string, payload []byte) (*scripts.ExecutionResult, error) {
+	delivery := newDelivery("", eventType, payload)
+	switch eventType {
+	case "push":
+		var pushEvent PushEvent
+		if err := json.Unmarshal(payload, &pushEvent); err != nil {
+			return nil, fmt.Errorf("无法解析 push 事件数据: %v", err)
+		}
+		pushEvent.normalize()
+		pushEvent.Delivery = delivery
+		if tagPush(pushEvent) {
+			return nil, fmt.Errorf("标签由 create 事件部署，忽略推送")
+		}
+		if target, ok := previewForPush(config.Get().Preview, pushEvent); ok {
+			if target.Skip != "" {
+				return nil, fmt.Errorf("%s", target.Skip)
+			}
+			return runPreview(target, NewRunID())
+		}
+		return DeployPush(pushEvent, NewRunID())
+	case "pull_request":
+		if cfg := config.Get().Preview; !cfg.Enabled || !cfg.PullRequests {
+			return nil, fmt.Errorf("未启用拉取请求预览")
+		}
+		var event PullRequestEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("无法解析 pull_request 事件数据: %v", err)
+		}
+		target, ok, err := previewForPullRequest(event, delivery)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("忽略 %s 动作", event.Action)
+		}
+		return runPreview(target, NewRunID())
+	case "ping":
+		var event PingEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("无法解析 ping 事件数据: %v", err)
+		}
+		ping(event)
+		return nil, nil
+	case "release":
+		var event ReleaseEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("无法解析 release 事件数据: %v", err)
+		}
+		t, skip := triggerForRelease(config.Get().Triggers.Release, event, delivery)
+		if t == nil {
+			return nil, fmt.Errorf("%s", skip)
+		}
+		return deployTrigger(t, NewRunID())
+	case "create":
+		var event CreateEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("无法解析 create 事件数据: %v", err)
+		}
+		t, skip := triggerForCreate(config.Get().Triggers.Tag, event, delivery)
+		if t == nil {
+			return nil, fmt.Errorf("%s", skip)
+		}
+		return deployTrigger(t, NewRunID())
+	default:
+		return nil, fmt.Errorf("不支持的事件类型: %s", eventType)
+	}
 }
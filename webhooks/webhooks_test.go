@@ -0,0 +1,88 @@
+package webhooks
+
+import (
+	"Hexo-AutoCD/config"
+	sign "Hexo-AutoCD/signature"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// send 把签名后的事件发送给 HandleWebhook，返回状态码与解析后的响应
+func send(t *testing.T, event string, body []byte, header http.Header) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/webhook", HandleWebhook)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", sign.Sign(body, config.Get().Webhook.Secret))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s: invalid response %q", event, w.Body.String())
+	}
+	return w.Code, response
+}
+
+// waitFile 等待后台部署写出文件，返回其内容
+func waitFile(t *testing.T, name string) string {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		data, err := os.ReadFile(name)
+		if err == nil {
+			return string(data)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s not written: %v", name, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// envMap 解析 env -0 的输出
+func envMap(output string) map[string]string {
+	env := map[string]string{}
+	for _, kv := range strings.Split(output, "\x00") {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+func TestHandleWebhookRejects(t *testing.T) {
+	useConfig(t, map[string]string{"deploy.sh": "#!/bin/bash\n"}, "scripts:\n  path: {{dir}}\n  push: deploy.sh\n")
+	body := []byte(`{"zen":"x"}`)
+	tests := []struct {
+		name   string
+		header http.Header
+		event  string
+		status int
+	}{
+		{"missing signature", http.Header{"X-Hub-Signature-256": {""}}, "ping", http.StatusBadRequest},
+		{"wrong signature", http.Header{"X-Hub-Signature-256": {sign.Sign(body, "wrong secret")}}, "ping", http.StatusUnauthorized},
+		{"missing event", nil, "", http.StatusBadRequest},
+		{"unsupported event", nil, "issues", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status, response := send(t, tt.event, body, tt.header); status != tt.status {
+			t.Errorf("%s: status %d %v, want %d", tt.name, status, response, tt.status)
+		}
+	}
+}